@@ -15,14 +15,20 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/percona/mongodb-orchestration-tools/controller"
 	"github.com/percona/mongodb-orchestration-tools/controller/replset"
+	"github.com/percona/mongodb-orchestration-tools/controller/restore"
 	"github.com/percona/mongodb-orchestration-tools/controller/user"
+	user_json "github.com/percona/mongodb-orchestration-tools/controller/user/json"
 	"github.com/percona/mongodb-orchestration-tools/internal"
+	"github.com/percona/mongodb-orchestration-tools/internal/backup"
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api"
@@ -40,7 +46,14 @@ var (
 	cmdUser          *kingpin.CmdClause
 	cmdUserUpdate    *kingpin.CmdClause
 	cmdUserRemove    *kingpin.CmdClause
+	cmdUserSync      *kingpin.CmdClause
 	cmdUserReloadSys *kingpin.CmdClause
+	cmdUserRole      *kingpin.CmdClause
+	cmdRoleCreate    *kingpin.CmdClause
+	cmdRoleUpdate    *kingpin.CmdClause
+	cmdRoleList      *kingpin.CmdClause
+	cmdRoleDrop      *kingpin.CmdClause
+	cmdRestore       *kingpin.CmdClause
 )
 
 func handleReplsetCmd(app *kingpin.Application, cnf *controller.Config) {
@@ -74,7 +87,13 @@ func handleUserCmd(app *kingpin.Application, cnf *controller.Config) {
 	cmdUser = app.Command("user", "Control MongoDB users")
 	cmdUserRemove = cmdUser.Command("remove", "Remove a MongoDB user")
 	cmdUserUpdate = cmdUser.Command("update", "Add/update a MongoDB user")
+	cmdUserSync = cmdUser.Command("sync", "Create/update/drop MongoDB users and custom roles to match a spec file")
 	cmdUserReloadSys = cmdUser.Command("reload-system", "Reload the DCOS Framework MongoDB system users")
+	cmdUserRole = cmdUser.Command("role", "Control MongoDB custom roles")
+	cmdRoleCreate = cmdUserRole.Command("create", "Create a MongoDB custom role")
+	cmdRoleUpdate = cmdUserRole.Command("update", "Update the privileges and inherited roles of a MongoDB custom role")
+	cmdRoleList = cmdUserRole.Command("list", "List the MongoDB custom roles of a database as JSON")
+	cmdRoleDrop = cmdUserRole.Command("drop", "Drop a MongoDB custom role")
 
 	// user
 	cmdUser.Flag(
@@ -121,6 +140,138 @@ func handleUserCmd(app *kingpin.Application, cnf *controller.Config) {
 		"db",
 		"the MongoDB database of the user, this flag or env var "+dcos.EnvMongoDBChangeUserDb+" is required",
 	).Envar(dcos.EnvMongoDBChangeUserDb).Required().StringVar(&cnf.User.Database)
+
+	// user sync
+	cmdUserSync.Arg(
+		"file",
+		"the required YAML or JSON file describing the desired MongoDB users and custom roles",
+	).Required().ExistingFileVar(&cnf.User.File)
+	cmdUserSync.Flag(
+		"prune",
+		"drop the users and custom roles that are not in the spec file, system users are never dropped",
+	).BoolVar(&cnf.User.Prune)
+	cmdUserSync.Flag(
+		"dryRun",
+		"log the changes without applying them",
+	).BoolVar(&cnf.User.DryRun)
+
+	// user role create/update
+	for _, cmd := range []*kingpin.CmdClause{cmdRoleCreate, cmdRoleUpdate} {
+		cmd.Arg(
+			"file",
+			"the required YAML or JSON file describing the MongoDB custom role",
+		).Required().ExistingFileVar(&cnf.User.File)
+	}
+
+	// user role list
+	cmdRoleList.Flag(
+		"db",
+		"the MongoDB database of the roles, this flag or env var "+dcos.EnvMongoDBChangeUserDb+" is required",
+	).Envar(dcos.EnvMongoDBChangeUserDb).Required().StringVar(&cnf.User.Database)
+
+	// user role drop
+	cmdRoleDrop.Flag(
+		"role",
+		"the MongoDB custom role to be dropped, built-in roles cannot be dropped",
+	).Required().StringVar(&cnf.User.Role)
+	cmdRoleDrop.Flag(
+		"db",
+		"the MongoDB database of the role, this flag or env var "+dcos.EnvMongoDBChangeUserDb+" is required",
+	).Envar(dcos.EnvMongoDBChangeUserDb).Required().StringVar(&cnf.User.Database)
+}
+
+func handleRestoreCmd(app *kingpin.Application, cnf *controller.Config) {
+	cmdRestore = app.Command("restore", "Restore a MongoDB replset from a backup archive")
+
+	cmdRestore.Flag(
+		"endpoint",
+		"DC/OS SDK service mongod endpoint name, overridden by env var "+dcos.EnvMongoDBMongodEndpointName,
+	).Default(dcos.DefaultMongoDBMongodEndpointName).Envar(dcos.EnvMongoDBMongodEndpointName).StringVar(&cnf.Restore.EndpointName)
+	cmdRestore.Flag(
+		"apiHost",
+		"DC/OS SDK API hostname, overridden by env var "+dcos.EnvSchedulerAPIHost,
+	).Default(api.DefaultSchedulerHost).Envar(dcos.EnvSchedulerAPIHost).StringVar(&cnf.Restore.API.Host)
+	cmdRestore.Flag(
+		"apiTimeout",
+		"DC/OS SDK API timeout",
+	).Default(api.DefaultHTTPTimeout).DurationVar(&cnf.Restore.API.Timeout)
+	cmdRestore.Flag(
+		"archive",
+		"the name of the backup archive to restore, '"+controller.DefaultRestoreArchive+"' restores the newest archive of the replset",
+	).Default(controller.DefaultRestoreArchive).StringVar(&cnf.Restore.Archive)
+	cmdRestore.Flag(
+		"nsInclude",
+		"restore only namespaces matching this pattern, eg: 'mydb.*', may be repeated",
+	).StringsVar(&cnf.Restore.NsInclude)
+	cmdRestore.Flag(
+		"nsExclude",
+		"skip namespaces matching this pattern, may be repeated",
+	).StringsVar(&cnf.Restore.NsExclude)
+	cmdRestore.Flag(
+		"drop",
+		"drop each collection before restoring it",
+	).BoolVar(&cnf.Restore.Drop)
+	cmdRestore.Flag(
+		"oplogReplay",
+		"replay the oplog captured by the backup archive for a consistent restore",
+	).BoolVar(&cnf.Restore.OplogReplay)
+	cmdRestore.Flag(
+		"oplogLimit",
+		"restore to this point in time as an RFC3339 time or <seconds>[:ordinal] timestamp, the oplog of the backup archive and the stored PITR oplog chunks are replayed up to, but not including, it, implies --oplogReplay",
+	).StringVar(&cnf.Restore.OplogLimit)
+	cmdRestore.Flag(
+		"progressInterval",
+		"the frequency to log the progress of the restore, 0 disables progress logging",
+	).Default(controller.DefaultRestoreProgressInterval).DurationVar(&cnf.Restore.ProgressInterval)
+	cmdRestore.Flag(
+		"mongorestoreBin",
+		"the path to the mongorestore binary",
+	).Default(controller.DefaultMongorestoreBin).StringVar(&cnf.Restore.MongorestoreBin)
+	cmdRestore.Flag(
+		"username",
+		"mongodb backup username, this flag or env var "+pkg.EnvMongoDBBackupUser+" is required",
+	).Envar(pkg.EnvMongoDBBackupUser).Required().StringVar(&cnf.Restore.Username)
+	cmdRestore.Flag(
+		"password",
+		"mongodb backup password, this flag or env var "+pkg.EnvMongoDBBackupPassword+" is required",
+	).Envar(pkg.EnvMongoDBBackupPassword).Required().StringVar(&cnf.Restore.Password)
+
+	// backup storage
+	cmdRestore.Flag(
+		"storage",
+		"the storage of backup archives, overridden by env var "+dcos.EnvBackupStorage+". Options: "+strings.Join(backup.Storages, ", "),
+	).Default(backup.DefaultStorage).Envar(dcos.EnvBackupStorage).EnumVar(&cnf.Restore.Storage.Storage, backup.Storages...)
+	cmdRestore.Flag(
+		"localPath",
+		"the directory of the local backup storage",
+	).Default(dcos.MesosSandboxPathOrFallback(
+		"backup",
+		backup.DefaultLocalPathFallback,
+	)).StringVar(&cnf.Restore.Storage.LocalPath)
+	cmdRestore.Flag(
+		"s3.endpoint",
+		"the url of the S3-compatible endpoint of the s3 backup storage, overridden by env var "+dcos.EnvBackupS3Endpoint,
+	).Default(backup.DefaultS3Endpoint).Envar(dcos.EnvBackupS3Endpoint).StringVar(&cnf.Restore.Storage.S3.Endpoint)
+	cmdRestore.Flag(
+		"s3.region",
+		"the region of the s3 backup storage, overridden by env var "+dcos.EnvBackupS3Region,
+	).Default(backup.DefaultS3Region).Envar(dcos.EnvBackupS3Region).StringVar(&cnf.Restore.Storage.S3.Region)
+	cmdRestore.Flag(
+		"s3.bucket",
+		"the bucket of the s3 backup storage, overridden by env var "+dcos.EnvBackupS3Bucket,
+	).Envar(dcos.EnvBackupS3Bucket).StringVar(&cnf.Restore.Storage.S3.Bucket)
+	cmdRestore.Flag(
+		"s3.prefix",
+		"the key prefix of backup archives in the s3 bucket, overridden by env var "+dcos.EnvBackupS3Prefix,
+	).Envar(dcos.EnvBackupS3Prefix).StringVar(&cnf.Restore.Storage.S3.Prefix)
+	cmdRestore.Flag(
+		"s3.accessKey",
+		"the access key of the s3 backup storage, overridden by env var "+dcos.EnvBackupS3AccessKey,
+	).Envar(dcos.EnvBackupS3AccessKey).StringVar(&cnf.Restore.Storage.S3.AccessKey)
+	cmdRestore.Flag(
+		"s3.secretKey",
+		"the secret key of the s3 backup storage, overridden by env var "+dcos.EnvBackupS3SecretKey,
+	).Envar(dcos.EnvBackupS3SecretKey).StringVar(&cnf.Restore.Storage.S3.SecretKey)
 }
 
 func handleFailed(err error) {
@@ -137,6 +288,12 @@ func main() {
 		User: &controller.ConfigUser{
 			API: &api.Config{},
 		},
+		Restore: &controller.ConfigRestore{
+			API: &api.Config{},
+			Storage: &backup.StorageConfig{
+				S3: &backup.S3Config{},
+			},
+		},
 	}
 
 	app.Flag(
@@ -149,25 +306,31 @@ func main() {
 	).Envar(pkg.EnvMongoDBPort).Required().IntVar(&mongoDBPort)
 	app.Flag(
 		"replset",
-		"mongodb replica set name, this flag or env var "+pkg.EnvMongoDBReplset+" is required",
-	).Envar(pkg.EnvMongoDBReplset).Required().StringVar(&cnf.Replset)
+		"mongodb replica set name, this flag, env var "+pkg.EnvMongoDBReplset+" or a --uri with a replicaSet is required",
+	).Envar(pkg.EnvMongoDBReplset).StringVar(&cnf.Replset)
 	app.Flag(
 		"userAdminUser",
 		"mongodb userAdmin username, overridden by env var "+pkg.EnvMongoDBUserAdminUser,
-	).Envar(pkg.EnvMongoDBUserAdminUser).Required().StringVar(&cnf.UserAdminUser)
+	).Envar(pkg.EnvMongoDBUserAdminUser).StringVar(&cnf.UserAdminUser)
 	app.Flag(
 		"userAdminPassword",
 		"mongodb userAdmin password or path to file containing it, overridden by env var "+pkg.EnvMongoDBUserAdminPassword,
-	).Envar(pkg.EnvMongoDBUserAdminPassword).Required().StringVar(&cnf.UserAdminPassword)
+	).Envar(pkg.EnvMongoDBUserAdminPassword).StringVar(&cnf.UserAdminPassword)
 	app.Flag(
 		"enableSecrets",
 		"enable DC/OS Secrets, this causes passwords to be loaded from files, overridden by env var "+dcos.EnvSecretsEnabled,
 	).Envar(dcos.EnvSecretsEnabled).BoolVar(&enableSecrets)
 
 	cnf.SSL = db.NewSSLConfig(app)
+	cnf.Pool = db.NewPoolConfig(app)
+	db.NewDriverFlag(app, &cnf.Driver)
+	db.NewAuthMechanismFlag(app, &cnf.AuthMechanism)
+	db.NewAuthFallbackFlag(app, &cnf.AuthFallback)
+	db.NewUriFlag(app, cnf.ApplyUri)
 
 	handleReplsetCmd(app, cnf)
 	handleUserCmd(app, cnf)
+	handleRestoreCmd(app, cnf)
 
 	command, err := app.Parse(os.Args[1:])
 	if err != nil {
@@ -181,6 +344,9 @@ func main() {
 		)
 	}
 
+	ctx, cancel := tool.NewSignalContext(syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
 	switch command {
 	case cmdInit.FullCommand():
 		if cnf.ReplsetInit.PrimaryAddr == "" {
@@ -188,12 +354,12 @@ func main() {
 			cnf.ReplsetInit.PrimaryAddr = primaryHost + ":" + strconv.Itoa(mongoDBPort)
 			log.Debugf("Using primary address %q for replset init", cnf.ReplsetInit.PrimaryAddr)
 		}
-		err := replset.NewInitiator(cnf).Run()
+		err := replset.NewInitiator(cnf).Run(ctx)
 		if err != nil {
 			handleFailed(err)
 		}
 	case cmdUserUpdate.FullCommand():
-		uc, err := user.NewController(cnf, api.New(cnf.User.API))
+		uc, err := user.NewController(ctx, cnf, api.New(cnf.User.API))
 		if err != nil {
 			handleFailed(err)
 		}
@@ -204,7 +370,7 @@ func main() {
 			handleFailed(err)
 		}
 	case cmdUserRemove.FullCommand():
-		uc, err := user.NewController(cnf, api.New(cnf.User.API))
+		uc, err := user.NewController(ctx, cnf, api.New(cnf.User.API))
 		if err != nil {
 			handleFailed(err)
 		}
@@ -214,8 +380,19 @@ func main() {
 		if err != nil {
 			handleFailed(err)
 		}
+	case cmdUserSync.FullCommand():
+		uc, err := user.NewController(ctx, cnf, api.New(cnf.User.API))
+		if err != nil {
+			handleFailed(err)
+		}
+		defer uc.Close()
+
+		err = uc.SyncUsers()
+		if err != nil {
+			handleFailed(err)
+		}
 	case cmdUserReloadSys.FullCommand():
-		uc, err := user.NewController(cnf, api.New(cnf.User.API))
+		uc, err := user.NewController(ctx, cnf, api.New(cnf.User.API))
 		if err != nil {
 			handleFailed(err)
 		}
@@ -225,5 +402,47 @@ func main() {
 		if err != nil {
 			handleFailed(err)
 		}
+	case cmdRoleCreate.FullCommand(), cmdRoleUpdate.FullCommand(), cmdRoleList.FullCommand(), cmdRoleDrop.FullCommand():
+		uc, err := user.NewController(ctx, cnf, api.New(cnf.User.API))
+		if err != nil {
+			handleFailed(err)
+		}
+		defer uc.Close()
+
+		switch command {
+		case cmdRoleCreate.FullCommand():
+			err = uc.CreateRole()
+		case cmdRoleUpdate.FullCommand():
+			err = uc.UpdateRole()
+		case cmdRoleDrop.FullCommand():
+			err = uc.DropRole()
+		case cmdRoleList.FullCommand():
+			var roles []*user_json.CustomRole
+			roles, err = uc.ListRoles()
+			if err == nil {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				err = encoder.Encode(roles)
+			}
+		}
+		if err != nil {
+			handleFailed(err)
+		}
+	case cmdRestore.FullCommand():
+		if enableSecrets {
+			cnf.Restore.Password = internal.PasswordFromFile(
+				os.Getenv(dcos.EnvMesosSandbox),
+				cnf.Restore.Password,
+				"backup",
+			)
+		}
+		storage, err := backup.NewStorage(cnf.Restore.Storage)
+		if err != nil {
+			handleFailed(err)
+		}
+		_, err = restore.New(cnf, api.New(cnf.Restore.API), storage).Run(ctx)
+		if err != nil {
+			handleFailed(err)
+		}
 	}
 }
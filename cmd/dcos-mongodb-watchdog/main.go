@@ -17,7 +17,6 @@ package main
 import (
 	"net/http"
 	"os"
-	"os/signal"
 	"syscall"
 
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
@@ -47,6 +46,7 @@ func runPrometheusMetricsServer(collector prometheus.Collector) {
 	}).Info("Starting Prometheus metrics server")
 
 	prometheus.MustRegister(collector)
+	prometheus.MustRegister(db.AuthFallbacksTotal)
 
 	http.Handle(metricsPath, promhttp.Handler())
 	log.Fatal(http.ListenAndServe(metricsListen, nil))
@@ -62,12 +62,12 @@ func main() {
 	}
 	app.Flag(
 		"username",
-		"MongoDB clusterAdmin username, this flag or env var "+pkg.EnvMongoDBClusterAdminUser+" is required",
-	).Envar(pkg.EnvMongoDBClusterAdminUser).Required().StringVar(&cnf.Username)
+		"MongoDB clusterAdmin username, this flag, env var "+pkg.EnvMongoDBClusterAdminUser+" or a --uri with credentials is required",
+	).Envar(pkg.EnvMongoDBClusterAdminUser).StringVar(&cnf.Username)
 	app.Flag(
 		"password",
-		"MongoDB clusterAdmin password, this flag or env var "+pkg.EnvMongoDBClusterAdminPassword+" is required",
-	).Envar(pkg.EnvMongoDBClusterAdminPassword).Required().StringVar(&cnf.Password)
+		"MongoDB clusterAdmin password, this flag, env var "+pkg.EnvMongoDBClusterAdminPassword+" or a --uri with credentials is required",
+	).Envar(pkg.EnvMongoDBClusterAdminPassword).StringVar(&cnf.Password)
 	//app.Flag(
 	//	"enableSecrets",
 	//	"enable DC/OS Secrets, this causes passwords to be loaded from files, overridden by env var "+dcos.EnvSecretsEnabled,
@@ -92,6 +92,10 @@ func main() {
 		"replsetTimeout",
 		"MongoDB connect timeout, should be less than 'replsetPoll', overridden by env var WATCHDOG_REPLSET_TIMEOUT",
 	).Default(config.DefaultReplsetTimeout).Envar("WATCHDOG_REPLSET_TIMEOUT").DurationVar(&cnf.ReplsetTimeout)
+	app.Flag(
+		"certReloadPoll",
+		"Frequency of checks for changed SSL/TLS certificate files and updates of certificate expiry metrics, overridden by env var WATCHDOG_CERT_RELOAD_POLL",
+	).Default(config.DefaultCertReloadPoll).Envar("WATCHDOG_CERT_RELOAD_POLL").DurationVar(&cnf.CertReloadPoll)
 	app.Flag(
 		"apiHost",
 		"DC/OS SDK API hostname, overridden by env var "+dcos.EnvSchedulerAPIHost,
@@ -110,6 +114,11 @@ func main() {
 	).Default(config.DefaultMetricsPath).StringVar(&metricsPath)
 
 	cnf.SSL = db.NewSSLConfig(app)
+	cnf.Pool = db.NewPoolConfig(app)
+	db.NewAuthMechanismFlag(app, &cnf.AuthMechanism)
+	db.NewDriverFlag(app, &cnf.Driver)
+	db.NewAuthFallbackFlag(app, &cnf.AuthFallback)
+	db.NewUriFlag(app, cnf.ApplyUri)
 
 	_, err := app.Parse(os.Args[1:])
 	if err != nil {
@@ -125,20 +134,17 @@ func main() {
 
 	apiClient := api.New(cnf.API)
 	wMetrics := metrics.NewCollector()
-	quit := make(chan bool)
-	watchdog := watchdog.New(cnf, apiClient, wMetrics, quit)
-	go watchdog.Run()
+	ctx, cancel := tool.NewSignalContext(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	watchdog := watchdog.New(cnf, apiClient, wMetrics)
+	go watchdog.Run(ctx)
 
 	if metricsListen != "" {
 		go runPrometheusMetricsServer(wMetrics)
 	}
 
 	// wait for signals from the OS
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	sig := <-signals
-	log.Infof("Received %s signal, killing watchdog", sig)
-
-	// send quit to all goroutines
-	close(quit)
+	<-ctx.Done()
+	log.Info("Killing watchdog")
 }
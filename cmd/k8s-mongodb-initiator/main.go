@@ -17,6 +17,7 @@ package main
 import (
 	"os"
 	"strconv"
+	"syscall"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/percona/mongodb-orchestration-tools/controller"
@@ -85,8 +86,8 @@ func main() {
 
 	app.Flag(
 		"replset",
-		"mongodb replica set name, this flag or env var "+pkg.EnvMongoDBReplset+" is required",
-	).Envar(pkg.EnvMongoDBReplset).Required().StringVar(&cnf.Replset)
+		"mongodb replica set name, this flag, env var "+pkg.EnvMongoDBReplset+" or a --uri with a replicaSet is required",
+	).Envar(pkg.EnvMongoDBReplset).StringVar(&cnf.Replset)
 
 	app.Flag(
 		"ip",
@@ -101,14 +102,18 @@ func main() {
 	app.Flag(
 		"userAdminUser",
 		"mongodb userAdmin username, overridden by env var "+pkg.EnvMongoDBUserAdminUser,
-	).Envar(pkg.EnvMongoDBUserAdminUser).Required().StringVar(&cnf.UserAdminUser)
+	).Envar(pkg.EnvMongoDBUserAdminUser).StringVar(&cnf.UserAdminUser)
 
 	app.Flag(
 		"userAdminPassword",
 		"mongodb userAdmin password or path to file containing it, overridden by env var "+pkg.EnvMongoDBUserAdminPassword,
-	).Envar(pkg.EnvMongoDBUserAdminPassword).Required().StringVar(&cnf.UserAdminPassword)
+	).Envar(pkg.EnvMongoDBUserAdminPassword).StringVar(&cnf.UserAdminPassword)
 
 	cnf.SSL = db.NewSSLConfig(app)
+	db.NewAuthMechanismFlag(app, &cnf.AuthMechanism)
+	db.NewDriverFlag(app, &cnf.Driver)
+	db.NewAuthFallbackFlag(app, &cnf.AuthFallback)
+	db.NewUriFlag(app, cnf.ApplyUri)
 
 	handleInitCmd(app, cnf)
 
@@ -120,6 +125,9 @@ func main() {
 		log.Fatalf("Port must be > 1024")
 	}
 
+	ctx, cancel := tool.NewSignalContext(syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
 	switch command {
 	case cmdInit.FullCommand():
 		var host string
@@ -131,7 +139,7 @@ func main() {
 
 		cnf.ReplsetInit.PrimaryAddr = host + ":" + strconv.Itoa(port)
 
-		err := replset.NewInitiator(cnf).Run()
+		err := replset.NewInitiator(cnf).Run(ctx)
 		if err != nil {
 			log.Fatalf("Error initiating replset: %v", err)
 		}
@@ -15,21 +15,29 @@
 package main
 
 import (
+	"context"
 	"os"
-	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/percona/mongodb-orchestration-tools/executor"
+	"github.com/percona/mongodb-orchestration-tools/executor/backup"
 	"github.com/percona/mongodb-orchestration-tools/executor/config"
+	"github.com/percona/mongodb-orchestration-tools/executor/exporter"
 	"github.com/percona/mongodb-orchestration-tools/executor/job"
 	"github.com/percona/mongodb-orchestration-tools/executor/metrics"
 	"github.com/percona/mongodb-orchestration-tools/executor/mongodb"
+	"github.com/percona/mongodb-orchestration-tools/executor/pitr"
+	"github.com/percona/mongodb-orchestration-tools/executor/pmm"
+	"github.com/percona/mongodb-orchestration-tools/executor/profiler"
 	"github.com/percona/mongodb-orchestration-tools/internal"
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos"
 	"github.com/percona/mongodb-orchestration-tools/internal/tool"
 	"github.com/percona/mongodb-orchestration-tools/pkg"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -42,12 +50,16 @@ var (
 func handleMongoDB(app *kingpin.Application, cnf *config.Config) {
 	app.Flag(
 		"mongodb.totalMemoryMB",
-		"the total amount of system memory, in megabytes",
-	).Envar(dcos.EnvMongoDBMemoryMB).Required().UintVar(&cnf.MongoDB.TotalMemoryMB)
+		"the total amount of system memory, in megabytes, defaults to "+dcos.EnvMongoDBMemoryMB+" env var if set, otherwise detected from the cgroup memory limit or /proc/meminfo",
+	).Envar(dcos.EnvMongoDBMemoryMB).UintVar(&cnf.MongoDB.TotalMemoryMB)
 	app.Flag(
 		"mongodb.configDir",
 		"path to mongodb instance config file, defaults to $"+dcos.EnvMesosSandbox+" if available, otherwise "+mongodb.DefaultConfigDirFallback,
 	).Default(mongodb.DefaultConfigDirFallback).Envar(dcos.EnvMesosSandbox).StringVar(&cnf.MongoDB.ConfigDir)
+	app.Flag(
+		"mongodb.configOverlayFile",
+		"optional YAML file of mongod config keys to layer on top of the config file, env vars such as "+mongodb.ConfigKeyEnvVar("net.maxIncomingConnections")+" have precedence, defaults to "+dcos.EnvMongoDBConfigOverlayFile+" env var",
+	).Envar(dcos.EnvMongoDBConfigOverlayFile).ExistingFileVar(&cnf.MongoDB.ConfigOverlayFile)
 	app.Flag(
 		"mongodb.binDir",
 		"path to mongodb binary directory",
@@ -71,6 +83,33 @@ func handleMongoDB(app *kingpin.Application, cnf *config.Config) {
 		"mongodb.wiredTigerCacheRatio",
 		"the ratio of system memory to be used for wiredTiger cache",
 	).Default(mongodb.DefaultWiredTigerCacheRatio).Envar(dcos.EnvMongoDBWiredTigerCacheSizeRatio).Float64Var(&cnf.MongoDB.WiredTigerCacheRatio)
+	app.Flag(
+		"mongodb.logFile",
+		"file to write the mongod output to, rotated by size, defaults to $"+dcos.EnvMesosSandbox+"/mongod.log if available, otherwise disabled",
+	).Default(dcos.MesosSandboxPathOrFallback(
+		"mongod.log",
+		"",
+	)).StringVar(&cnf.MongoDB.LogFile)
+	app.Flag(
+		"mongodb.logMaxSizeMB",
+		"the size of the mongod log file to rotate at, in megabytes",
+	).Default(mongodb.DefaultLogMaxSizeMB).IntVar(&cnf.MongoDB.LogMaxSizeMB)
+	app.Flag(
+		"mongodb.logMaxBackups",
+		"the number of rotated mongod log files to keep",
+	).Default(mongodb.DefaultLogMaxBackups).IntVar(&cnf.MongoDB.LogMaxBackups)
+	app.Flag(
+		"mongodb.logPrefix",
+		"prefix for the lines of mongod output written to the executor output",
+	).StringVar(&cnf.MongoDB.LogPrefix)
+	app.Flag(
+		"mongodb.parseJSONLog",
+		"parse MongoDB 4.4+ structured JSON logs, re-logging warnings and errors and counting entries in metrics",
+	).BoolVar(&cnf.MongoDB.ParseJSONLog)
+	app.Flag(
+		"mongodb.preflightMode",
+		"the mode of the host tuning checks before starting mongodb: 'warn' logs problems, 'fix' also raises resource limits, 'strict' also refuses to start on problems, defaults to "+dcos.EnvMongoDBPreflightMode+" env var",
+	).Default(mongodb.DefaultPreflightMode).Envar(dcos.EnvMongoDBPreflightMode).EnumVar(&cnf.MongoDB.PreflightMode, mongodb.PreflightModes...)
 }
 
 func handleMetrics(app *kingpin.Application, cnf *config.Config) {
@@ -90,6 +129,223 @@ func handleMetrics(app *kingpin.Application, cnf *config.Config) {
 		"metrics.statsd_port",
 		"The frequency to send metrics to DC/OS Metrics service, defaults to "+dcos.EnvMetricsStatsdPort+" env var",
 	).Envar(dcos.EnvMetricsStatsdPort).IntVar(&cnf.Metrics.StatsdPort)
+	app.Flag(
+		"metrics.sink",
+		"The metrics sink(s) to push to, may be repeated to fan out to multiple sinks. Options: "+strings.Join(metrics.Sinks, ", "),
+	).Default(metrics.DefaultSink).EnumsVar(&cnf.Metrics.Sinks, metrics.Sinks...)
+	app.Flag(
+		"metrics.tag",
+		"A key=value tag to add to metrics of the dogstatsd, influxdb and otlp sinks, may be repeated",
+	).StringMapVar(&cnf.Metrics.Tags)
+	app.Flag(
+		"metrics.influxdb_url",
+		"The url of the influxdb sink, either udp://host:port or http(s)://host:port/write?db=name",
+	).StringVar(&cnf.Metrics.InfluxDBURL)
+	app.Flag(
+		"metrics.otlp_url",
+		"The OTLP/HTTP metrics url of the otlp sink, eg: http://localhost:4318/v1/metrics",
+	).StringVar(&cnf.Metrics.OTLPURL)
+}
+
+func handleExporter(app *kingpin.Application, cnf *config.Config) {
+	app.Flag(
+		"exporter.enable",
+		"Enable the Prometheus exporter for MongoDB, defaults to "+dcos.EnvPrometheusEnabled+" env var",
+	).Envar(dcos.EnvPrometheusEnabled).BoolVar(&cnf.Exporter.Enabled)
+	app.Flag(
+		"exporter.listen",
+		"The listen address of the Prometheus exporter, defaults to "+dcos.EnvPrometheusListen+" env var",
+	).Default(exporter.DefaultListen).Envar(dcos.EnvPrometheusListen).StringVar(&cnf.Exporter.Listen)
+	app.Flag(
+		"exporter.path",
+		"The HTTP path of the Prometheus exporter metrics",
+	).Default(exporter.DefaultPath).StringVar(&cnf.Exporter.Path)
+	app.Flag(
+		"exporter.interval",
+		"The frequency to gather metrics for the Prometheus exporter, defaults to "+dcos.EnvPrometheusInterval+" env var",
+	).Default(exporter.DefaultInterval).Envar(dcos.EnvPrometheusInterval).DurationVar(&cnf.Exporter.Interval)
+}
+
+func handleBackup(app *kingpin.Application, cnf *config.Config) {
+	app.Flag(
+		"backup.enable",
+		"Enable scheduled backups on the dedicated backup member, defaults to "+dcos.EnvBackupEnabled+" env var",
+	).Envar(dcos.EnvBackupEnabled).BoolVar(&cnf.Backup.Enabled)
+	app.Flag(
+		"backup.schedule",
+		"The cron schedule of backups in UTC, eg: '0 0 * * *' or '@daily', defaults to "+dcos.EnvBackupSchedule+" env var",
+	).Default(backup.DefaultSchedule).Envar(dcos.EnvBackupSchedule).StringVar(&cnf.Backup.Schedule)
+	app.Flag(
+		"backup.storage",
+		"The storage of backup archives, defaults to "+dcos.EnvBackupStorage+" env var. Options: "+strings.Join(backupStorage.Storages, ", "),
+	).Default(backupStorage.DefaultStorage).Envar(dcos.EnvBackupStorage).EnumVar(&cnf.Backup.Storage.Storage, backupStorage.Storages...)
+	app.Flag(
+		"backup.retention",
+		"The number of backup archives to keep, 0 is unlimited, defaults to "+dcos.EnvBackupRetention+" env var",
+	).Default(backup.DefaultRetention).Envar(dcos.EnvBackupRetention).IntVar(&cnf.Backup.Retention)
+	app.Flag(
+		"backup.timeout",
+		"The maximum duration of a backup",
+	).Default(backup.DefaultTimeout).DurationVar(&cnf.Backup.Timeout)
+	app.Flag(
+		"backup.localPath",
+		"The directory of the local backup storage, defaults to $"+dcos.EnvMesosSandbox+"/backup if available, otherwise "+backupStorage.DefaultLocalPathFallback,
+	).Default(dcos.MesosSandboxPathOrFallback(
+		"backup",
+		backupStorage.DefaultLocalPathFallback,
+	)).StringVar(&cnf.Backup.Storage.LocalPath)
+	app.Flag(
+		"backup.tmpDir",
+		"The directory of the temporary files of backups, defaults to $"+dcos.EnvMesosSandbox+"/tmp if available, otherwise "+mongodb.DefaultTmpDirFallback,
+	).Default(dcos.MesosSandboxPathOrFallback(
+		"tmp",
+		mongodb.DefaultTmpDirFallback,
+	)).StringVar(&cnf.Backup.TmpDir)
+	app.Flag(
+		"backup.mongodumpBin",
+		"The path to the mongodump binary",
+	).Default(backup.DefaultMongodumpBin).StringVar(&cnf.Backup.MongodumpBin)
+	app.Flag(
+		"backup.username",
+		"The mongodb backup username, defaults to "+pkg.EnvMongoDBBackupUser+" env var",
+	).Envar(pkg.EnvMongoDBBackupUser).StringVar(&cnf.Backup.Username)
+	app.Flag(
+		"backup.password",
+		"The mongodb backup password, defaults to "+pkg.EnvMongoDBBackupPassword+" env var",
+	).Envar(pkg.EnvMongoDBBackupPassword).StringVar(&cnf.Backup.Password)
+	app.Flag(
+		"backup.s3.endpoint",
+		"The url of the S3-compatible endpoint of the s3 backup storage, defaults to "+dcos.EnvBackupS3Endpoint+" env var",
+	).Default(backupStorage.DefaultS3Endpoint).Envar(dcos.EnvBackupS3Endpoint).StringVar(&cnf.Backup.Storage.S3.Endpoint)
+	app.Flag(
+		"backup.s3.region",
+		"The region of the s3 backup storage, defaults to "+dcos.EnvBackupS3Region+" env var",
+	).Default(backupStorage.DefaultS3Region).Envar(dcos.EnvBackupS3Region).StringVar(&cnf.Backup.Storage.S3.Region)
+	app.Flag(
+		"backup.s3.bucket",
+		"The bucket of the s3 backup storage, defaults to "+dcos.EnvBackupS3Bucket+" env var",
+	).Envar(dcos.EnvBackupS3Bucket).StringVar(&cnf.Backup.Storage.S3.Bucket)
+	app.Flag(
+		"backup.s3.prefix",
+		"The key prefix of backup archives in the s3 bucket, defaults to "+dcos.EnvBackupS3Prefix+" env var",
+	).Envar(dcos.EnvBackupS3Prefix).StringVar(&cnf.Backup.Storage.S3.Prefix)
+	app.Flag(
+		"backup.s3.accessKey",
+		"The access key of the s3 backup storage, defaults to "+dcos.EnvBackupS3AccessKey+" env var",
+	).Envar(dcos.EnvBackupS3AccessKey).StringVar(&cnf.Backup.Storage.S3.AccessKey)
+	app.Flag(
+		"backup.s3.secretKey",
+		"The secret key of the s3 backup storage, defaults to "+dcos.EnvBackupS3SecretKey+" env var",
+	).Envar(dcos.EnvBackupS3SecretKey).StringVar(&cnf.Backup.Storage.S3.SecretKey)
+}
+
+func handlePITR(app *kingpin.Application, cnf *config.Config) {
+	app.Flag(
+		"pitr.enable",
+		"Enable oplog tailing for point-in-time recovery on the dedicated backup member, uses the backup storage and user, defaults to "+dcos.EnvPITREnabled+" env var",
+	).Envar(dcos.EnvPITREnabled).BoolVar(&cnf.PITR.Enabled)
+	app.Flag(
+		"pitr.chunkInterval",
+		"The maximum duration of oplog entries in a stored chunk, defaults to "+dcos.EnvPITRChunkInterval+" env var",
+	).Default(pitr.DefaultChunkInterval).Envar(dcos.EnvPITRChunkInterval).DurationVar(&cnf.PITR.ChunkInterval)
+	app.Flag(
+		"pitr.chunkMaxBytes",
+		"The maximum uncompressed size of oplog entries in a stored chunk, in bytes",
+	).Default(pitr.DefaultChunkMaxBytes).IntVar(&cnf.PITR.ChunkMaxBytes)
+	app.Flag(
+		"pitr.retrySleep",
+		"Amount of time to wait before retrying oplog tailing after an error",
+	).Default(pitr.DefaultRetrySleep).DurationVar(&cnf.PITR.RetrySleep)
+	app.Flag(
+		"pitr.retention",
+		"The age of stored oplog chunks to keep, older chunks are deleted, 0 is unlimited, defaults to "+dcos.EnvPITRRetention+" env var",
+	).Default(pitr.DefaultRetention).Envar(dcos.EnvPITRRetention).DurationVar(&cnf.PITR.Retention)
+}
+
+func handlePMM(app *kingpin.Application, cnf *config.Config) {
+	app.Flag(
+		"pmm.enable",
+		"Enable registration of the node with a Percona Monitoring and Management server, defaults to "+dcos.EnvPMMEnabled+" env var",
+	).Envar(dcos.EnvPMMEnabled).BoolVar(&cnf.PMM.Enabled)
+	app.Flag(
+		"pmm.enableQueryAnalytics",
+		"Enable PMM Query Analytics, defaults to "+dcos.EnvPMMEnableQueryAnalytics+" env var",
+	).Envar(dcos.EnvPMMEnableQueryAnalytics).BoolVar(&cnf.PMM.EnableQueryAnalytics)
+	app.Flag(
+		"pmm.serverAddress",
+		"The address of the PMM server, defaults to "+dcos.EnvPMMServerAddress+" env var",
+	).Envar(dcos.EnvPMMServerAddress).StringVar(&cnf.PMM.ServerAddress)
+	app.Flag(
+		"pmm.clientName",
+		"The name of the node in PMM, defaults to "+dcos.EnvPMMClientName+" env var or the hostname",
+	).Envar(dcos.EnvPMMClientName).StringVar(&cnf.PMM.ClientName)
+	app.Flag(
+		"pmm.serverSSL",
+		"Enable SSL to communicate with the PMM server, defaults to "+dcos.EnvPMMServerSSL+" env var",
+	).Envar(dcos.EnvPMMServerSSL).BoolVar(&cnf.PMM.ServerSSL)
+	app.Flag(
+		"pmm.serverInsecureSSL",
+		"Enable insecure SSL (self-signed certificate) to communicate with the PMM server, defaults to "+dcos.EnvPMMServerInsecureSSL+" env var",
+	).Envar(dcos.EnvPMMServerInsecureSSL).BoolVar(&cnf.PMM.ServerInsecureSSL)
+	app.Flag(
+		"pmm.mongodbClusterName",
+		"The MongoDB cluster name in PMM, defaults to "+dcos.EnvPMMMongoDBClusterName+" env var",
+	).Envar(dcos.EnvPMMMongoDBClusterName).StringVar(&cnf.PMM.MongoDBClusterName)
+	app.Flag(
+		"pmm.linuxMetricsExporterPort",
+		"The port of the PMM linux metrics exporter, defaults to "+dcos.EnvPMMLinuxMetricsExporterPort+" env var",
+	).Default(pmm.DefaultLinuxMetricsExporterPort).Envar(dcos.EnvPMMLinuxMetricsExporterPort).UintVar(&cnf.PMM.LinuxMetricsExporterPort)
+	app.Flag(
+		"pmm.mongodbMetricsExporterPort",
+		"The port of the PMM MongoDB metrics exporter, defaults to "+dcos.EnvPMMMongoDBMetricsExporterPort+" env var",
+	).Default(pmm.DefaultMongoDBMetricsExporterPort).Envar(dcos.EnvPMMMongoDBMetricsExporterPort).UintVar(&cnf.PMM.MongoDBMetricsExporterPort)
+	app.Flag(
+		"pmm.pmmAdminBin",
+		"The path to the pmm-admin binary",
+	).Default(pmm.DefaultPMMAdminBin).StringVar(&cnf.PMM.PMMAdminBin)
+	app.Flag(
+		"pmm.registerBackoffMin",
+		"initial time to wait before retrying a failed PMM server registration, doubled on each failure",
+	).Default(pmm.DefaultRegisterBackoffMin).DurationVar(&cnf.PMM.RegisterBackoffMin)
+	app.Flag(
+		"pmm.registerBackoffMax",
+		"maximum time to wait before retrying a failed PMM server registration",
+	).Default(pmm.DefaultRegisterBackoffMax).DurationVar(&cnf.PMM.RegisterBackoffMax)
+}
+
+func handleProfiler(app *kingpin.Application, cnf *config.Config) {
+	app.Flag(
+		"profiler.enable",
+		"Enable management of the database profiler settings of all databases, defaults to "+dcos.EnvProfilerEnabled+" env var",
+	).Envar(dcos.EnvProfilerEnabled).BoolVar(&cnf.Profiler.Enabled)
+	app.Flag(
+		"profiler.level",
+		"The database profiler level: 0 (off), 1 (slow operations) or 2 (all operations), defaults to "+dcos.EnvProfilerLevel+" env var",
+	).Default(profiler.DefaultLevel).Envar(dcos.EnvProfilerLevel).IntVar(&cnf.Profiler.Level)
+	app.Flag(
+		"profiler.slowms",
+		"The threshold of slow operations in milliseconds, defaults to "+dcos.EnvProfilerSlowMs+" env var",
+	).Default(profiler.DefaultSlowMs).Envar(dcos.EnvProfilerSlowMs).IntVar(&cnf.Profiler.SlowMs)
+	app.Flag(
+		"profiler.sampleRate",
+		"The fraction of slow operations to profile, requires MongoDB 3.6+, defaults to "+dcos.EnvProfilerSampleRate+" env var",
+	).Default(profiler.DefaultSampleRate).Envar(dcos.EnvProfilerSampleRate).Float64Var(&cnf.Profiler.SampleRate)
+	app.Flag(
+		"profiler.interval",
+		"The frequency to apply the profiler settings to new databases and to drain the profiler",
+	).Default(profiler.DefaultInterval).DurationVar(&cnf.Profiler.Interval)
+	app.Flag(
+		"profiler.drainFile",
+		"Optional file to append the system.profile entries of all databases to as JSON lines, defaults to "+dcos.EnvProfilerDrainFile+" env var",
+	).Envar(dcos.EnvProfilerDrainFile).StringVar(&cnf.Profiler.DrainFile)
+	app.Flag(
+		"profiler.username",
+		"The mongodb user to manage the profiler as, requires the 'enableProfiler' action on all databases, defaults to the executor user",
+	).StringVar(&cnf.Profiler.Username)
+	app.Flag(
+		"profiler.password",
+		"The password of the profiler mongodb user",
+	).StringVar(&cnf.Profiler.Password)
 }
 
 func main() {
@@ -102,13 +358,26 @@ func main() {
 		pkg.EnvMongoDBClusterMonitorUser,
 		pkg.EnvMongoDBClusterMonitorPassword,
 	)
+	db.NewDriverFlag(app, &dbConfig.Driver)
 	cnf := &config.Config{
 		DB:      dbConfig,
 		MongoDB: &mongodb.Config{},
 		Metrics: &metrics.Config{
 			DB: dbConfig,
 		},
-		Verbose: *verbose,
+		Exporter: &exporter.Config{},
+		Backup: &backup.Config{
+			DB: dbConfig,
+			Storage: &backupStorage.StorageConfig{
+				S3: &backupStorage.S3Config{},
+			},
+		},
+		PITR: &pitr.Config{},
+		PMM: &pmm.Config{
+			DB: dbConfig,
+		},
+		Profiler: &profiler.Config{},
+		Verbose:  *verbose,
 	}
 
 	app.Flag(
@@ -123,6 +392,22 @@ func main() {
 		"delayBackgroundJobs",
 		"Amount of time to delay running of executor background jobs",
 	).Default(config.DefaultDelayBackgroundJob).DurationVar(&cnf.DelayBackgroundJob)
+	app.Flag(
+		"jobs.restartPolicy",
+		"Policy for restarting executor background jobs that panic, fail or exit. Options: "+strings.Join(job.RestartPolicies, ", "),
+	).Default(config.DefaultJobRestartPolicy).EnumVar(&cnf.JobRestartPolicy, job.RestartPolicies...)
+	app.Flag(
+		"jobs.restartDelay",
+		"Amount of time to wait before restarting an executor background job",
+	).Default(config.DefaultJobRestartDelay).DurationVar(&cnf.JobRestartDelay)
+	app.Flag(
+		"jobs.maxRestarts",
+		"Maximum number of restarts of an executor background job, 0 is unlimited",
+	).Default(config.DefaultJobMaxRestarts).IntVar(&cnf.JobMaxRestarts)
+	app.Flag(
+		"jobs.stopTimeout",
+		"Amount of time to wait for executor background jobs to stop on shutdown",
+	).Default(config.DefaultJobStopTimeout).DurationVar(&cnf.JobStopTimeout)
 	app.Flag(
 		"enableSecrets",
 		"enable secrets, this causes passwords to be loaded from files, overridden by env var "+dcos.EnvSecretsEnabled,
@@ -130,6 +415,11 @@ func main() {
 
 	handleMongoDB(app, cnf)
 	handleMetrics(app, cnf)
+	handleExporter(app, cnf)
+	handleBackup(app, cnf)
+	handlePITR(app, cnf)
+	handlePMM(app, cnf)
+	handleProfiler(app, cnf)
 
 	nodeType, err := app.Parse(os.Args[1:])
 	if err != nil {
@@ -142,17 +432,36 @@ func main() {
 			cnf.DB.DialInfo.Password,
 			"password",
 		)
+		if cnf.Backup.Enabled || cnf.PITR.Enabled {
+			cnf.Backup.Password = internal.PasswordFromFile(
+				os.Getenv(dcos.EnvMesosSandbox),
+				cnf.Backup.Password,
+				"backup",
+			)
+		}
+		if cnf.Profiler.Enabled && cnf.Profiler.Username != "" {
+			cnf.Profiler.Password = internal.PasswordFromFile(
+				os.Getenv(dcos.EnvMesosSandbox),
+				cnf.Profiler.Password,
+				"profiler",
+			)
+		}
 	}
 
-	quit := make(chan bool, 1)
-	e := executor.New(cnf, &quit)
+	ctx, cancel := tool.NewSignalContext(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	e := executor.New(cnf)
 
 	var daemon executor.Daemon
+	var collectors []prometheus.Collector
 	daemonState := make(chan *os.ProcessState, 1)
 
 	switch cnf.NodeType {
 	case config.NodeTypeMongod:
-		daemon = mongodb.NewMongod(cnf.MongoDB, daemonState)
+		mongod := mongodb.NewMongod(cnf.MongoDB, daemonState)
+		collectors = append(collectors, mongod.Collector())
+		daemon = mongod
 	case config.NodeTypeMongos:
 		log.Fatalf("mongos nodes are not supported yet!")
 	default:
@@ -166,7 +475,10 @@ func main() {
 	}
 
 	// wait for Daemon to become available
-	session, err := db.WaitForSession(
+	pool := db.NewPool(nil)
+	defer pool.Close()
+	session, err := pool.WaitForSession(
+		ctx,
 		cnf.DB,
 		0,
 		cnf.ConnectRetrySleep,
@@ -177,16 +489,21 @@ func main() {
 	defer session.Close()
 
 	// start job Runner
-	go job.New(cnf, session, &quit).Run()
-
-	// wait for signals from the OS
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	runner := job.New(cnf, pool)
+	runner.Register(collectors...)
+	go runner.Run(ctx)
+	stopJobs := func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), cnf.JobStopTimeout)
+		defer stopCancel()
+		if err := runner.Stop(stopCtx); err != nil {
+			log.Errorf("Error stopping background jobs: %s", err)
+		}
+	}
 
 	// wait for OS signal or daemonState (*os.ProcessState from daemon process)
 	select {
 	case state := <-daemonState:
-		quit <- true
+		stopJobs()
 
 		logFields := log.Fields{
 			"success": state.Success(),
@@ -199,8 +516,8 @@ func main() {
 		}
 
 		log.WithFields(logFields).Fatalf("Unexpected die/exit from %s with status: %s", daemon.Name(), state.String())
-	case sig := <-signals:
-		quit <- true
-		log.Infof("Received %s signal, killing %s daemon and jobs", sig, daemon.Name())
+	case <-ctx.Done():
+		log.Infof("Killing %s daemon and jobs", daemon.Name())
+		stopJobs()
 	}
 }
@@ -46,7 +46,16 @@ func main() {
 	k8sCmd := app.Command("k8s", "Performs liveness check for MongoDB on Kubernetes")
 	livenessCmd := k8sCmd.Command("liveness", "Run a liveness check of MongoDB").Default()
 	_ = k8sCmd.Command("readiness", "Run a readiness check of MongoDB")
+	startupCmd := k8sCmd.Command("startup", "Run a startup check of MongoDB, tracking initial sync progress")
 	startupDelaySeconds := livenessCmd.Flag("startupDelaySeconds", "").Default("7200").Uint64()
+	startupStallTimeout := startupCmd.Flag(
+		"stallTimeout",
+		"amount of time an initial sync may make no progress before the startup check fails",
+	).Default(healthcheck.DefaultStartupStallTimeout).Duration()
+	startupStateFile := startupCmd.Flag(
+		"stateFile",
+		"path to the file used to track initial sync progress between startup checks",
+	).Default(healthcheck.DefaultStartupStateFile).String()
 	component := k8sCmd.Flag("component", "").Default("mongod").String()
 
 	cnf := db.NewConfig(
@@ -54,6 +63,11 @@ func main() {
 		pkg.EnvMongoDBClusterMonitorUser,
 		pkg.EnvMongoDBClusterMonitorPassword,
 	)
+	db.NewDriverFlag(app, &cnf.Driver)
+
+	storageCnf := healthcheck.NewStorageConfig(app)
+	replCnf := healthcheck.NewReplicationConfig(app)
+	certCnf := healthcheck.NewCertExpiryConfig(app)
 
 	command, err := app.Parse(os.Args[1:])
 	if err != nil {
@@ -66,30 +80,52 @@ func main() {
 			"password",
 		)
 	}
+	// try an insecure SSL connection first, to avoid hostname validation errors
+	sslFlags := cnf.SSL
 	sslConf := db.SSLConfig{}
+	if sslFlags != nil {
+		sslConf = *sslFlags
+	}
 	cnf.SSL = &sslConf
 	cnf.SSL.Insecure = true
 
-	session, err := db.GetSession(cnf)
-	if err != nil {
-		log.Info("ssl connection error: " + err.Error())
-	}
-
-	if session == nil {
-		cnf.SSL = nil
-		session, err = db.GetSession(cnf)
-		if err != nil {
-			log.Fatalf("Error connecting to mongodb: %s", err)
-			return
+	session, err := db.NewSession(cnf)
+	if tlsErrs, ok := db.IsTLSError(err); ok && tlsErrs.Handshake() {
+		// the server does not accept our SSL connection, retry in plaintext. Certificate
+		// errors are not retried
+		for _, tlsErr := range tlsErrs.Errors {
+			log.WithFields(log.Fields{
+				"host": tlsErr.Host,
+				"kind": tlsErr.Kind,
+			}).Infof("ssl connection error, retrying without ssl: %s", tlsErr.Err)
 		}
+		cnf.SSL = nil
+		session, err = db.NewSession(cnf)
+	}
+	if err != nil {
+		log.Fatalf("Error connecting to mongodb: %s", err)
+		return
 	}
 
 	defer session.Close()
 
+	expiring, err := healthcheck.CertExpiryCheck(sslFlags, certCnf)
+	if err != nil {
+		log.Warnf("Cannot check SSL/TLS certificate expiry: %s", err)
+	}
+	for _, expiry := range expiring {
+		log.WithFields(log.Fields{
+			"type":      expiry.Type,
+			"name":      expiry.Name,
+			"subject":   expiry.Subject,
+			"not_after": expiry.NotAfter,
+		}).Warnf("SSL/TLS certificate expires in %.1f days", expiry.DaysUntilExpiry())
+	}
+
 	switch command {
 	case "dcos health":
 		log.Debug("Running DC/OS health check")
-		state, memberState, err := healthcheck.HealthCheck(session, healthcheck.OkMemberStates)
+		state, memberState, err := healthcheck.HealthCheck(session, healthcheck.OkMemberStates, replCnf)
 		if err != nil {
 			log.Debug(err.Error())
 			session.Close()
@@ -104,6 +140,17 @@ func main() {
 			session.Close()
 			os.Exit(state.ExitCode())
 		}
+		if storageCnf.Enabled {
+			state, stats, err := healthcheck.StorageCheck(session, storageCnf)
+			if stats != nil {
+				log.WithFields(stats.Fields()).Debug("Storage stats")
+			}
+			if err != nil {
+				log.Debug(err.Error())
+				session.Close()
+				os.Exit(state.ExitCode())
+			}
+		}
 		log.Debug("Member passed DC/OS readiness check")
 	case "k8s liveness":
 		log.Infof("Running Kubernetes liveness check for %s", *component)
@@ -124,13 +171,49 @@ func main() {
 				os.Exit(1)
 			}
 		}
+	case "k8s startup":
+		log.Infof("Running Kubernetes startup check for %s", *component)
+		switch *component {
+		case "mongod":
+			memberState, progress, err := healthcheck.HealthCheckMongodStartup(session, *startupStateFile, *startupStallTimeout)
+			if progress != nil {
+				log.WithFields(progress.Fields()).Info("Initial sync progress")
+			}
+			if err != nil {
+				log.Error(err.Error())
+				session.Close()
+				os.Exit(1)
+			}
+			log.Infof("Member passed Kubernetes startup check with replication state: %s", memberState)
+		case "mongos":
+			err := healthcheck.HealthCheckMongosLiveness(session)
+			if err != nil {
+				log.Error(err.Error())
+				session.Close()
+				os.Exit(1)
+			}
+		}
 	case "k8s readiness":
 		log.Infof("Running Kubernetes readiness check for %s", *component)
 		switch *component {
 		case "mongod":
-			log.Error("readiness check for mongod is not implemented")
-			session.Close()
-			os.Exit(1)
+			_, err := healthcheck.ReadinessCheck(session)
+			if err != nil {
+				log.Error(err.Error())
+				session.Close()
+				os.Exit(1)
+			}
+			if storageCnf.Enabled {
+				_, stats, err := healthcheck.StorageCheck(session, storageCnf)
+				if stats != nil {
+					log.WithFields(stats.Fields()).Info("Storage stats")
+				}
+				if err != nil {
+					log.Error(err.Error())
+					session.Close()
+					os.Exit(1)
+				}
+			}
 		case "mongos":
 			err := healthcheck.MongosReadinessCheck(session)
 			if err != nil {
@@ -15,10 +15,14 @@
 package controller
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/backup"
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api"
+	"github.com/percona/mongodb-orchestration-tools/pkg"
+	"gopkg.in/mgo.v2"
 )
 
 var (
@@ -27,6 +31,10 @@ var (
 	DefaultRetrySleep       = "3s"
 	DefaultMaxConnectTries  = "30"
 	DefaultInitMaxReplTries = "60"
+
+	DefaultRestoreArchive          = "latest"
+	DefaultRestoreProgressInterval = "10s"
+	DefaultMongorestoreBin         = "/usr/bin/mongorestore"
 )
 
 type ConfigReplsetInit struct {
@@ -43,17 +51,79 @@ type ConfigUser struct {
 	EndpointName    string
 	Database        string
 	Username        string
+	Role            string
 	File            string
+	Prune           bool
+	DryRun          bool
 	MaxConnectTries uint
 	RetrySleep      time.Duration
 }
 
+type ConfigRestore struct {
+	API              *api.Config
+	Storage          *backup.StorageConfig
+	EndpointName     string
+	Archive          string
+	NsInclude        []string
+	NsExclude        []string
+	Drop             bool
+	OplogReplay      bool
+	OplogLimit       string
+	MongorestoreBin  string
+	Username         string
+	Password         string
+	ProgressInterval time.Duration
+}
+
 type Config struct {
 	SSL               *db.SSLConfig
+	Pool              *db.PoolConfig
+	Driver            db.Driver
+	AuthMechanism     string
+	AuthFallback      bool
 	ServiceName       string
 	Replset           string
 	UserAdminUser     string
 	UserAdminPassword string
 	ReplsetInit       *ConfigReplsetInit
 	User              *ConfigUser
+	Restore           *ConfigRestore
+}
+
+// ApplyUri applies the userAdmin credentials, auth mechanism, replica set name and SSL options of the
+// mongodb connection string 'uri', if it is set. The hosts of the connection string
+// are not used, as the controller finds the replset members itself
+func (cnf *Config) ApplyUri(uri string) error {
+	if uri != "" {
+		dbCnf := &db.Config{
+			DialInfo: &mgo.DialInfo{
+				Username:       cnf.UserAdminUser,
+				Password:       cnf.UserAdminPassword,
+				Mechanism:      cnf.AuthMechanism,
+				ReplicaSetName: cnf.Replset,
+			},
+			SSL: cnf.SSL,
+		}
+		err := dbCnf.ApplyUri(uri)
+		if err != nil {
+			return err
+		}
+		cnf.UserAdminUser = dbCnf.DialInfo.Username
+		cnf.UserAdminPassword = dbCnf.DialInfo.Password
+		cnf.AuthMechanism = dbCnf.DialInfo.Mechanism
+		cnf.Replset = dbCnf.DialInfo.ReplicaSetName
+		cnf.SSL = dbCnf.SSL
+	}
+
+	if cnf.Replset == "" {
+		return fmt.Errorf("required flag --replset, env var %s or a --uri with a replicaSet not provided", pkg.EnvMongoDBReplset)
+	} else if cnf.AuthMechanism == db.AuthMechanismX509 {
+		// the username is the client certificate subject if not set
+		return nil
+	} else if cnf.UserAdminUser == "" {
+		return fmt.Errorf("required flag --userAdminUser, env var %s or a --uri with credentials not provided", pkg.EnvMongoDBUserAdminUser)
+	} else if cnf.UserAdminPassword == "" {
+		return fmt.Errorf("required flag --userAdminPassword, env var %s or a --uri with credentials not provided", pkg.EnvMongoDBUserAdminPassword)
+	}
+	return nil
 }
@@ -15,6 +15,7 @@
 package replset
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -59,7 +60,7 @@ func isError(err error, prefix string) bool {
 	return false
 }
 
-func (i *Initiator) initReplset(rsCnfMan rsConfig.Manager, out io.Writer) error {
+func (i *Initiator) initReplset(rsCnfMan db.ReplsetConfigManager, out io.Writer) error {
 	config := rsConfig.NewConfig(i.config.Replset)
 	member := rsConfig.NewMember(i.config.ReplsetInit.PrimaryAddr)
 	member.Tags = &rsConfig.ReplsetTags{
@@ -96,7 +97,7 @@ func (i *Initiator) initReplset(rsCnfMan rsConfig.Manager, out io.Writer) error
 	return nil
 }
 
-func (i *Initiator) initAdminUser(session *mgo.Session) error {
+func (i *Initiator) initAdminUser(session db.Session) error {
 	err := user.UpdateUser(session, user.UserAdmin, "admin")
 	if err != nil && !isError(err, ErrMsgNotAuthorizedPrefix) {
 		return err
@@ -104,7 +105,7 @@ func (i *Initiator) initAdminUser(session *mgo.Session) error {
 	return nil
 }
 
-func (i *Initiator) initUsers(session *mgo.Session) error {
+func (i *Initiator) initUsers(session db.Session) error {
 	systemUsers := user.SystemUsers()
 	if len(systemUsers) > 0 {
 		err := user.UpdateUsers(session, systemUsers, "admin")
@@ -115,21 +116,26 @@ func (i *Initiator) initUsers(session *mgo.Session) error {
 	return nil
 }
 
-func (i *Initiator) getSession() (*mgo.Session, error) {
-	session, err := i.getLocalhostSession(true)
-	if err != nil {
-		log.Info("ssl connection error: " + err.Error())
-	}
-	if session == nil {
-		session, err = i.getLocalhostSession(false)
-		if err != nil {
-			return nil, err
+func (i *Initiator) getSession(ctx context.Context) (db.Session, error) {
+	session, err := i.getLocalhostSession(ctx, true)
+	if tlsErrs, ok := db.IsTLSError(err); ok && tlsErrs.Handshake() {
+		// only fall back to plaintext when the server does not accept SSL connections,
+		// certificate errors are returned
+		for _, tlsErr := range tlsErrs.Errors {
+			log.WithFields(log.Fields{
+				"host": tlsErr.Host,
+				"kind": tlsErr.Kind,
+			}).Infof("ssl connection error, retrying without ssl: %s", tlsErr.Err)
 		}
+		session, err = i.getLocalhostSession(ctx, false)
+	}
+	if err != nil {
+		return nil, err
 	}
 	return session, nil
 }
 
-func (i Initiator) getLocalhostSession(secure bool) (*mgo.Session, error) {
+func (i Initiator) getLocalhostSession(ctx context.Context, secure bool) (db.Session, error) {
 	split := strings.SplitN(i.config.ReplsetInit.PrimaryAddr, ":", 2)
 	localhostHost := "localhost:" + split[1]
 	sslCnf := db.SSLConfig{}
@@ -140,6 +146,7 @@ func (i Initiator) getLocalhostSession(secure bool) (*mgo.Session, error) {
 			FailFast: true,
 			Timeout:  db.DefaultMongoDBTimeoutDuration,
 		},
+		Driver: i.config.Driver,
 	}
 	if secure {
 		if i.config.SSL != nil {
@@ -149,7 +156,8 @@ func (i Initiator) getLocalhostSession(secure bool) (*mgo.Session, error) {
 		dbConf.SSL = &sslCnf
 	}
 
-	session, err := db.WaitForSession(
+	session, err := db.WaitForNewSession(
+		ctx,
 		&dbConf,
 		i.config.ReplsetInit.MaxConnectTries,
 		i.config.ReplsetInit.RetrySleep,
@@ -169,7 +177,7 @@ func (i Initiator) getLocalhostSession(secure bool) (*mgo.Session, error) {
 	return session, nil
 }
 
-func (i *Initiator) getLocalhostNoAuthSession() (*mgo.Session, error) {
+func (i *Initiator) getLocalhostNoAuthSession(ctx context.Context) (db.Session, error) {
 	// if enabled, use an insecure SSL connection to avoid hostname validation error
 	// for the server hostname, only for the first connection.
 	sslCnfInsecure := db.SSLConfig{}
@@ -180,7 +188,8 @@ func (i *Initiator) getLocalhostNoAuthSession() (*mgo.Session, error) {
 
 	split := strings.SplitN(i.config.ReplsetInit.PrimaryAddr, ":", 2)
 	localhostHost := "localhost:" + split[1]
-	session, err := db.WaitForSession(
+	session, err := db.WaitForNewSession(
+		ctx,
 		&db.Config{
 			DialInfo: &mgo.DialInfo{
 				Addrs:    []string{localhostHost},
@@ -188,7 +197,8 @@ func (i *Initiator) getLocalhostNoAuthSession() (*mgo.Session, error) {
 				FailFast: true,
 				Timeout:  db.DefaultMongoDBTimeoutDuration,
 			},
-			SSL: &sslCnfInsecure,
+			SSL:    &sslCnfInsecure,
+			Driver: i.config.Driver,
 		},
 		i.config.ReplsetInit.MaxConnectTries,
 		i.config.ReplsetInit.RetrySleep,
@@ -208,19 +218,23 @@ func (i *Initiator) getLocalhostNoAuthSession() (*mgo.Session, error) {
 	return session, nil
 }
 
-func (i *Initiator) getReplsetSession() (*mgo.Session, error) {
-	session, err := db.WaitForSession(
+func (i *Initiator) getReplsetSession(ctx context.Context) (db.Session, error) {
+	session, err := db.WaitForNewSession(
+		ctx,
 		&db.Config{
 			DialInfo: &mgo.DialInfo{
 				Addrs:          []string{i.config.ReplsetInit.PrimaryAddr},
 				Username:       i.config.UserAdminUser,
 				Password:       i.config.UserAdminPassword,
+				Mechanism:      i.config.AuthMechanism,
 				ReplicaSetName: i.config.Replset,
 				Direct:         true,
 				FailFast:       true,
 				Timeout:        db.DefaultMongoDBTimeoutDuration,
 			},
-			SSL: i.config.SSL,
+			SSL:          i.config.SSL,
+			Driver:       i.config.Driver,
+			AuthFallback: i.config.AuthFallback,
 		},
 		i.config.ReplsetInit.MaxConnectTries,
 		i.config.ReplsetInit.RetrySleep,
@@ -240,14 +254,14 @@ func (i *Initiator) getReplsetSession() (*mgo.Session, error) {
 	return session, nil
 }
 
-func (i *Initiator) prepareReplset(session *mgo.Session, out io.Writer) error {
-	err := i.initReplset(rsConfig.New(session), out)
+func (i *Initiator) prepareReplset(ctx context.Context, session db.Session, out io.Writer) error {
+	err := i.initReplset(db.NewReplsetConfigManager(session), out)
 	if err != nil {
 		log.WithError(err).Error("Error intiating replica set")
 		return err
 	} else {
 		log.Info("Waiting for host to become primary")
-		err = db.WaitForPrimary(session, i.config.ReplsetInit.MaxConnectTries, i.config.ReplsetInit.RetrySleep)
+		err = db.WaitForPrimary(ctx, session, i.config.ReplsetInit.MaxConnectTries, i.config.ReplsetInit.RetrySleep)
 		if err != nil {
 			log.WithError(err).Error("Error getting waiting for primary session")
 			return err
@@ -261,7 +275,8 @@ func (i *Initiator) prepareReplset(session *mgo.Session, out io.Writer) error {
 	return nil
 }
 
-func (i *Initiator) Run() error {
+// Run initiates the replset, returning early if the context is done
+func (i *Initiator) Run(ctx context.Context) error {
 	log.WithFields(log.Fields{
 		"service": i.config.ServiceName,
 	}).Info("Mongod replset initiator started")
@@ -269,19 +284,23 @@ func (i *Initiator) Run() error {
 	log.WithFields(log.Fields{
 		"sleep": i.config.ReplsetInit.Delay,
 	}).Info("Waiting to start initiation")
-	time.Sleep(i.config.ReplsetInit.Delay)
+	select {
+	case <-time.After(i.config.ReplsetInit.Delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
 	// First we must use a localhost, no-authentication session
 	// so that we can use the MongoDB Localhost Exception:
 	// https://docs.mongodb.com/manual/core/security-users/#localhost-exception
-	localhostSession, err := i.getSession()
+	localhostSession, err := i.getSession(ctx)
 	if err != nil {
 		log.WithError(err).Error("Error getting localhost no-auth session")
 		return err
 	}
 	defer localhostSession.Close()
 
-	err = i.prepareReplset(localhostSession, os.Stdout)
+	err = i.prepareReplset(ctx, localhostSession, os.Stdout)
 	if err != nil {
 		if isError(err, ErrMsgNotAuthorizedPrefix) || isError(err, ErrMsgNotPrimary) || isError(err, ErrMsgRsInitRequiresAuth) {
 			log.Warning("Replset already initiated, skipping initiation")
@@ -294,7 +313,7 @@ func (i *Initiator) Run() error {
 	log.Info("Closing localhost connection, reconnecting with a replset+auth connection")
 	localhostSession.Close()
 
-	replsetAuthSession, err := i.getReplsetSession()
+	replsetAuthSession, err := i.getReplsetSession(ctx)
 	if err != nil {
 		log.WithError(err).Error("Error getting replica set session")
 		return err
@@ -16,6 +16,7 @@ package replset
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -31,7 +32,7 @@ import (
 )
 
 var (
-	testSession   *mgo.Session
+	testSession   db.Session
 	testInitiator *Initiator
 	testConfig    = &controller.Config{
 		SSL: &db.SSLConfig{},
@@ -42,12 +43,12 @@ func TestMain(m *testing.M) {
 	logger.SetupLogger(nil, logger.GetLogFormatter(), os.Stdout)
 
 	if testutils.Enabled() {
-		var err error
-		testSession, err = testutils.GetSession(testutils.MongodbPrimaryPort)
+		mgoSession, err := testutils.GetSession(testutils.MongodbPrimaryPort)
 		if err != nil {
 			fmt.Printf("Error getting session: %v", err)
 			os.Exit(1)
 		}
+		testSession = db.NewMgoSession(mgoSession)
 	}
 	exit := m.Run()
 	if testSession != nil {
@@ -118,18 +119,18 @@ func TestControllerReplsetInitiatorPrepareReplset(t *testing.T) {
 	//
 	// https://jira.percona.com/browse/CLOUD-46
 	var out bytes.Buffer
-	assert.NoError(t, i.prepareReplset(testSession, &out))
+	assert.NoError(t, i.prepareReplset(context.Background(), testSession, &out))
 
 	// test using a Secondary node
 	i.replInitTries = 0
 	i.config.ReplsetInit.PrimaryAddr = testutils.MongodbHost + ":" + testutils.MongodbSecondary1Port
-	testSecondarySession, err := i.getLocalhostNoAuthSession()
+	testSecondarySession, err := i.getLocalhostNoAuthSession(context.Background())
 	if err != nil {
 		t.Fatalf("cannot get secondary session: %v", err)
 	}
 	defer testSecondarySession.Close()
 
-	buildInfo, err := testSecondarySession.BuildInfo()
+	buildInfo, err := db.GetBuildInfo(testSecondarySession)
 	if err != nil {
 		t.Fatalf("cannot get secondary session build info: %v", err)
 	}
@@ -138,7 +139,7 @@ func TestControllerReplsetInitiatorPrepareReplset(t *testing.T) {
 	// part of an already-initiated replset
 	//
 	// https://jira.percona.com/browse/CLOUD-46
-	err = i.prepareReplset(testSecondarySession, &out)
+	err = i.prepareReplset(context.Background(), testSecondarySession, &out)
 	assert.Error(t, err)
 
 	// Check error msg based on version (error changes in 4.x)
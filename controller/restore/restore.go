@@ -0,0 +1,439 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/controller"
+	"github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrNoArchive = errors.New("no backup archive found")
+
+	summaryRegexp = regexp.MustCompile(`(\d+) document\(s\) restored successfully\. (\d+) document\(s\) failed to restore`)
+)
+
+const oplogFile = "oplog.bson"
+
+// Summary is the summary of a completed restore
+type Summary struct {
+	Archive  string
+	Bytes    int64
+	Duration time.Duration
+	Restored int64
+	Failed   int64
+	Chunks   int
+}
+
+// ParseOplogLimit parses a point in time as an RFC3339 time or a '<seconds>[:ordinal]'
+// timestamp into the '--oplogLimit' format of mongorestore
+func ParseOplogLimit(limit string) (string, error) {
+	if limit == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(time.RFC3339, limit); err == nil {
+		return strconv.FormatInt(t.Unix(), 10) + ":0", nil
+	}
+
+	parts := strings.SplitN(limit, ":", 2)
+	seconds, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid oplog limit %q: expected an RFC3339 time or <seconds>[:ordinal]", limit)
+	}
+	var ordinal uint64
+	if len(parts) == 2 {
+		ordinal, err = strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid oplog limit ordinal %q", limit)
+		}
+	}
+	return strconv.FormatUint(seconds, 10) + ":" + strconv.FormatUint(ordinal, 10), nil
+}
+
+// oplogLimitTimestamp returns the bson.MongoTimestamp of an oplog limit returned by ParseOplogLimit
+func oplogLimitTimestamp(oplogLimit string) bson.MongoTimestamp {
+	var seconds, ordinal uint32
+	fmt.Sscanf(oplogLimit, "%d:%d", &seconds, &ordinal)
+	return backup.NewTimestamp(seconds, ordinal)
+}
+
+// progressReader is an io.Reader that counts the bytes read
+type progressReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	atomic.AddInt64(&p.read, int64(n))
+	return n, err
+}
+
+func (p *progressReader) BytesRead() int64 {
+	return atomic.LoadInt64(&p.read)
+}
+
+// Restorer restores a backup archive with mongorestore. A restore to a point in time
+// replays the oplog captured in the archive by 'mongodump --oplog', then the oplog
+// chunks stored by the executor PITR job since the start of the archive
+type Restorer struct {
+	config  *controller.Config
+	api     api.Client
+	storage backup.Storage
+}
+
+func New(config *controller.Config, client api.Client, storage backup.Storage) *Restorer {
+	return &Restorer{
+		config:  config,
+		api:     client,
+		storage: storage,
+	}
+}
+
+// getArchive returns the name of the archive to restore, resolving 'latest' to the
+// newest backup archive of the replset
+func (r *Restorer) getArchive(ctx context.Context) (string, error) {
+	if r.config.Restore.Archive != controller.DefaultRestoreArchive {
+		return r.config.Restore.Archive, nil
+	}
+	names, err := r.storage.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	archives := backup.FilterArchives(names, r.config.Replset)
+	if len(archives) == 0 {
+		return "", ErrNoArchive
+	}
+	return archives[len(archives)-1], nil
+}
+
+// getHost returns the mongorestore host string of the replset, from the DC/OS SDK endpoint
+func (r *Restorer) getHost() (string, error) {
+	log.Infof("Gathering MongoDB seed list from endpoint %s", r.config.Restore.EndpointName)
+	endpoint, err := r.api.GetEndpoint(r.config.Restore.EndpointName)
+	if err != nil {
+		return "", err
+	}
+	hosts := endpoint.Hosts()
+	if len(hosts) == 0 {
+		return "", fmt.Errorf("no hosts in endpoint %s", r.config.Restore.EndpointName)
+	}
+	return r.config.Replset + "/" + strings.Join(hosts, ","), nil
+}
+
+// connArgs returns the mongorestore command-line arguments to connect to 'host'. The
+// password is read from the tool config file 'configFile', if set
+func (r *Restorer) connArgs(host, configFile string) []string {
+	args := []string{
+		"--host=" + host,
+		"--authenticationDatabase=admin",
+		"--username=" + r.config.Restore.Username,
+	}
+	if configFile != "" {
+		args = append(args, "--config="+configFile)
+	}
+	return append(args, r.config.SSL.ToolArgs()...)
+}
+
+// args returns the mongorestore command-line arguments to restore the archive from stdin
+func (r *Restorer) args(host, oplogLimit, configFile string) []string {
+	cnf := r.config.Restore
+	args := append(r.connArgs(host, configFile), "--archive", "--gzip", "--stopOnError")
+	for _, ns := range cnf.NsInclude {
+		args = append(args, "--nsInclude="+ns)
+	}
+	for _, ns := range cnf.NsExclude {
+		args = append(args, "--nsExclude="+ns)
+	}
+	if cnf.Drop {
+		args = append(args, "--drop")
+	}
+	if cnf.OplogReplay || oplogLimit != "" {
+		args = append(args, "--oplogReplay")
+	}
+	if oplogLimit != "" {
+		args = append(args, "--oplogLimit="+oplogLimit)
+	}
+	return args
+}
+
+// oplogArgs returns the mongorestore command-line arguments to replay the oplog.bson file
+// of dump directory 'dir' up to 'oplogLimit'
+func (r *Restorer) oplogArgs(host, oplogLimit, configFile, dir string) []string {
+	return append(r.connArgs(host, configFile),
+		"--stopOnError",
+		"--oplogReplay",
+		"--oplogLimit="+oplogLimit,
+		dir,
+	)
+}
+
+// getChunks returns the PITR oplog chunks of the replset with entries between the
+// start of backup archive 'name' and 'limit'
+func (r *Restorer) getChunks(ctx context.Context, name string, limit bson.MongoTimestamp) ([]*backup.Chunk, error) {
+	archiveTime, err := backup.ParseArchiveTime(name, r.config.Replset)
+	if err != nil {
+		return nil, fmt.Errorf("cannot replay the PITR oplog chunks after archive %s: %v", name, err)
+	}
+	names, err := r.storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := backup.NewTimestamp(uint32(archiveTime.Unix()), 0)
+	chunks := make([]*backup.Chunk, 0)
+	for _, chunk := range backup.FilterChunks(names, r.config.Replset) {
+		if chunk.End < start || chunk.Start >= limit {
+			continue
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// copyChunk writes the uncompressed oplog entries of 'chunk' to 'w'
+func (r *Restorer) copyChunk(ctx context.Context, chunk *backup.Chunk, w io.Writer) error {
+	reader, _, err := r.storage.Get(ctx, chunk.Name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("cannot read oplog chunk %s: %v", chunk.Name, err)
+	}
+	defer gz.Close()
+	_, err = io.Copy(w, gz)
+	return err
+}
+
+// writeOplog assembles the oplog entries of 'chunks' into the oplog.bson file of a new
+// temporary dump directory and returns the directory
+func (r *Restorer) writeOplog(ctx context.Context, chunks []*backup.Chunk) (string, error) {
+	dir, err := ioutil.TempDir("", "mongorestore-oplog")
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Create(filepath.Join(dir, oplogFile))
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	for _, chunk := range chunks {
+		log.WithFields(log.Fields{
+			"chunk": chunk.Name,
+			"start": backup.TimestampTime(chunk.Start),
+			"end":   backup.TimestampTime(chunk.End),
+		}).Debug("Adding oplog chunk to replay")
+		err = r.copyChunk(ctx, chunk, file)
+		if err != nil {
+			file.Close()
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	err = file.Close()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// replayChunks replays the oplog entries of 'chunks' up to 'oplogLimit' with mongorestore
+func (r *Restorer) replayChunks(ctx context.Context, chunks []*backup.Chunk, host, oplogLimit, configFile string) error {
+	dir, err := r.writeOplog(ctx, chunks)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	log.WithFields(log.Fields{
+		"chunks":     len(chunks),
+		"start":      backup.TimestampTime(chunks[0].Start),
+		"end":        backup.TimestampTime(chunks[len(chunks)-1].End),
+		"oplogLimit": oplogLimit,
+	}).Info("Replaying PITR oplog chunks")
+	return r.restore(ctx, r.oplogArgs(host, oplogLimit, configFile, dir), nil, &Summary{})
+}
+
+// logProgress logs the bytes read of the archive every interval until 'done' is closed
+func (r *Restorer) logProgress(reader *progressReader, size int64, done chan struct{}) {
+	if r.config.Restore.ProgressInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.config.Restore.ProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fields := log.Fields{"bytes": reader.BytesRead()}
+			if size > 0 {
+				fields["total"] = size
+				fields["percent"] = fmt.Sprintf("%.1f", float64(reader.BytesRead())/float64(size)*100)
+			}
+			log.WithFields(fields).Info("Restore progress")
+		case <-done:
+			return
+		}
+	}
+}
+
+// restore runs mongorestore with the archive from 'reader' as stdin, parsing the summary from its output
+func (r *Restorer) restore(ctx context.Context, args []string, reader io.Reader, summary *Summary) error {
+	cmd := exec.CommandContext(ctx, r.config.Restore.MongorestoreBin, args...)
+	cmd.Stdin = reader
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	var lastLine string
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		log.Debugf("mongorestore: %s", line)
+		lastLine = line
+		if match := summaryRegexp.FindStringSubmatch(line); match != nil {
+			summary.Restored, _ = strconv.ParseInt(match[1], 10, 64)
+			summary.Failed, _ = strconv.ParseInt(match[2], 10, 64)
+		}
+	}
+
+	err = cmd.Wait()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("mongorestore failed: %v: %s", err, lastLine)
+	}
+	return nil
+}
+
+// Run restores a backup archive into the replset
+func (r *Restorer) Run(ctx context.Context) (*Summary, error) {
+	oplogLimit, err := ParseOplogLimit(r.config.Restore.OplogLimit)
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.getArchive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	host, err := r.getHost()
+	if err != nil {
+		return nil, err
+	}
+
+	archive, size, err := r.storage.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var chunks []*backup.Chunk
+	if oplogLimit != "" {
+		limit := oplogLimitTimestamp(oplogLimit)
+		chunks, err = r.getChunks(ctx, name, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) > 0 && chunks[len(chunks)-1].End < limit {
+			log.Warnf("The stored PITR oplog chunks end at %s, before the oplog limit", backup.TimestampTime(chunks[len(chunks)-1].End))
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"storage":    r.storage.Name(),
+		"archive":    name,
+		"size":       size,
+		"host":       host,
+		"drop":       r.config.Restore.Drop,
+		"nsInclude":  r.config.Restore.NsInclude,
+		"nsExclude":  r.config.Restore.NsExclude,
+		"oplogLimit": oplogLimit,
+		"chunks":     len(chunks),
+	}).Info("Starting restore")
+
+	var configFile string
+	if r.config.Restore.Password != "" {
+		configFile, err = db.WriteToolConfig("", r.config.Restore.Password)
+		if err != nil {
+			return nil, fmt.Errorf("cannot write mongorestore config file: %v", err)
+		}
+		defer os.Remove(configFile)
+	}
+
+	start := time.Now()
+	summary := &Summary{Archive: name}
+	reader := &progressReader{reader: archive}
+	done := make(chan struct{})
+	go r.logProgress(reader, size, done)
+	err = r.restore(ctx, r.args(host, oplogLimit, configFile), reader, summary)
+	close(done)
+	summary.Bytes = reader.BytesRead()
+	summary.Duration = time.Since(start)
+	if err != nil {
+		return summary, err
+	}
+	if summary.Failed > 0 {
+		return summary, fmt.Errorf("%d document(s) failed to restore", summary.Failed)
+	}
+
+	if len(chunks) > 0 {
+		err = r.replayChunks(ctx, chunks, host, oplogLimit, configFile)
+		summary.Duration = time.Since(start)
+		if err != nil {
+			return summary, err
+		}
+		summary.Chunks = len(chunks)
+	}
+
+	log.WithFields(log.Fields{
+		"archive":  summary.Archive,
+		"bytes":    summary.Bytes,
+		"duration": summary.Duration,
+		"restored": summary.Restored,
+		"failed":   summary.Failed,
+		"chunks":   summary.Chunks,
+	}).Info("Completed restore")
+	return summary, nil
+}
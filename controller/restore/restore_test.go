@@ -0,0 +1,256 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/controller"
+	"github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api"
+	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api/mocks"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// newTestRestorer returns a Restorer using a local storage in 'dir' and a shell
+// script standing in for mongorestore
+func newTestRestorer(t *testing.T, dir, script string) *Restorer {
+	bin := testutils.WriteScript(t, dir, "mongorestore", script)
+
+	mockAPI := &mocks.Client{}
+	mockAPI.On("GetEndpoint", "mongo-port").Return(&api.Endpoint{
+		Dns: []string{"mongo-rs-0:27017", "mongo-rs-1:27017"},
+	}, nil)
+
+	storage := backup.NewLocalStorage(filepath.Join(dir, "backup"))
+	return New(&controller.Config{
+		Replset: "rs",
+		SSL:     &db.SSLConfig{},
+		Restore: &controller.ConfigRestore{
+			EndpointName:     "mongo-port",
+			Archive:          controller.DefaultRestoreArchive,
+			MongorestoreBin:  bin,
+			Username:         "backup",
+			Password:         "123456",
+			ProgressInterval: time.Millisecond,
+		},
+	}, mockAPI, storage)
+}
+
+func TestControllerRestoreParseOplogLimit(t *testing.T) {
+	for limit, expected := range map[string]string{
+		"":                     "",
+		"1546300800":           "1546300800:0",
+		"1546300800:5":         "1546300800:5",
+		"2019-01-01T00:00:00Z": "1546300800:0",
+	} {
+		parsed, err := ParseOplogLimit(limit)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, parsed)
+	}
+	for _, limit := range []string{"yesterday", "-1", "1546300800:x", "2019-01-01"} {
+		_, err := ParseOplogLimit(limit)
+		assert.Error(t, err, ".ParseOplogLimit() should fail for %q", limit)
+	}
+}
+
+func TestControllerRestoreArgs(t *testing.T) {
+	r := newTestRestorer(t, os.TempDir(), "")
+	assert.Equal(t, []string{
+		"--host=rs/host:27017",
+		"--authenticationDatabase=admin",
+		"--username=backup",
+		"--config=/tmp/mongorestore.yaml",
+		"--archive",
+		"--gzip",
+		"--stopOnError",
+	}, r.args("rs/host:27017", "", "/tmp/mongorestore.yaml"))
+
+	r.config.Restore.NsInclude = []string{"app.*"}
+	r.config.Restore.NsExclude = []string{"app.sessions"}
+	r.config.Restore.Drop = true
+	args := r.args("rs/host:27017", "1546300800:0", "")
+	assert.Equal(t, []string{
+		"--nsInclude=app.*",
+		"--nsExclude=app.sessions",
+		"--drop",
+		"--oplogReplay",
+		"--oplogLimit=1546300800:0",
+	}, args[6:])
+
+	assert.Equal(t, []string{
+		"--host=rs/host:27017",
+		"--authenticationDatabase=admin",
+		"--username=backup",
+		"--stopOnError",
+		"--oplogReplay",
+		"--oplogLimit=1546300800:0",
+		"/tmp/dump",
+	}, r.oplogArgs("rs/host:27017", "1546300800:0", "", "/tmp/dump"))
+}
+
+func TestControllerRestoreOplogLimitTimestamp(t *testing.T) {
+	assert.Equal(t, backup.NewTimestamp(1546300800, 5), oplogLimitTimestamp("1546300800:5"))
+}
+
+func TestControllerRestoreGetArchive(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	r := newTestRestorer(t, dir, "")
+	_, err := r.getArchive(ctx)
+	assert.Equal(t, ErrNoArchive, err)
+
+	for _, name := range []string{"rs-20190101T000000Z.archive.gz", "rs-20190102T000000Z.archive.gz", "other-20190103T000000Z.archive.gz"} {
+		r.storage.Put(ctx, name, strings.NewReader(""))
+	}
+	name, err := r.getArchive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "rs-20190102T000000Z.archive.gz", name, ".getArchive() should return the newest archive of the replset")
+
+	r.config.Restore.Archive = "rs-20190101T000000Z.archive.gz"
+	name, _ = r.getArchive(ctx)
+	assert.Equal(t, "rs-20190101T000000Z.archive.gz", name)
+}
+
+func TestControllerRestoreRun(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	// the stand-in mongorestore checks its args and drains the archive from stdin
+	r := newTestRestorer(t, dir, `
+echo "$@" > `+filepath.Join(dir, "args")+`
+for arg in "$@"; do case "$arg" in --config=*) cp "${arg#--config=}" `+filepath.Join(dir, "config")+`;; esac; done
+cat > `+filepath.Join(dir, "restored")+`
+echo "restoring app.users from archive" >&2
+echo "10 document(s) restored successfully. 0 document(s) failed to restore." >&2`)
+	r.storage.Put(ctx, "rs-20190101T000000Z.archive.gz", strings.NewReader("archive"))
+	r.config.Restore.Drop = true
+
+	summary, err := r.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &Summary{
+		Archive:  "rs-20190101T000000Z.archive.gz",
+		Bytes:    7,
+		Duration: summary.Duration,
+		Restored: 10,
+	}, summary)
+
+	restored, _ := ioutil.ReadFile(filepath.Join(dir, "restored"))
+	assert.Equal(t, "archive", string(restored))
+	args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+	assert.Contains(t, string(args), "--host=rs/mongo-rs-0:27017,mongo-rs-1:27017 ")
+	assert.Contains(t, string(args), "--drop")
+	assert.NotContains(t, string(args), "123456", "the password should not be in the command-line")
+	config, _ := ioutil.ReadFile(filepath.Join(dir, "config"))
+	assert.Equal(t, "password: \"123456\"\n", string(config))
+	assert.Contains(t, testLogBuffer.String(), "Completed restore")
+
+	// invalid point in time
+	r.config.Restore.OplogLimit = "yesterday"
+	_, err = r.Run(ctx)
+	assert.Error(t, err)
+	r.config.Restore.OplogLimit = ""
+
+	// failed documents
+	r.config.Restore.MongorestoreBin = testutils.WriteScript(t, dir, "mongorestore-failed", "cat > /dev/null\necho \"8 document(s) restored successfully. 2 document(s) failed to restore.\" >&2")
+	summary, err = r.Run(ctx)
+	assert.EqualError(t, err, "2 document(s) failed to restore")
+	assert.Equal(t, int64(2), summary.Failed)
+
+	// failed mongorestore
+	testutils.WriteScript(t, dir, "mongorestore-failed", "cat > /dev/null\necho 'Failed: error connecting to db server' >&2\nexit 1")
+	_, err = r.Run(ctx)
+	assert.EqualError(t, err, "mongorestore failed: exit status 1: Failed: error connecting to db server")
+
+	// failed endpoint
+	mockAPI := &mocks.Client{}
+	mockAPI.On("GetEndpoint", "mongo-port").Return(nil, errors.New("api error"))
+	r.api = mockAPI
+	_, err = r.Run(ctx)
+	assert.EqualError(t, err, "api error")
+}
+
+// putChunk stores a PITR oplog chunk of replset 'rs' with the gzipped 'data'
+func putChunk(t *testing.T, storage backup.Storage, start, end bson.MongoTimestamp, data string) {
+	buffer := new(bytes.Buffer)
+	gz := gzip.NewWriter(buffer)
+	gz.Write([]byte(data))
+	gz.Close()
+	_, err := storage.Put(context.Background(), backup.ChunkName("rs", start, end), buffer)
+	assert.NoError(t, err)
+}
+
+func TestControllerRestoreRunOplogChunks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	// the stand-in mongorestore saves the oplog.bson of a dump directory argument,
+	// otherwise it drains the archive from stdin
+	r := newTestRestorer(t, dir, `
+for arg in "$@"; do last="$arg"; done
+if [ -d "$last" ]; then
+  echo "$@" > `+filepath.Join(dir, "oplog-args")+`
+  cp "$last/oplog.bson" `+filepath.Join(dir, "oplog")+`
+  exit 0
+fi
+cat > /dev/null
+echo "10 document(s) restored successfully. 0 document(s) failed to restore." >&2`)
+	r.storage.Put(ctx, "rs-20190101T000000Z.archive.gz", strings.NewReader("archive"))
+	putChunk(t, r.storage, backup.NewTimestamp(1546300000, 1), backup.NewTimestamp(1546300700, 1), "before-archive")
+	putChunk(t, r.storage, backup.NewTimestamp(1546300700, 2), backup.NewTimestamp(1546300900, 1), "entry1")
+	putChunk(t, r.storage, backup.NewTimestamp(1546300900, 2), backup.NewTimestamp(1546301000, 1), "entry2")
+	putChunk(t, r.storage, backup.NewTimestamp(1546302000, 1), backup.NewTimestamp(1546302100, 1), "after-limit")
+
+	// no oplog limit, the chunks are not replayed
+	summary, err := r.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, summary.Chunks)
+	_, err = os.Stat(filepath.Join(dir, "oplog"))
+	assert.True(t, os.IsNotExist(err))
+
+	r.config.Restore.OplogLimit = "1546301500"
+	summary, err = r.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, summary.Chunks)
+	assert.Equal(t, int64(10), summary.Restored)
+
+	oplog, _ := ioutil.ReadFile(filepath.Join(dir, "oplog"))
+	assert.Equal(t, "entry1entry2", string(oplog), "only the chunks between the archive and the oplog limit should be replayed")
+	args, _ := ioutil.ReadFile(filepath.Join(dir, "oplog-args"))
+	assert.Contains(t, string(args), "--oplogReplay --oplogLimit=1546301500:0 ")
+	assert.NotContains(t, string(args), "123456", "the password should not be in the command-line")
+
+	// archive without a start time
+	r.config.Restore.Archive = "custom.archive.gz"
+	r.storage.Put(ctx, "custom.archive.gz", strings.NewReader("archive"))
+	_, err = r.Run(ctx)
+	assert.Error(t, err)
+}
@@ -15,6 +15,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -31,23 +32,27 @@ var (
 	ErrNoDbProvided     = errors.New("no db/database provided")
 	ErrNoUserProvided   = errors.New("no username provided")
 	ErrNoPasswdProvided = errors.New("no new password provided")
+	ErrNoRoleProvided   = errors.New("no role provided")
+	ErrCannotChgBuiltin = errors.New("cannot change built-in role")
 	ErrUserNotFound     = errors.New("could not find user")
 )
 
 type Controller struct {
 	api             api.Client
 	dbConfig        *db.Config
-	session         *mgo.Session
+	pool            *db.Pool
+	session         db.Session
 	config          *controller.Config
 	maxConnectTries uint
 	retrySleep      time.Duration
 }
 
-func NewController(config *controller.Config, client api.Client) (*Controller, error) {
+func NewController(ctx context.Context, config *controller.Config, client api.Client) (*Controller, error) {
 	var err error
 	uc := &Controller{
 		api:             client,
 		config:          config,
+		pool:            db.NewPool(config.Pool),
 		maxConnectTries: config.User.MaxConnectTries,
 		retrySleep:      config.User.RetrySleep,
 	}
@@ -55,7 +60,7 @@ func NewController(config *controller.Config, client api.Client) (*Controller, e
 	if err != nil {
 		return nil, err
 	}
-	uc.session, err = uc.getSession()
+	uc.session, err = uc.getSession(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -75,16 +80,21 @@ func (uc *Controller) getDBConfig() (*db.Config, error) {
 			Addrs:          mongodService.Hosts(),
 			Username:       uc.config.UserAdminUser,
 			Password:       uc.config.UserAdminPassword,
+			Mechanism:      uc.config.AuthMechanism,
 			ReplicaSetName: uc.config.Replset,
 			Direct:         true,
 			FailFast:       true,
 		},
-		SSL: uc.config.SSL,
+		SSL:          uc.config.SSL,
+		Driver:       uc.config.Driver,
+		AuthFallback: uc.config.AuthFallback,
+		// user changes use a majority write concern and must run on the primary
+		Primary: true,
 	}, nil
 }
 
-func (uc *Controller) getSession() (*mgo.Session, error) {
-	session, err := db.WaitForSession(uc.dbConfig, uc.maxConnectTries, uc.retrySleep)
+func (uc *Controller) getSession(ctx context.Context) (db.Session, error) {
+	session, err := uc.pool.WaitForSession(ctx, uc.dbConfig, uc.maxConnectTries, uc.retrySleep)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"hosts": uc.dbConfig.DialInfo.Addrs,
@@ -95,14 +105,9 @@ func (uc *Controller) getSession() (*mgo.Session, error) {
 	log.WithFields(log.Fields{
 		"hosts":   uc.dbConfig.DialInfo.Addrs,
 		"replset": uc.config.Replset,
+		"driver":  session.Driver(),
 	}).Info("Connected to MongoDB host(s)")
-
-	session.SetMode(mgo.Primary, true)
-	session.SetSafe(&mgo.Safe{
-		WMode: "majority",
-		FSync: true,
-	})
-	return session, err
+	return session, nil
 }
 
 func (uc *Controller) Close() {
@@ -114,6 +119,7 @@ func (uc *Controller) Close() {
 		uc.session.Close()
 		uc.session = nil
 	}
+	uc.pool.Close()
 }
 
 func (uc *Controller) UpdateUsers() error {
@@ -155,6 +161,37 @@ func (uc *Controller) UpdateUsers() error {
 	return nil
 }
 
+// SyncUsers creates, updates and drops users and custom roles to match the
+// spec file of the config
+func (uc *Controller) SyncUsers() error {
+	if uc.config.User.File == "" {
+		return errors.New("No file provided")
+	}
+
+	spec, err := user_json.NewSpecFromFile(uc.config.User.File)
+	if err != nil {
+		log.WithError(err).Errorf("Failed loading spec file: %s", uc.config.User.File)
+		return err
+	}
+
+	changes, err := Sync(uc.session, spec, &SyncOptions{
+		Prune:     uc.config.User.Prune,
+		DryRun:    uc.config.User.DryRun,
+		AdminUser: uc.dbConfig.DialInfo.Username,
+	})
+	for _, change := range changes {
+		log.WithFields(log.Fields{
+			"dryRun": uc.config.User.DryRun,
+		}).Infof("Sync: %s", change)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("User sync complete, %d change(s)", len(changes))
+	return nil
+}
+
 func (uc *Controller) RemoveUser() error {
 	if uc.config.User.Username == "" {
 		return ErrNoUserProvided
@@ -174,6 +211,78 @@ func (uc *Controller) RemoveUser() error {
 	return nil
 }
 
+// loadRole loads the custom role of the file of the config
+func (uc *Controller) loadRole() (*user_json.CustomRole, error) {
+	if uc.config.User.File == "" {
+		return nil, errors.New("No file provided")
+	}
+	role, err := user_json.NewCustomRoleFromFile(uc.config.User.File)
+	if err != nil {
+		log.WithError(err).Errorf("Failed loading role file: %s", uc.config.User.File)
+		return nil, err
+	}
+	return role, nil
+}
+
+func (uc *Controller) CreateRole() error {
+	role, err := uc.loadRole()
+	if err != nil {
+		return err
+	}
+
+	err = CreateRole(uc.session, role)
+	if err != nil {
+		log.WithError(err).Errorf("Cannot create role %s", role.Role)
+		return err
+	}
+
+	log.Info("Role creation complete")
+	return nil
+}
+
+func (uc *Controller) UpdateRole() error {
+	role, err := uc.loadRole()
+	if err != nil {
+		return err
+	}
+
+	err = UpdateRole(uc.session, role)
+	if err != nil {
+		log.WithError(err).Errorf("Cannot update role %s", role.Role)
+		return err
+	}
+
+	log.Info("Role update complete")
+	return nil
+}
+
+// ListRoles returns the custom roles of the database of the config
+func (uc *Controller) ListRoles() ([]*user_json.CustomRole, error) {
+	if uc.config.User.Database == "" {
+		return nil, ErrNoDbProvided
+	}
+	return GetRoles(uc.session, uc.config.User.Database)
+}
+
+func (uc *Controller) DropRole() error {
+	if uc.config.User.Role == "" {
+		return ErrNoRoleProvided
+	} else if uc.config.User.Database == "" {
+		return ErrNoDbProvided
+	} else if user_json.IsBuiltinRole(uc.config.User.Role) {
+		log.Errorf("Cannot drop built-in role %s", uc.config.User.Role)
+		return ErrCannotChgBuiltin
+	}
+
+	err := RemoveRole(uc.session, uc.config.User.Role, uc.config.User.Database)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Role removal complete")
+	return nil
+}
+
 func (uc *Controller) ReloadSystemUsers() error {
 	err := UpdateUsers(uc.session, SystemUsers(), "admin")
 	if err != nil {
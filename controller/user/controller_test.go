@@ -15,8 +15,13 @@
 package user
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
+	"github.com/percona/mongodb-orchestration-tools/controller"
+	"github.com/percona/mongodb-orchestration-tools/internal"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api/mocks"
@@ -43,12 +48,14 @@ func TestControllerUserNew(t *testing.T) {
 	}, nil)
 
 	var err error
-	testController, err = NewController(testControllerConfig, mockAPI)
+	testController, err = NewController(context.Background(), testControllerConfig, mockAPI)
 	assert.NoError(t, err, ".NewController() should not return an error")
 	assert.NotNil(t, testController, ".NewController() should return a Controller that is not nil")
 	assert.NotNil(t, testController.session, ".NewController() should return a Controller with a session field that is not nil")
 	assert.NoError(t, testController.session.Ping(), ".NewController() should return a Controller with a session that is pingable")
-	assert.Equal(t, mgo.Primary, testController.session.Mode(), ".NewController() should return a Controller with a session that is in mgo.Primary mode")
+	mgoSession, err := db.MgoSession(testController.session)
+	assert.NoError(t, err, ".NewController() should return a Controller with an mgo session by default")
+	assert.Equal(t, mgo.Primary, mgoSession.Mode(), ".NewController() should return a Controller with a session that is in mgo.Primary mode")
 }
 
 func TestControllerUserControllerUpdateUsers(t *testing.T) {
@@ -113,3 +120,45 @@ func TestControllerUserControllerClose(t *testing.T) {
 	testController.Close()
 	assert.Nil(t, testController.session, "Controller session should not nil after .Close()")
 }
+
+func TestControllerUserControllerDropRoleInvalid(t *testing.T) {
+	uc := &Controller{config: &controller.Config{User: &controller.ConfigUser{}}}
+	assert.Equal(t, ErrNoRoleProvided, uc.DropRole())
+	uc.config.User.Role = "readWrite"
+	assert.Equal(t, ErrNoDbProvided, uc.DropRole())
+	uc.config.User.Database = "app"
+	assert.Equal(t, ErrCannotChgBuiltin, uc.DropRole())
+
+	uc.config.User.Database = ""
+	_, err := uc.ListRoles()
+	assert.Equal(t, ErrNoDbProvided, err)
+}
+
+func TestControllerUserControllerRoles(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	uc := &Controller{
+		session: testSession,
+		config: &controller.Config{
+			User: &controller.ConfigUser{
+				File:     internal.RelPathToAbs(filepath.Join(testDirRelPath, "test-role.json")),
+				Role:     "appReader",
+				Database: "app",
+			},
+		},
+	}
+	assert.Error(t, uc.UpdateRole(), ".UpdateRole() should fail before the role is created")
+	assert.NoError(t, uc.CreateRole())
+	assert.NoError(t, uc.UpdateRole())
+
+	roles, err := uc.ListRoles()
+	assert.NoError(t, err)
+	if assert.Len(t, roles, 1) {
+		assert.Equal(t, "appReader", roles[0].Role)
+	}
+
+	assert.NoError(t, uc.DropRole())
+	roles, err = uc.ListRoles()
+	assert.NoError(t, err)
+	assert.Len(t, roles, 0)
+}
@@ -30,14 +30,14 @@ const (
 )
 
 type Role struct {
-	Role     string `json:"role"`
-	Database string `json:"db"`
+	Role     string `json:"role" yaml:"role"`
+	Database string `json:"db" yaml:"db"`
 }
 
 type User struct {
-	Username string  `json:"user"`
-	Password string  `json:"pwd"`
-	Roles    []*Role `json:"roles"`
+	Username string  `json:"user" yaml:"user"`
+	Password string  `json:"pwd" yaml:"pwd"`
+	Roles    []*Role `json:"roles" yaml:"roles"`
 }
 
 type CLIPayload struct {
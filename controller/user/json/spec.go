@@ -0,0 +1,245 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// builtinRoles are the built-in roles of MongoDB, custom roles cannot use their names
+var builtinRoles = []string{
+	"read",
+	"readWrite",
+	"dbAdmin",
+	"dbOwner",
+	"userAdmin",
+	"clusterAdmin",
+	"clusterManager",
+	"clusterMonitor",
+	"hostManager",
+	"backup",
+	"restore",
+	"readAnyDatabase",
+	"readWriteAnyDatabase",
+	"userAdminAnyDatabase",
+	"dbAdminAnyDatabase",
+	"root",
+	"__system",
+}
+
+// IsBuiltinRole returns true if the role is a built-in role of MongoDB
+func IsBuiltinRole(role string) bool {
+	for _, builtinRole := range builtinRoles {
+		if role == builtinRole {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource is the resource of a privilege, either a database and collection
+// or the cluster. An empty database or collection matches all of them
+type Resource struct {
+	Database   string `json:"db" yaml:"db"`
+	Collection string `json:"collection" yaml:"collection"`
+	Cluster    bool   `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+}
+
+// String returns the resource in the format 'db.collection' or 'cluster'
+func (r *Resource) String() string {
+	if r.Cluster {
+		return "cluster"
+	}
+	return r.Database + "." + r.Collection
+}
+
+// Privilege is a set of actions allowed on a resource
+type Privilege struct {
+	Resource *Resource `json:"resource" yaml:"resource"`
+	Actions  []string  `json:"actions" yaml:"actions"`
+}
+
+// CustomRole is a user-defined role of privileges and inherited roles
+type CustomRole struct {
+	Role       string       `json:"role" yaml:"role"`
+	Database   string       `json:"db" yaml:"db"`
+	Privileges []*Privilege `json:"privileges" yaml:"privileges"`
+	Roles      []*Role      `json:"roles" yaml:"roles"`
+}
+
+func (role *CustomRole) Validate() error {
+	if role.Role == "" {
+		return errors.New("'role' field is required")
+	} else if IsBuiltinRole(role.Role) {
+		return fmt.Errorf("cannot use the name of built-in role '%s'", role.Role)
+	} else if role.Database == "" {
+		return errors.New("'db' field is required")
+	} else if len(role.Privileges) < 1 && len(role.Roles) < 1 {
+		return errors.New("'privileges' or 'roles' field is required, must be an array with one or more documents")
+	}
+	for _, privilege := range role.Privileges {
+		if privilege.Resource == nil {
+			return errors.New("'resource' field is required")
+		} else if len(privilege.Actions) < 1 {
+			return errors.New("'actions' field is required, must be an array with one or more actions")
+		}
+		for _, action := range privilege.Actions {
+			if action == "" {
+				return errors.New("'actions' field cannot contain an empty action")
+			}
+		}
+		resource := privilege.Resource
+		if resource.Cluster {
+			if resource.Database != "" || resource.Collection != "" {
+				return errors.New("cannot set 'db' or 'collection' fields of a 'cluster' resource")
+			} else if role.Database != adminDB {
+				return errors.New("cannot set privilege on the cluster unless role is added to 'admin'")
+			}
+		} else if resource.Database != role.Database && role.Database != adminDB {
+			return errors.New("cannot set privilege on other database unless role is added to 'admin'")
+		}
+	}
+	for _, inherited := range role.Roles {
+		if inherited.Role == "" {
+			return errors.New("'role' field is required")
+		} else if inherited.Database == "" {
+			return errors.New("'db' field is required")
+		} else if inherited.Database != role.Database && role.Database != adminDB {
+			return errors.New("cannot inherit role of other database unless role is added to 'admin'")
+		}
+	}
+	return nil
+}
+
+// SpecUser is a user of a Spec. The password is set by the 'pwd' field or
+// is loaded from the file 'pwdFile' or the environment variable 'pwdEnv'
+type SpecUser struct {
+	User         `yaml:",inline"`
+	Database     string `json:"db" yaml:"db"`
+	PasswordFile string `json:"pwdFile,omitempty" yaml:"pwdFile,omitempty"`
+	PasswordEnv  string `json:"pwdEnv,omitempty" yaml:"pwdEnv,omitempty"`
+}
+
+// loadPassword sets the password of the user from its 'pwdFile' or 'pwdEnv'
+// field, relative file paths are relative to 'baseDir'
+func (user *SpecUser) loadPassword(baseDir string) error {
+	if user.Password != "" && (user.PasswordFile != "" || user.PasswordEnv != "") ||
+		user.PasswordFile != "" && user.PasswordEnv != "" {
+		return fmt.Errorf("invalid user %s: only one of the 'pwd', 'pwdFile' or 'pwdEnv' fields can be set", user.Username)
+	}
+	if user.PasswordFile != "" {
+		file := user.PasswordFile
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		bytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("cannot load password of user %s: %v", user.Username, err)
+		}
+		user.Password = strings.TrimSpace(string(bytes))
+	} else if user.PasswordEnv != "" {
+		user.Password = os.Getenv(user.PasswordEnv)
+	}
+	return nil
+}
+
+func (user *SpecUser) Validate() error {
+	if user.Database == "" {
+		return errors.New("'db' field is required")
+	}
+	return user.User.Validate(user.Database)
+}
+
+// Spec is the desired state of the users and custom roles of a deployment
+type Spec struct {
+	Users []*SpecUser   `json:"users" yaml:"users"`
+	Roles []*CustomRole `json:"roles" yaml:"roles"`
+}
+
+// Validate validates the users and roles of the spec and ensures they are
+// only defined once
+func (spec *Spec) Validate() error {
+	roles := map[string]bool{}
+	for _, role := range spec.Roles {
+		if err := role.Validate(); err != nil {
+			return fmt.Errorf("invalid role %s: %v", role.Role, err)
+		}
+		name := role.Role + "@" + role.Database
+		if roles[name] {
+			return fmt.Errorf("role %s is defined more than once", name)
+		}
+		roles[name] = true
+	}
+	users := map[string]bool{}
+	for _, user := range spec.Users {
+		if err := user.Validate(); err != nil {
+			return fmt.Errorf("invalid user %s: %v", user.Username, err)
+		}
+		name := user.Username + "@" + user.Database
+		if users[name] {
+			return fmt.Errorf("user %s is defined more than once", name)
+		}
+		users[name] = true
+	}
+	return nil
+}
+
+// unmarshalFile parses a YAML or JSON file, files with a '.json' extension
+// are parsed as JSON
+func unmarshalFile(file string, out interface{}) error {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(file) == ".json" {
+		return unmarshalJSON(bytes, out)
+	}
+	return yaml.UnmarshalStrict(bytes, out)
+}
+
+// NewCustomRoleFromFile loads and validates a custom role from a YAML or JSON file
+func NewCustomRoleFromFile(file string) (*CustomRole, error) {
+	role := &CustomRole{}
+	err := unmarshalFile(file, role)
+	if err != nil {
+		return nil, err
+	}
+	return role, role.Validate()
+}
+
+// NewSpecFromFile loads a spec from a YAML or JSON file and loads the
+// passwords of its users
+func NewSpecFromFile(file string) (*Spec, error) {
+	spec := &Spec{}
+	err := unmarshalFile(file, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range spec.Users {
+		err = user.loadPassword(filepath.Dir(file))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spec, spec.Validate()
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testSpecFile        = "testdata/test-spec.yaml"
+	testSpecFileJSON    = "testdata/test-spec.json"
+	testSpecFileInvalid = "testdata/test-spec-invalid.yaml"
+	testRoleFile        = "testdata/test-role.json"
+	testRoleFileBuiltin = "testdata/test-role-builtin.yaml"
+)
+
+func TestControllerUserJSONNewSpecFromFile(t *testing.T) {
+	spec, err := NewSpecFromFile(testSpecFile)
+	assert.NoError(t, err)
+	if assert.Len(t, spec.Roles, 2) {
+		assert.Equal(t, "appReader", spec.Roles[0].Role)
+		assert.Equal(t, "app.", spec.Roles[0].Privileges[0].Resource.String())
+		assert.Equal(t, []string{"find", "listCollections"}, spec.Roles[0].Privileges[0].Actions)
+		assert.Equal(t, "cluster", spec.Roles[1].Privileges[0].Resource.String())
+	}
+	if assert.Len(t, spec.Users, 2) {
+		assert.Equal(t, "appUser", spec.Users[0].Username)
+		assert.Equal(t, "123456", spec.Users[0].Password)
+		assert.Equal(t, "app", spec.Users[0].Database)
+		assert.Equal(t, "secret123", spec.Users[1].Password, ".NewSpecFromFile() should load 'pwdFile' relative to the spec file")
+	}
+
+	// json spec with a password from the environment
+	assert.NoError(t, os.Setenv("TEST_SPEC_PASSWORD", "env123456"))
+	defer os.Unsetenv("TEST_SPEC_PASSWORD")
+	spec, err = NewSpecFromFile(testSpecFileJSON)
+	assert.NoError(t, err)
+	if assert.Len(t, spec.Users, 1) {
+		assert.Equal(t, "env123456", spec.Users[0].Password)
+	}
+
+	// more than one password source
+	_, err = NewSpecFromFile(testSpecFileInvalid)
+	assert.Error(t, err)
+
+	// a user file is not a valid spec
+	_, err = NewSpecFromFile(testUserFile)
+	assert.Error(t, err)
+
+	_, err = NewSpecFromFile("/does/not/exist")
+	assert.Error(t, err)
+}
+
+func TestControllerUserJSONNewCustomRoleFromFile(t *testing.T) {
+	role, err := NewCustomRoleFromFile(testRoleFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "appReader", role.Role)
+	assert.Equal(t, "app", role.Database)
+	if assert.Len(t, role.Privileges, 1) {
+		assert.Equal(t, "app.orders", role.Privileges[0].Resource.String())
+	}
+	assert.Len(t, role.Roles, 1)
+
+	// built-in role name
+	_, err = NewCustomRoleFromFile(testRoleFileBuiltin)
+	assert.Error(t, err)
+
+	// user file
+	_, err = NewCustomRoleFromFile(testUserFile)
+	assert.Error(t, err)
+
+	_, err = NewCustomRoleFromFile("/does/not/exist")
+	assert.Error(t, err)
+}
+
+func TestControllerUserJSONSpecValidate(t *testing.T) {
+	role := &CustomRole{
+		Role:     "appReader",
+		Database: "app",
+		Privileges: []*Privilege{
+			{Resource: &Resource{Database: "app"}, Actions: []string{"find"}},
+		},
+	}
+	user := &SpecUser{
+		User: User{
+			Username: "appUser",
+			Password: "123456",
+			Roles:    []*Role{{Role: "appReader", Database: "app"}},
+		},
+		Database: "app",
+	}
+	spec := &Spec{Users: []*SpecUser{user}, Roles: []*CustomRole{role}}
+	assert.NoError(t, spec.Validate())
+
+	// duplicate role
+	spec.Roles = append(spec.Roles, role)
+	assert.Error(t, spec.Validate())
+	spec.Roles = spec.Roles[:1]
+
+	// duplicate user
+	spec.Users = append(spec.Users, user)
+	assert.Error(t, spec.Validate())
+	spec.Users = spec.Users[:1]
+
+	// no user database
+	user.Database = ""
+	assert.Error(t, spec.Validate())
+	user.Database = "app"
+	assert.NoError(t, spec.Validate())
+}
+
+func TestControllerUserJSONCustomRoleValidate(t *testing.T) {
+	assert.Error(t, (&CustomRole{Database: "app", Roles: []*Role{{Role: "read", Database: "app"}}}).Validate())
+	assert.Error(t, (&CustomRole{Role: "test", Roles: []*Role{{Role: "read", Database: "app"}}}).Validate())
+	assert.Error(t, (&CustomRole{Role: "test", Database: "app"}).Validate(), "a role needs privileges or roles")
+	assert.NoError(t, (&CustomRole{Role: "test", Database: "app", Roles: []*Role{{Role: "read", Database: "app"}}}).Validate())
+	assert.Error(t, (&CustomRole{Role: "root", Database: "admin", Roles: []*Role{{Role: "read", Database: "app"}}}).Validate(), "a role cannot use a built-in role name")
+	assert.True(t, IsBuiltinRole("readWrite"))
+	assert.False(t, IsBuiltinRole("appReader"))
+
+	// inherited roles
+	assert.Error(t, (&CustomRole{Role: "test", Database: "app", Roles: []*Role{{Database: "app"}}}).Validate())
+	assert.Error(t, (&CustomRole{Role: "test", Database: "app", Roles: []*Role{{Role: "read"}}}).Validate())
+	assert.Error(t, (&CustomRole{Role: "test", Database: "app", Roles: []*Role{{Role: "read", Database: "other"}}}).Validate())
+	assert.NoError(t, (&CustomRole{Role: "test", Database: "admin", Roles: []*Role{{Role: "read", Database: "other"}}}).Validate())
+
+	// privileges
+	privilege := &Privilege{Resource: &Resource{Database: "app", Collection: "test"}, Actions: []string{"find"}}
+	role := &CustomRole{Role: "test", Database: "app", Privileges: []*Privilege{privilege}}
+	assert.NoError(t, role.Validate())
+	privilege.Actions = []string{}
+	assert.Error(t, role.Validate())
+	privilege.Actions = []string{"find", ""}
+	assert.Error(t, role.Validate())
+	privilege.Actions = []string{"find"}
+	privilege.Resource.Database = "other"
+	assert.Error(t, role.Validate(), "a role of a non-admin database cannot have privileges on other databases")
+	privilege.Resource = &Resource{Cluster: true}
+	assert.Error(t, role.Validate(), "a role of a non-admin database cannot have privileges on the cluster")
+	role.Database = "admin"
+	assert.NoError(t, role.Validate())
+	privilege.Resource.Database = "app"
+	assert.Error(t, role.Validate(), "a cluster resource cannot have a database")
+	privilege.Resource = nil
+	assert.Error(t, role.Validate())
+}
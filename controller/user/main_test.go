@@ -39,7 +39,7 @@ const (
 )
 
 var (
-	testSession     *mgo.Session
+	testSession     db.Session
 	testController  *Controller
 	testLogBuffer   = new(bytes.Buffer)
 	testSystemUsers = []*mgo.User{
@@ -62,19 +62,13 @@ var (
 	}
 )
 
-func checkUserExists(session *mgo.Session, user, db string) error {
-	resp := struct {
-		Username string `bson:"user"`
-		Database string `bson:"db"`
-	}{}
-	err := session.DB(testControllerConfig.User.Database).C("system.users").Find(bson.M{
-		"user": user,
-		"db":   db,
-	}).One(&resp)
+func checkUserExists(session db.Session, user, dbName string) error {
+	resp := usersInfoResp{}
+	err := session.RunOn(dbName, bson.D{{Name: "usersInfo", Value: user}}, &resp)
 	if err != nil {
 		return err
 	}
-	if resp.Username != user || resp.Database != db {
+	if len(resp.Users) != 1 || resp.Users[0].Username != user || resp.Users[0].Database != dbName {
 		return errors.New("user does not match")
 	}
 	return nil
@@ -84,11 +78,11 @@ func TestMain(m *testing.M) {
 	logger.SetupLogger(nil, logger.GetLogFormatter(), testLogBuffer)
 
 	if testutils.Enabled() {
-		var err error
-		testSession, err = testutils.GetSession(testutils.MongodbPrimaryPort)
+		mgoSession, err := testutils.GetSession(testutils.MongodbPrimaryPort)
 		if err != nil {
 			panic(err)
 		}
+		testSession = db.NewMgoSession(mgoSession)
 	}
 
 	exit := m.Run()
@@ -0,0 +1,187 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package user
+
+import (
+	"errors"
+
+	user_json "github.com/percona/mongodb-orchestration-tools/controller/user/json"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type roleRef struct {
+	Role     string `bson:"role"`
+	Database string `bson:"db"`
+}
+
+type rolesInfoResp struct {
+	Roles []struct {
+		Role       string `bson:"role"`
+		Database   string `bson:"db"`
+		Privileges []struct {
+			Resource struct {
+				Database   string `bson:"db"`
+				Collection string `bson:"collection"`
+				Cluster    bool   `bson:"cluster"`
+			} `bson:"resource"`
+			Actions []string `bson:"actions"`
+		} `bson:"privileges"`
+		Roles []roleRef `bson:"roles"`
+	} `bson:"roles"`
+
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+func rolesInfo(session db.Session, dbName string, cmd bson.D) ([]*user_json.CustomRole, error) {
+	resp := rolesInfoResp{}
+	err := session.RunOn(dbName, cmd, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+
+	roles := []*user_json.CustomRole{}
+	for _, info := range resp.Roles {
+		role := &user_json.CustomRole{
+			Role:       info.Role,
+			Database:   info.Database,
+			Privileges: []*user_json.Privilege{},
+			Roles:      []*user_json.Role{},
+		}
+		for _, privilege := range info.Privileges {
+			role.Privileges = append(role.Privileges, &user_json.Privilege{
+				Resource: &user_json.Resource{
+					Database:   privilege.Resource.Database,
+					Collection: privilege.Resource.Collection,
+					Cluster:    privilege.Resource.Cluster,
+				},
+				Actions: privilege.Actions,
+			})
+		}
+		for _, inherited := range info.Roles {
+			role.Roles = append(role.Roles, &user_json.Role{
+				Role:     inherited.Role,
+				Database: inherited.Database,
+			})
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// GetRoles returns the custom roles of the database 'dbName' with their privileges
+func GetRoles(session db.Session, dbName string) ([]*user_json.CustomRole, error) {
+	return rolesInfo(session, dbName, bson.D{
+		{Name: "rolesInfo", Value: 1},
+		{Name: "showPrivileges", Value: true},
+	})
+}
+
+// roleExists returns true if the custom role exists in the database 'dbName'
+func roleExists(session db.Session, roleName, dbName string) (bool, error) {
+	roles, err := rolesInfo(session, dbName, bson.D{{Name: "rolesInfo", Value: roleName}})
+	if err != nil {
+		return false, err
+	}
+	return len(roles) > 0, nil
+}
+
+// rolePrivileges returns the privileges of a role in the format of the 'createRole' server command
+func rolePrivileges(role *user_json.CustomRole) []bson.M {
+	privileges := []bson.M{}
+	for _, privilege := range role.Privileges {
+		resource := bson.M{"cluster": true}
+		if !privilege.Resource.Cluster {
+			resource = bson.M{"db": privilege.Resource.Database, "collection": privilege.Resource.Collection}
+		}
+		privileges = append(privileges, bson.M{"resource": resource, "actions": privilege.Actions})
+	}
+	return privileges
+}
+
+// roleRoles returns the inherited roles of a role in the format of the 'createRole' server command
+func roleRoles(role *user_json.CustomRole) []bson.M {
+	roles := []bson.M{}
+	for _, inherited := range role.Roles {
+		roles = append(roles, bson.M{"role": inherited.Role, "db": inherited.Database})
+	}
+	return roles
+}
+
+var (
+	ErrRoleExists   = errors.New("role already exists")
+	ErrRoleNotFound = errors.New("could not find role")
+)
+
+// saveRole runs the 'createRole' or 'updateRole' server command of a custom role
+func saveRole(session db.Session, role *user_json.CustomRole, cmdName string) error {
+	err := role.Validate()
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"role":       role.Role,
+		"privileges": len(role.Privileges),
+		"roles":      len(role.Roles),
+		"db":         role.Database,
+	}).Info("Adding/updating MongoDB role")
+
+	exists, err := roleExists(session, role.Role, role.Database)
+	if err != nil {
+		return err
+	} else if exists && cmdName == "createRole" {
+		return ErrRoleExists
+	} else if !exists && cmdName == "updateRole" {
+		return ErrRoleNotFound
+	}
+	return runUserCmd(session, role.Database, bson.D{
+		{Name: cmdName, Value: role.Role},
+		{Name: "privileges", Value: rolePrivileges(role)},
+		{Name: "roles", Value: roleRoles(role)},
+		{Name: "writeConcern", Value: majorityWriteConcern},
+	})
+}
+
+// CreateRole creates a custom role, it fails if the role exists
+func CreateRole(session db.Session, role *user_json.CustomRole) error {
+	return saveRole(session, role, "createRole")
+}
+
+// UpdateRole replaces the privileges and inherited roles of an existing custom role
+func UpdateRole(session db.Session, role *user_json.CustomRole) error {
+	return saveRole(session, role, "updateRole")
+}
+
+func RemoveRole(session db.Session, roleName, dbName string) error {
+	log.Infof("Removing role %s from db %s", roleName, dbName)
+	exists, err := roleExists(session, roleName, dbName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Warnf("Cannot remove role, %s does not exist in database %s", roleName, dbName)
+		return nil
+	}
+	return runUserCmd(session, dbName, bson.D{
+		{Name: "dropRole", Value: roleName},
+		{Name: "writeConcern", Value: majorityWriteConcern},
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package user
+
+import (
+	"testing"
+
+	user_json "github.com/percona/mongodb-orchestration-tools/controller/user/json"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControllerUserCreateUpdateRole(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	role := &user_json.CustomRole{
+		Role:     "testRoleUpdate",
+		Database: "admin",
+		Privileges: []*user_json.Privilege{
+			{Resource: &user_json.Resource{Cluster: true}, Actions: []string{"serverStatus"}},
+		},
+	}
+	assert.Equal(t, ErrRoleNotFound, UpdateRole(testSession, role), ".UpdateRole() should fail for a missing role")
+	assert.NoError(t, CreateRole(testSession, role))
+	assert.Equal(t, ErrRoleExists, CreateRole(testSession, role), ".CreateRole() should fail for an existing role")
+
+	// update the privileges and inherited roles
+	role.Privileges[0].Resource = &user_json.Resource{Database: "test"}
+	role.Roles = []*user_json.Role{{Role: "read", Database: "test"}}
+	assert.NoError(t, UpdateRole(testSession, role))
+
+	roles, err := GetRoles(testSession, "admin")
+	assert.NoError(t, err)
+	var found *user_json.CustomRole
+	for _, r := range roles {
+		if r.Role == role.Role {
+			found = r
+		}
+	}
+	if assert.NotNil(t, found, ".GetRoles() should return the updated role") {
+		assert.True(t, rolesEqual(role, found))
+	}
+
+	// invalid role
+	assert.Error(t, CreateRole(testSession, &user_json.CustomRole{Role: "testRoleInvalid", Database: "admin"}))
+
+	assert.NoError(t, RemoveRole(testSession, role.Role, "admin"))
+	exists, err := roleExists(testSession, role.Role, "admin")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// removing a missing role is not an error
+	assert.NoError(t, RemoveRole(testSession, role.Role, "admin"))
+}
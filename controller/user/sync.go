@@ -0,0 +1,294 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package user
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	user_json "github.com/percona/mongodb-orchestration-tools/controller/user/json"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type SyncAction string
+
+const (
+	SyncActionCreate SyncAction = "create"
+	SyncActionUpdate SyncAction = "update"
+	SyncActionDrop   SyncAction = "drop"
+
+	syncKindUser = "user"
+	syncKindRole = "role"
+)
+
+// SyncChange is a change to a user or custom role needed to reach the state of a spec
+type SyncChange struct {
+	Action   SyncAction
+	Kind     string
+	Name     string
+	Database string
+	user     *user_json.SpecUser
+	role     *user_json.CustomRole
+}
+
+func (c *SyncChange) String() string {
+	return fmt.Sprintf("%s %s %s@%s", c.Action, c.Kind, c.Name, c.Database)
+}
+
+type SyncOptions struct {
+	// Prune drops the users and custom roles that are not in the spec
+	Prune bool
+
+	// DryRun returns the changes without applying them
+	DryRun bool
+
+	// AdminUser is the user of the session, it is never dropped
+	AdminUser string
+}
+
+type existingUser struct {
+	Username string
+	Database string
+}
+
+// isProtectedUser returns true if the user cannot be dropped by a sync
+func (opts *SyncOptions) isProtectedUser(username, dbName string) bool {
+	if username == opts.AdminUser && (dbName == SystemUserDatabase || dbName == "$external") {
+		return true
+	}
+	return isSystemUser(username, dbName)
+}
+
+// roleState returns the privileges and inherited roles of a role in a form
+// that does not depend on their order
+func roleState(role *user_json.CustomRole) (map[string][]string, []string) {
+	privileges := map[string][]string{}
+	for _, privilege := range role.Privileges {
+		resource := privilege.Resource.String()
+		privileges[resource] = append(privileges[resource], privilege.Actions...)
+	}
+	for _, actions := range privileges {
+		sort.Strings(actions)
+	}
+	roles := []string{}
+	for _, inherited := range role.Roles {
+		roles = append(roles, inherited.Role+"@"+inherited.Database)
+	}
+	sort.Strings(roles)
+	return privileges, roles
+}
+
+func rolesEqual(a, b *user_json.CustomRole) bool {
+	aPrivileges, aRoles := roleState(a)
+	bPrivileges, bRoles := roleState(b)
+	return reflect.DeepEqual(aPrivileges, bPrivileges) && reflect.DeepEqual(aRoles, bRoles)
+}
+
+// planSync returns the changes to the existing users and roles needed to reach
+// the state of the spec. Roles are changed before users, in the order of the spec.
+// Existing users are always updated as their passwords cannot be compared
+func planSync(spec *user_json.Spec, users []*existingUser, roles []*user_json.CustomRole, opts *SyncOptions) ([]*SyncChange, error) {
+	changes := []*SyncChange{}
+
+	specRoles := map[string]bool{}
+	for _, role := range spec.Roles {
+		specRoles[role.Role+"@"+role.Database] = true
+		change := &SyncChange{
+			Action:   SyncActionCreate,
+			Kind:     syncKindRole,
+			Name:     role.Role,
+			Database: role.Database,
+			role:     role,
+		}
+		for _, existing := range roles {
+			if existing.Role != role.Role || existing.Database != role.Database {
+				continue
+			}
+			change.Action = SyncActionUpdate
+			if rolesEqual(existing, role) {
+				change = nil
+			}
+			break
+		}
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	specUsers := map[string]bool{}
+	for _, user := range spec.Users {
+		if isSystemUser(user.Username, user.Database) {
+			return nil, ErrCannotChgSysUser
+		}
+		specUsers[user.Username+"@"+user.Database] = true
+		change := &SyncChange{
+			Action:   SyncActionCreate,
+			Kind:     syncKindUser,
+			Name:     user.Username,
+			Database: user.Database,
+			user:     user,
+		}
+		for _, existing := range users {
+			if existing.Username == user.Username && existing.Database == user.Database {
+				change.Action = SyncActionUpdate
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if opts.Prune {
+		for _, existing := range users {
+			if specUsers[existing.Username+"@"+existing.Database] || opts.isProtectedUser(existing.Username, existing.Database) {
+				continue
+			}
+			changes = append(changes, &SyncChange{
+				Action:   SyncActionDrop,
+				Kind:     syncKindUser,
+				Name:     existing.Username,
+				Database: existing.Database,
+			})
+		}
+		for _, existing := range roles {
+			if specRoles[existing.Role+"@"+existing.Database] {
+				continue
+			}
+			changes = append(changes, &SyncChange{
+				Action:   SyncActionDrop,
+				Kind:     syncKindRole,
+				Name:     existing.Role,
+				Database: existing.Database,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// getAllUsers returns the users of all databases
+func getAllUsers(session db.Session) ([]*existingUser, error) {
+	resp := usersInfoResp{}
+	err := session.RunOn(SystemUserDatabase, bson.D{
+		{Name: "usersInfo", Value: bson.M{"forAllDBs": true}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	users := []*existingUser{}
+	for _, user := range resp.Users {
+		users = append(users, &existingUser{Username: user.Username, Database: user.Database})
+	}
+	return users, nil
+}
+
+// syncDatabases returns the databases of the users and roles of the spec, of the
+// existing users and 'extraDBs', the custom roles of these databases are synced
+func syncDatabases(spec *user_json.Spec, users []*existingUser, extraDBs []string) []string {
+	dbNames := map[string]bool{SystemUserDatabase: true}
+	for _, dbName := range extraDBs {
+		dbNames[dbName] = true
+	}
+	for _, role := range spec.Roles {
+		dbNames[role.Database] = true
+	}
+	for _, user := range spec.Users {
+		dbNames[user.Database] = true
+		for _, role := range user.Roles {
+			dbNames[role.Database] = true
+		}
+	}
+	for _, user := range users {
+		dbNames[user.Database] = true
+	}
+	delete(dbNames, "$external")
+
+	dbs := []string{}
+	for dbName := range dbNames {
+		dbs = append(dbs, dbName)
+	}
+	sort.Strings(dbs)
+	return dbs
+}
+
+func applySyncChange(session db.Session, change *SyncChange) error {
+	switch change.Kind {
+	case syncKindRole:
+		switch change.Action {
+		case SyncActionCreate:
+			return CreateRole(session, change.role)
+		case SyncActionUpdate:
+			return UpdateRole(session, change.role)
+		}
+		return RemoveRole(session, change.Name, change.Database)
+	case syncKindUser:
+		if change.Action == SyncActionDrop {
+			return RemoveUser(session, change.Name, change.Database)
+		}
+		mgoUser, err := change.user.ToMgoUser(change.Database)
+		if err != nil {
+			return err
+		}
+		return UpdateUser(session, mgoUser, change.Database)
+	}
+	return fmt.Errorf("unknown sync change kind %q", change.Kind)
+}
+
+// Sync creates, updates and, if opts.Prune is set, drops users and custom roles
+// to reach the state of the spec. The changes are returned, on an error they are
+// the changes that were applied before the error
+func Sync(session db.Session, spec *user_json.Spec, opts *SyncOptions) ([]*SyncChange, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := getAllUsers(session)
+	if err != nil {
+		return nil, err
+	}
+	// pruning must find the custom roles of all databases, not only those of the spec
+	var extraDBs []string
+	if opts.Prune {
+		extraDBs, err = db.ListDatabases(session)
+		if err != nil {
+			return nil, err
+		}
+	}
+	roles := []*user_json.CustomRole{}
+	for _, dbName := range syncDatabases(spec, users, extraDBs) {
+		dbRoles, err := GetRoles(session, dbName)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, dbRoles...)
+	}
+
+	changes, err := planSync(spec, users, roles, opts)
+	if err != nil || opts.DryRun {
+		return changes, err
+	}
+	for i, change := range changes {
+		err = applySyncChange(session, change)
+		if err != nil {
+			return changes[:i], fmt.Errorf("cannot %s: %v", change, err)
+		}
+	}
+	return changes, nil
+}
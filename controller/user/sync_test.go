@@ -0,0 +1,214 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package user
+
+import (
+	"testing"
+
+	user_json "github.com/percona/mongodb-orchestration-tools/controller/user/json"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func testSyncSpec() *user_json.Spec {
+	return &user_json.Spec{
+		Roles: []*user_json.CustomRole{
+			{
+				Role:     "testSyncReader",
+				Database: "testSync",
+				Privileges: []*user_json.Privilege{
+					{Resource: &user_json.Resource{Database: "testSync"}, Actions: []string{"find", "listCollections"}},
+				},
+			},
+		},
+		Users: []*user_json.SpecUser{
+			{
+				User: user_json.User{
+					Username: "testSyncUser",
+					Password: "123456",
+					Roles:    []*user_json.Role{{Role: "testSyncReader", Database: "testSync"}},
+				},
+				Database: "testSync",
+			},
+		},
+	}
+}
+
+func TestControllerUserRolesEqual(t *testing.T) {
+	a := &user_json.CustomRole{
+		Privileges: []*user_json.Privilege{
+			{Resource: &user_json.Resource{Database: "test"}, Actions: []string{"find", "insert"}},
+			{Resource: &user_json.Resource{Cluster: true}, Actions: []string{"serverStatus"}},
+		},
+		Roles: []*user_json.Role{{Role: "read", Database: "a"}, {Role: "read", Database: "b"}},
+	}
+	b := &user_json.CustomRole{
+		Privileges: []*user_json.Privilege{
+			{Resource: &user_json.Resource{Cluster: true}, Actions: []string{"serverStatus"}},
+			{Resource: &user_json.Resource{Database: "test"}, Actions: []string{"insert", "find"}},
+		},
+		Roles: []*user_json.Role{{Role: "read", Database: "b"}, {Role: "read", Database: "a"}},
+	}
+	assert.True(t, rolesEqual(a, b), ".rolesEqual() should not depend on the order of privileges, actions and roles")
+
+	b.Privileges[1].Resource.Collection = "test"
+	assert.False(t, rolesEqual(a, b))
+	b.Privileges[1].Resource.Collection = ""
+	b.Roles = b.Roles[:1]
+	assert.False(t, rolesEqual(a, b))
+}
+
+func TestControllerUserPlanSync(t *testing.T) {
+	SystemUsernames = []string{"admin"}
+	spec := testSyncSpec()
+	opts := &SyncOptions{AdminUser: "userAdmin"}
+
+	// nothing exists
+	changes, err := planSync(spec, []*existingUser{}, []*user_json.CustomRole{}, opts)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "create role testSyncReader@testSync", changes[0].String())
+		assert.Equal(t, "create user testSyncUser@testSync", changes[1].String())
+	}
+
+	// everything exists, the unchanged role is skipped
+	users := []*existingUser{
+		{Username: "testSyncUser", Database: "testSync"},
+		{Username: "oldUser", Database: "testSync"},
+		{Username: "admin", Database: SystemUserDatabase},
+		{Username: "userAdmin", Database: SystemUserDatabase},
+	}
+	roles := []*user_json.CustomRole{
+		{
+			Role:     "testSyncReader",
+			Database: "testSync",
+			Privileges: []*user_json.Privilege{
+				{Resource: &user_json.Resource{Database: "testSync"}, Actions: []string{"listCollections", "find"}},
+			},
+		},
+		{Role: "oldRole", Database: "testSync", Roles: []*user_json.Role{{Role: "read", Database: "testSync"}}},
+	}
+	changes, err = planSync(spec, users, roles, opts)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "update user testSyncUser@testSync", changes[0].String())
+	}
+
+	// changed role
+	roles[0].Privileges[0].Actions = []string{"find"}
+	changes, err = planSync(spec, users, roles, opts)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "update role testSyncReader@testSync", changes[0].String())
+	}
+
+	// prune skips the system and admin users
+	opts.Prune = true
+	changes, err = planSync(spec, users, roles, opts)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 4) {
+		assert.Equal(t, "drop user oldUser@testSync", changes[2].String())
+		assert.Equal(t, "drop role oldRole@testSync", changes[3].String())
+	}
+
+	// system users cannot be in the spec
+	spec.Users[0].Username = "admin"
+	spec.Users[0].Database = SystemUserDatabase
+	_, err = planSync(spec, users, roles, opts)
+	assert.Equal(t, ErrCannotChgSysUser, err)
+}
+
+func TestControllerUserSyncDatabases(t *testing.T) {
+	spec := testSyncSpec()
+	spec.Users[0].Roles = append(spec.Users[0].Roles, &user_json.Role{Role: "read", Database: "other"})
+	users := []*existingUser{
+		{Username: "test", Database: "users"},
+		{Username: "CN=test", Database: "$external"},
+	}
+	assert.Equal(t, []string{"admin", "other", "testSync", "users"}, syncDatabases(spec, users, nil))
+	assert.Equal(t, []string{"admin", "app", "local", "other", "testSync", "users"}, syncDatabases(spec, users, []string{"admin", "app", "local"}))
+}
+
+func TestControllerUserSync(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	spec := testSyncSpec()
+
+	// dry run
+	changes, err := Sync(testSession, spec, &SyncOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Error(t, checkUserExists(testSession, "testSyncUser", "testSync"), ".Sync() should not create users on a dry run")
+
+	changes, err = Sync(testSession, spec, &SyncOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.NoError(t, checkUserExists(testSession, "testSyncUser", "testSync"))
+
+	// the unchanged role is not updated again
+	changes, err = Sync(testSession, spec, &SyncOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, SyncActionUpdate, changes[0].Action)
+	}
+
+	// prune the user and role, as a dry run to keep the other users of the test server
+	spec.Users = []*user_json.SpecUser{}
+	spec.Roles = []*user_json.CustomRole{}
+	changes, err = Sync(testSession, spec, &SyncOptions{Prune: true, DryRun: true, AdminUser: testutils.MongodbAdminUser})
+	assert.NoError(t, err)
+	drops := []string{}
+	for _, change := range changes {
+		assert.Equal(t, SyncActionDrop, change.Action)
+		drops = append(drops, change.String())
+	}
+	assert.Contains(t, drops, "drop user testSyncUser@testSync")
+	assert.Contains(t, drops, "drop role testSyncReader@testSync")
+	assert.NotContains(t, drops, "drop user "+testutils.MongodbAdminUser+"@admin")
+
+	assert.NoError(t, RemoveUser(testSession, "testSyncUser", "testSync"))
+	assert.NoError(t, RemoveRole(testSession, "testSyncReader", "testSync"))
+}
+
+func TestControllerUserSyncPruneAllDatabases(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	// a custom role of a database without users or roles in the spec
+	dbName := "testSyncPrune"
+	resp := cmdResp{}
+	assert.NoError(t, testSession.RunOn(dbName, bson.D{
+		{Name: "insert", Value: "test"},
+		{Name: "documents", Value: []bson.M{{"test": true}}},
+	}, &resp))
+	defer testSession.RunOn(dbName, bson.D{{Name: "dropDatabase", Value: 1}}, &resp)
+	role := &user_json.CustomRole{
+		Role:     "testSyncPruneReader",
+		Database: dbName,
+		Privileges: []*user_json.Privilege{
+			{Resource: &user_json.Resource{Database: dbName}, Actions: []string{"find"}},
+		},
+	}
+	assert.NoError(t, CreateRole(testSession, role))
+	defer RemoveRole(testSession, role.Role, dbName)
+
+	changes, err := Sync(testSession, &user_json.Spec{}, &SyncOptions{Prune: true, DryRun: true, AdminUser: testutils.MongodbAdminUser})
+	assert.NoError(t, err)
+	drops := []string{}
+	for _, change := range changes {
+		drops = append(drops, change.String())
+	}
+	assert.Contains(t, drops, "drop role testSyncPruneReader@"+dbName)
+}
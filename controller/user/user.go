@@ -17,8 +17,10 @@ package user
 import (
 	"errors"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
 const (
@@ -29,11 +31,68 @@ const (
 	RoleUserAdminAny   mgo.Role = mgo.RoleUserAdminAny
 )
 
+// majorityWriteConcern is the write concern of user changes
+var majorityWriteConcern = bson.M{"w": "majority", "j": true}
+
 type UserChangeData struct {
 	Users []*mgo.User `bson:"users"`
 }
 
-func UpdateUser(session *mgo.Session, user *mgo.User, dbName string) error {
+type usersInfoResp struct {
+	Users []struct {
+		Username string `bson:"user"`
+		Database string `bson:"db"`
+	} `bson:"users"`
+
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+type cmdResp struct {
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+// userExists returns true if the user exists in the database 'dbName'
+func userExists(session db.Session, username, dbName string) (bool, error) {
+	resp := usersInfoResp{}
+	err := session.RunOn(dbName, bson.D{{Name: "usersInfo", Value: username}}, &resp)
+	if err != nil {
+		return false, err
+	}
+	if resp.Ok == 0 {
+		return false, errors.New(resp.Errmsg)
+	}
+	return len(resp.Users) > 0, nil
+}
+
+// userRoles returns the roles of a user in the format of the 'createUser' server command
+func userRoles(user *mgo.User, dbName string) []bson.M {
+	roles := []bson.M{}
+	for _, role := range user.Roles {
+		roles = append(roles, bson.M{"role": string(role), "db": dbName})
+	}
+	for roleDB, otherRoles := range user.OtherDBRoles {
+		for _, role := range otherRoles {
+			roles = append(roles, bson.M{"role": string(role), "db": roleDB})
+		}
+	}
+	return roles
+}
+
+func runUserCmd(session db.Session, dbName string, cmd bson.D) error {
+	resp := cmdResp{}
+	err := session.RunOn(dbName, cmd, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Ok == 0 {
+		return errors.New(resp.Errmsg)
+	}
+	return nil
+}
+
+func UpdateUser(session db.Session, user *mgo.User, dbName string) error {
 	if user.Username == "" || user.Password == "" {
 		return errors.New("No username or password defined for user")
 	}
@@ -48,10 +107,23 @@ func UpdateUser(session *mgo.Session, user *mgo.User, dbName string) error {
 		"db":           dbName,
 	}).Info("Adding/updating MongoDB user")
 
-	return session.DB(dbName).UpsertUser(user)
+	exists, err := userExists(session, user.Username, dbName)
+	if err != nil {
+		return err
+	}
+	cmdName := "createUser"
+	if exists {
+		cmdName = "updateUser"
+	}
+	return runUserCmd(session, dbName, bson.D{
+		{Name: cmdName, Value: user.Username},
+		{Name: "pwd", Value: user.Password},
+		{Name: "roles", Value: userRoles(user, dbName)},
+		{Name: "writeConcern", Value: majorityWriteConcern},
+	})
 }
 
-func UpdateUsers(session *mgo.Session, users []*mgo.User, dbName string) error {
+func UpdateUsers(session db.Session, users []*mgo.User, dbName string) error {
 	for _, user := range users {
 		err := UpdateUser(session, user, dbName)
 		if err != nil {
@@ -61,14 +133,20 @@ func UpdateUsers(session *mgo.Session, users []*mgo.User, dbName string) error {
 	return nil
 }
 
-func RemoveUser(session *mgo.Session, username, db string) error {
-	log.Infof("Removing user %s from db %s", username, db)
-	err := session.DB(db).RemoveUser(username)
-	if err == mgo.ErrNotFound {
-		log.Warnf("Cannot remove user, %s does not exist in database %s", username, db)
+func RemoveUser(session db.Session, username, dbName string) error {
+	log.Infof("Removing user %s from db %s", username, dbName)
+	exists, err := userExists(session, username, dbName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Warnf("Cannot remove user, %s does not exist in database %s", username, dbName)
 		return nil
 	}
-	return err
+	return runUserCmd(session, dbName, bson.D{
+		{Name: "dropUser", Value: username},
+		{Name: "writeConcern", Value: majorityWriteConcern},
+	})
 }
 
 func isSystemUser(username, db string) bool {
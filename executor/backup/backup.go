@@ -0,0 +1,241 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	jobName       = "Backup"
+	backupTagName = "backup"
+)
+
+// IsMasterResp is the subset of the 'isMaster' server command response used to
+// find the dedicated backup member of a replica set
+type IsMasterResp struct {
+	SetName string            `bson:"setName" json:"setName"`
+	Hidden  bool              `bson:"hidden" json:"hidden"`
+	Tags    map[string]string `bson:"tags" json:"tags"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+// IsBackupMember returns true if the member is the hidden secondary tagged 'backup: true'
+// by the watchdog
+func (r *IsMasterResp) IsBackupMember() bool {
+	return r.Hidden && r.Tags[backupTagName] == "true"
+}
+
+type Backup struct {
+	sync.Mutex
+	config    *Config
+	session   db.Session
+	schedule  *Schedule
+	dumper    Dumper
+	storage   backupStorage.Storage
+	collector *Collector
+	running   bool
+}
+
+func New(config *Config, session db.Session, schedule *Schedule, dumper Dumper, storage backupStorage.Storage) *Backup {
+	return &Backup{
+		config:    config,
+		session:   session,
+		schedule:  schedule,
+		dumper:    dumper,
+		storage:   storage,
+		collector: NewCollector(),
+	}
+}
+
+func (b *Backup) Name() string {
+	return jobName
+}
+
+func (b *Backup) DoRun() bool {
+	return b.config.Enabled
+}
+
+func (b *Backup) setRunning(running bool) {
+	b.Lock()
+	defer b.Unlock()
+	b.running = running
+}
+
+func (b *Backup) IsRunning() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.running
+}
+
+// Collector returns the prometheus.Collector of the backup metrics
+func (b *Backup) Collector() *Collector {
+	return b.collector
+}
+
+// GetIsMaster runs the 'isMaster' server command on 'session'
+func GetIsMaster(session db.Session) (*IsMasterResp, error) {
+	resp := &IsMasterResp{}
+	err := session.Run(bson.D{{Name: "isMaster", Value: 1}}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	return resp, nil
+}
+
+// backup streams an archive from the Dumper to the Storage
+func (b *Backup) backup(ctx context.Context, name string) (int64, error) {
+	if b.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.Timeout)
+		defer cancel()
+	}
+
+	reader, writer := io.Pipe()
+	dumpErr := make(chan error, 1)
+	go func() {
+		err := b.dumper.Dump(ctx, writer)
+		writer.CloseWithError(err)
+		dumpErr <- err
+	}()
+
+	size, err := b.storage.Put(ctx, name, reader)
+	// unblock the Dumper if the Storage failed before reading the whole archive
+	reader.CloseWithError(err)
+	if dErr := <-dumpErr; dErr != nil {
+		return 0, dErr
+	}
+	return size, err
+}
+
+// prune deletes the oldest archives of replset 'rsName' beyond the retention count
+func (b *Backup) prune(ctx context.Context, rsName string) error {
+	names, err := b.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	archives := backupStorage.FilterArchives(names, rsName)
+	if b.config.Retention > 0 && len(archives) > b.config.Retention {
+		expired := archives[:len(archives)-b.config.Retention]
+		for _, name := range expired {
+			log.WithFields(log.Fields{
+				"storage": b.storage.Name(),
+				"backup":  name,
+			}).Info("Deleting expired backup")
+			err = b.storage.Delete(ctx, name)
+			if err != nil {
+				return fmt.Errorf("cannot delete expired backup %s: %v", name, err)
+			}
+		}
+		archives = archives[len(expired):]
+	}
+	b.collector.StoredBackups.Set(float64(len(archives)))
+	return nil
+}
+
+// runBackup runs a backup of replset 'rsName' and applies the retention
+func (b *Backup) runBackup(ctx context.Context, rsName string) error {
+	start := time.Now()
+	name := backupStorage.ArchiveName(rsName, start)
+	logger := log.WithFields(log.Fields{
+		"storage": b.storage.Name(),
+		"backup":  name,
+	})
+	logger.Info("Starting backup")
+
+	size, err := b.backup(ctx, name)
+	duration := time.Since(start)
+	b.collector.LastDurationSeconds.Set(duration.Seconds())
+	if err != nil {
+		b.collector.BackupsTotal.WithLabelValues("failed").Inc()
+		return err
+	}
+	b.collector.BackupsTotal.WithLabelValues("success").Inc()
+	b.collector.LastSuccessTimestamp.Set(float64(start.Unix()))
+	b.collector.LastSizeBytes.Set(float64(size))
+	logger.WithFields(log.Fields{
+		"size":     size,
+		"duration": duration,
+	}).Info("Completed backup")
+
+	return b.prune(ctx, rsName)
+}
+
+// runScheduled runs a backup if this instance is the backup member of the replset
+func (b *Backup) runScheduled(ctx context.Context) {
+	isMaster, err := GetIsMaster(b.session)
+	if err != nil {
+		log.Errorf("Cannot check if backup member: %s", err)
+		return
+	}
+	if !isMaster.IsBackupMember() {
+		log.Debug("Skipping backup on non-backup member")
+		return
+	}
+
+	err = b.runBackup(ctx, isMaster.SetName)
+	if err != nil && ctx.Err() == nil {
+		log.Errorf("Backup failed: %s", err)
+	}
+}
+
+func (b *Backup) Run(ctx context.Context) error {
+	if b.DoRun() == false {
+		log.Warn("Backup disabled! Skipping start")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"schedule":  b.schedule,
+		"storage":   b.storage.Name(),
+		"retention": b.config.Retention,
+	}).Info("Starting backup scheduler")
+
+	b.setRunning(true)
+	defer b.setRunning(false)
+	for {
+		next := b.schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			return fmt.Errorf("backup schedule %q has no next run time", b.schedule)
+		}
+		log.Debugf("Next backup scheduled at %s", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			b.runScheduled(ctx)
+		case <-ctx.Done():
+			log.Info("Stopping backup scheduler")
+			timer.Stop()
+			return nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/executor/backup/mocks"
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	storageMocks "github.com/percona/mongodb-orchestration-tools/internal/backup/mocks"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDumper is a Dumper that writes 'data' and returns 'err'. A mocks.Dumper is not
+// used as the mock formats its arguments, racing with the concurrent use of the pipe
+type testDumper struct {
+	data string
+	err  error
+}
+
+func (d *testDumper) Dump(ctx context.Context, out io.Writer) error {
+	out.Write([]byte(d.data))
+	return d.err
+}
+
+func TestExecutorBackupIsBackupMember(t *testing.T) {
+	assert.True(t, (&IsMasterResp{Hidden: true, Tags: map[string]string{"backup": "true"}}).IsBackupMember())
+	assert.False(t, (&IsMasterResp{Tags: map[string]string{"backup": "true"}}).IsBackupMember())
+	assert.False(t, (&IsMasterResp{Hidden: true}).IsBackupMember())
+}
+
+func TestExecutorBackupGetIsMaster(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	isMaster, err := GetIsMaster(testSession)
+	assert.NoError(t, err)
+	assert.Equal(t, testutils.MongodbReplsetName, isMaster.SetName)
+	assert.False(t, isMaster.IsBackupMember())
+}
+
+func TestExecutorBackupRunBackup(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	storage := backupStorage.NewLocalStorage(dir)
+	for _, name := range []string{"rs-20180101T000000Z.archive.gz", "rs-20180102T000000Z.archive.gz", "other-20180101T000000Z.archive.gz"} {
+		_, err := storage.Put(ctx, name, strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+
+	b := New(&Config{Retention: 2}, nil, nil, &testDumper{data: "archive"}, storage)
+	assert.NoError(t, b.runBackup(ctx, "rs"))
+
+	names, _ := storage.List(ctx)
+	assert.Len(t, names, 3, "the oldest backup of the replset should be deleted")
+	assert.Equal(t, "other-20180101T000000Z.archive.gz", names[0])
+	assert.Equal(t, "rs-20180102T000000Z.archive.gz", names[1])
+	data, _ := ioutil.ReadFile(dir + "/" + names[2])
+	assert.Equal(t, "archive", string(data))
+
+	assert.Equal(t, float64(1), testutils.MetricValue(t, b.collector.BackupsTotal.WithLabelValues("success")))
+	assert.Equal(t, float64(7), testutils.MetricValue(t, b.collector.LastSizeBytes))
+	assert.Equal(t, float64(2), testutils.MetricValue(t, b.collector.StoredBackups))
+	assert.NotZero(t, testutils.MetricValue(t, b.collector.LastSuccessTimestamp))
+}
+
+func TestExecutorBackupRunBackupFailed(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	storage := backupStorage.NewLocalStorage(dir)
+	b := New(&Config{}, nil, nil, &testDumper{data: "partial", err: errors.New("dump error")}, storage)
+	assert.EqualError(t, b.runBackup(context.Background(), "rs"), "dump error")
+	assert.Equal(t, float64(1), testutils.MetricValue(t, b.collector.BackupsTotal.WithLabelValues("failed")))
+
+	names, _ := storage.List(context.Background())
+	assert.Len(t, names, 0, "a failed backup should not be stored")
+
+	// a failed storage does not block the dumper
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, []byte{}, 0644))
+	b = New(&Config{}, nil, nil, &testDumper{data: "archive"}, backupStorage.NewLocalStorage(file))
+	assert.Error(t, b.runBackup(context.Background(), "rs"))
+}
+
+func TestExecutorBackupRun(t *testing.T) {
+	schedule, _ := ParseSchedule("@yearly")
+	mockStorage := &storageMocks.Storage{}
+	mockStorage.On("Name").Return("mock")
+	b := New(&Config{Enabled: true}, nil, schedule, &mocks.Dumper{}, mockStorage)
+	assert.Equal(t, "Backup", b.Name())
+	assert.True(t, b.DoRun())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan bool)
+	go func() {
+		b.Run(ctx)
+		stopped <- true
+	}()
+	for tries := 0; !b.IsRunning() && tries < 100; tries++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, b.IsRunning())
+
+	cancel()
+	<-stopped
+	assert.False(t, b.IsRunning())
+}
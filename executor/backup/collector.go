@@ -0,0 +1,83 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "mongodb"
+	subsystem = "backup"
+)
+
+type Collector struct {
+	BackupsTotal         *prometheus.CounterVec
+	LastSuccessTimestamp prometheus.Gauge
+	LastDurationSeconds  prometheus.Gauge
+	LastSizeBytes        prometheus.Gauge
+	StoredBackups        prometheus.Gauge
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		BackupsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "total",
+			Help:      "The total number of backups by status",
+		}, []string{"status"}),
+		LastSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "The unix time of the last successful backup",
+		}),
+		LastDurationSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "last_duration_seconds",
+			Help:      "The duration of the last backup, in seconds",
+		}),
+		LastSizeBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "last_size_bytes",
+			Help:      "The size of the last successful backup archive, in bytes",
+		}),
+		StoredBackups: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stored",
+			Help:      "The number of backup archives in storage after retention",
+		}),
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.BackupsTotal.Collect(ch)
+	c.LastSuccessTimestamp.Collect(ch)
+	c.LastDurationSeconds.Collect(ch)
+	c.LastSizeBytes.Collect(ch)
+	c.StoredBackups.Collect(ch)
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.BackupsTotal.Describe(ch)
+	c.LastSuccessTimestamp.Describe(ch)
+	c.LastDurationSeconds.Describe(ch)
+	c.LastSizeBytes.Describe(ch)
+	c.StoredBackups.Describe(ch)
+}
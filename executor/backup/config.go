@@ -0,0 +1,42 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"time"
+
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+)
+
+const (
+	DefaultSchedule     = "0 0 * * *"
+	DefaultRetention    = "7"
+	DefaultTimeout      = "12h"
+	DefaultMongodumpBin = "/usr/bin/mongodump"
+)
+
+type Config struct {
+	DB           *db.Config
+	Storage      *backupStorage.StorageConfig
+	Enabled      bool
+	Schedule     string
+	TmpDir       string
+	Retention    int
+	Timeout      time.Duration
+	MongodumpBin string
+	Username     string
+	Password     string
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// Dumper is an interface for writing a backup archive of a MongoDB instance
+type Dumper interface {
+	Dump(ctx context.Context, out io.Writer) error
+}
+
+// MongodumpDumper is a Dumper that streams a gzipped 'mongodump --oplog' archive
+type MongodumpDumper struct {
+	config *Config
+}
+
+func NewMongodumpDumper(config *Config) *MongodumpDumper {
+	return &MongodumpDumper{config: config}
+}
+
+// args returns the mongodump command-line arguments. The archive is written to stdout
+// and the password is read from the tool config file 'configFile', if set
+func (d *MongodumpDumper) args(configFile string) []string {
+	args := []string{
+		"--oplog",
+		"--archive",
+		"--gzip",
+	}
+	if d.config.DB != nil && d.config.DB.DialInfo != nil {
+		dialInfo := d.config.DB.DialInfo
+		if len(dialInfo.Addrs) > 0 {
+			args = append(args, "--host="+dialInfo.Addrs[0])
+		}
+		if dialInfo.Source != "" {
+			args = append(args, "--authenticationDatabase="+dialInfo.Source)
+		}
+	}
+	if d.config.Username != "" {
+		args = append(args, "--username="+d.config.Username)
+	}
+	if configFile != "" {
+		args = append(args, "--config="+configFile)
+	}
+	if d.config.DB != nil {
+		args = append(args, d.config.DB.SSL.ToolArgs()...)
+	}
+	return args
+}
+
+// lastLine returns the last non-empty line of 'output'
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return lines[len(lines)-1]
+}
+
+// Dump runs mongodump, writing the archive to 'out' until it completes or 'ctx' is done
+func (d *MongodumpDumper) Dump(ctx context.Context, out io.Writer) error {
+	log.WithFields(log.Fields{
+		"command": d.config.MongodumpBin,
+		"user":    d.config.Username,
+	}).Debug("Running mongodump")
+
+	var configFile string
+	if d.config.Password != "" {
+		var err error
+		configFile, err = db.WriteToolConfig(d.config.TmpDir, d.config.Password)
+		if err != nil {
+			return fmt.Errorf("cannot write mongodump config file: %v", err)
+		}
+		defer os.Remove(configFile)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.config.MongodumpBin, d.args(configFile)...)
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("mongodump failed: %v: %s", err, lastLine(stderr.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+)
+
+func TestExecutorBackupMongodumpDumperArgs(t *testing.T) {
+	dumper := NewMongodumpDumper(&Config{
+		DB: &db.Config{
+			DialInfo: &mgo.DialInfo{
+				Addrs:  []string{"localhost:27017"},
+				Source: "admin",
+			},
+			SSL: &db.SSLConfig{
+				Enabled:  true,
+				CAFile:   "/ca.pem",
+				Insecure: true,
+			},
+		},
+		Username: "backup",
+		Password: "123456",
+	})
+	assert.Equal(t, []string{
+		"--oplog",
+		"--archive",
+		"--gzip",
+		"--host=localhost:27017",
+		"--authenticationDatabase=admin",
+		"--username=backup",
+		"--config=/tmp/mongodump.yaml",
+		"--ssl",
+		"--sslCAFile=/ca.pem",
+		"--sslAllowInvalidCertificates",
+		"--sslAllowInvalidHostnames",
+	}, dumper.args("/tmp/mongodump.yaml"))
+
+	assert.Equal(t, []string{"--oplog", "--archive", "--gzip"}, NewMongodumpDumper(&Config{}).args(""))
+}
+
+func TestExecutorBackupMongodumpDumperDump(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	out := &bytes.Buffer{}
+	dumper := NewMongodumpDumper(&Config{
+		MongodumpBin: testutils.WriteScript(t, dir, "mongodump", "echo \"$@\""),
+	})
+	assert.NoError(t, dumper.Dump(context.Background(), out))
+	assert.Equal(t, "--oplog --archive --gzip\n", out.String())
+
+	dumper.config.MongodumpBin = testutils.WriteScript(t, dir, "mongodump", "echo 'Failed: error connecting' >&2; exit 1")
+	assert.EqualError(t, dumper.Dump(context.Background(), out), "mongodump failed: exit status 1: Failed: error connecting")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, dumper.Dump(ctx, out))
+
+	// the password is passed in a config file that is removed after the dump
+	out.Reset()
+	dumper.config.TmpDir = dir
+	dumper.config.Password = "123456"
+	dumper.config.MongodumpBin = testutils.WriteScript(t, dir, "mongodump", `for arg in "$@"; do case "$arg" in --config=*) cat "${arg#--config=}";; esac; done`)
+	assert.NoError(t, dumper.Dump(context.Background(), out))
+	assert.Equal(t, "password: \"123456\"\n", out.String())
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+}
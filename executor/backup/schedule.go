@@ -0,0 +1,189 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxScheduleYears is the number of years to search for the next time of a Schedule
+const maxScheduleYears = 5
+
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type scheduleField struct {
+	name string
+	min  uint
+	max  uint
+}
+
+var scheduleFields = []scheduleField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Schedule is a cron schedule in the standard 5-field format: 'minute hour day-of-month month day-of-week'
+type Schedule struct {
+	spec   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDom bool
+	anyDow bool
+}
+
+// parseScheduleField parses a comma-separated list of '*', 'n', 'n-m' items with an
+// optional '/step' into a bitset
+func parseScheduleField(value string, field scheduleField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		step := uint(1)
+		if i := strings.Index(item, "/"); i >= 0 {
+			parsed, err := strconv.ParseUint(item[i+1:], 10, 8)
+			if err != nil || parsed == 0 {
+				return 0, fmt.Errorf("invalid %s step: %q", field.name, item)
+			}
+			step = uint(parsed)
+			item = item[:i]
+		}
+
+		start, end := field.min, field.max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			parsed, err := strconv.ParseUint(bounds[0], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s: %q", field.name, item)
+			}
+			start = uint(parsed)
+			end = start
+			if len(bounds) == 2 {
+				parsed, err = strconv.ParseUint(bounds[1], 10, 8)
+				if err != nil {
+					return 0, fmt.Errorf("invalid %s: %q", field.name, item)
+				}
+				end = uint(parsed)
+			} else if step > 1 {
+				end = field.max
+			}
+		}
+		if start < field.min || end > field.max || start > end {
+			return 0, fmt.Errorf("%s out of range %d-%d: %q", field.name, field.min, field.max, item)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+	return bits, nil
+}
+
+// ParseSchedule parses a cron schedule spec, such as '0 0 * * *' or '@daily'
+func ParseSchedule(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 1 {
+		if expanded, ok := scheduleDescriptors[fields[0]]; ok {
+			fields = strings.Fields(expanded)
+		}
+	}
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected %d fields", spec, len(scheduleFields))
+	}
+
+	bitsets := make([]uint64, len(fields))
+	for i, value := range fields {
+		bits, err := parseScheduleField(value, scheduleFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+		}
+		bitsets[i] = bits
+	}
+
+	// sunday is both 0 and 7
+	dow := bitsets[4]
+	if dow&(1<<7) > 0 {
+		dow |= 1
+	}
+
+	return &Schedule{
+		spec:   spec,
+		minute: bitsets[0],
+		hour:   bitsets[1],
+		dom:    bitsets[2],
+		month:  bitsets[3],
+		dow:    dow,
+		anyDom: strings.HasPrefix(fields[2], "*"),
+		anyDow: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// matchDay returns true if the day of 't' matches the schedule. Like cron, when both
+// day-of-month and day-of-week are restricted a day matching either is a match
+func (s *Schedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) > 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) > 0
+	if s.anyDom || s.anyDow {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first time after 't' matching the schedule, in the location of 't'.
+// A zero time.Time is returned if there is no match within the next 5 years
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxScheduleYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorBackupParseSchedule(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@never"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, ".ParseSchedule() should fail for %q", spec)
+	}
+
+	schedule, err := ParseSchedule("@daily")
+	assert.NoError(t, err)
+	assert.Equal(t, "@daily", schedule.String())
+	assert.Equal(t, uint64(1), schedule.minute)
+	assert.Equal(t, uint64(1), schedule.hour)
+
+	schedule, err = ParseSchedule("0,30 1-3 */10 * 7")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1|1<<30), schedule.minute)
+	assert.Equal(t, uint64(1<<1|1<<2|1<<3), schedule.hour)
+	assert.Equal(t, uint64(1<<1|1<<11|1<<21|1<<31), schedule.dom)
+	assert.Equal(t, uint64(1|1<<7), schedule.dow, "sunday should match both 0 and 7")
+}
+
+func TestExecutorBackupScheduleNext(t *testing.T) {
+	start := time.Date(2018, time.December, 31, 23, 59, 30, 0, time.UTC)
+	for spec, expected := range map[string]time.Time{
+		"* * * * *":     time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"@hourly":       time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"30 2 * * *":    time.Date(2019, time.January, 1, 2, 30, 0, 0, time.UTC),
+		"0 0 * * 0":     time.Date(2019, time.January, 6, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":    time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"*/15 12 * * *": time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC),
+		"0 0 15 * 1":    time.Date(2019, time.January, 7, 0, 0, 0, 0, time.UTC),
+	} {
+		schedule, err := ParseSchedule(spec)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, schedule.Next(start), "unexpected next time for %q", spec)
+	}
+
+	// the next time is always after the given time
+	schedule, _ := ParseSchedule("0 0 * * *")
+	midnight := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, midnight.AddDate(0, 0, 1), schedule.Next(midnight))
+
+	// no match
+	schedule, _ = ParseSchedule("0 0 31 2 *")
+	assert.True(t, schedule.Next(start).IsZero())
+}
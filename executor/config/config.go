@@ -17,14 +17,23 @@ package config
 import (
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/executor/backup"
+	"github.com/percona/mongodb-orchestration-tools/executor/exporter"
 	"github.com/percona/mongodb-orchestration-tools/executor/metrics"
 	"github.com/percona/mongodb-orchestration-tools/executor/mongodb"
+	"github.com/percona/mongodb-orchestration-tools/executor/pitr"
+	"github.com/percona/mongodb-orchestration-tools/executor/pmm"
+	"github.com/percona/mongodb-orchestration-tools/executor/profiler"
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
 )
 
 const (
 	DefaultDelayBackgroundJob = "15s"
 	DefaultConnectRetrySleep  = "5s"
+	DefaultJobRestartPolicy   = "on-panic"
+	DefaultJobRestartDelay    = "10s"
+	DefaultJobMaxRestarts     = "0"
+	DefaultJobStopTimeout     = "10s"
 )
 
 type NodeType string
@@ -42,9 +51,18 @@ type Config struct {
 	DB                 *db.Config
 	MongoDB            *mongodb.Config
 	Metrics            *metrics.Config
+	Exporter           *exporter.Config
+	Backup             *backup.Config
+	PITR               *pitr.Config
+	PMM                *pmm.Config
+	Profiler           *profiler.Config
 	NodeType           NodeType
 	ServiceName        string
 	DelayBackgroundJob time.Duration
+	JobRestartPolicy   string
+	JobRestartDelay    time.Duration
+	JobMaxRestarts     int
+	JobStopTimeout     time.Duration
 	ConnectRetrySleep  time.Duration
 	Verbose            bool
 }
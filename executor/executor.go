@@ -21,13 +21,11 @@ import (
 
 type Executor struct {
 	config *config.Config
-	quit   *chan bool
 }
 
-func New(config *config.Config, quit *chan bool) *Executor {
+func New(config *config.Config) *Executor {
 	return &Executor{
 		config: config,
-		quit:   quit,
 	}
 }
 
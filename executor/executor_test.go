@@ -23,7 +23,7 @@ import (
 )
 
 func TestExecutorNew(t *testing.T) {
-	testExecutor = New(testExecutorConfig, &testQuitChan)
+	testExecutor = New(testExecutorConfig)
 	assert.NotNil(t, testExecutor, ".New() should not return nil")
 }
 
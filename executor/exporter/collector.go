@@ -0,0 +1,168 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "mongodb"
+
+// Stats is a snapshot of the stats gathered by a Scraper, nil fields were not gathered
+type Stats struct {
+	ServerStatus  *ServerStatus
+	ReplSetStatus *ReplSetStatus
+	DbStats       []*DbStats
+	OplogStats    *OplogStats
+}
+
+func newDesc(subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+// Collector is a prometheus.Collector of the last Stats snapshot
+type Collector struct {
+	sync.Mutex
+	stats *Stats
+
+	ScrapeErrorsTotal *prometheus.CounterVec
+
+	uptime             *prometheus.Desc
+	connections        *prometheus.Desc
+	connectionsCreated *prometheus.Desc
+	opcounters         *prometheus.Desc
+	wiredTigerCache    *prometheus.Desc
+	replSetMyState     *prometheus.Desc
+	replSetLag         *prometheus.Desc
+	dbCollections      *prometheus.Desc
+	dbObjects          *prometheus.Desc
+	dbDataSizeBytes    *prometheus.Desc
+	dbStorageSizeBytes *prometheus.Desc
+	dbIndexSizeBytes   *prometheus.Desc
+	oplogWindowSeconds *prometheus.Desc
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		ScrapeErrorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "scrape_errors_total",
+			Help:      "The total number of errors scraping stats from mongod",
+		}, []string{"source"}),
+		uptime:             newDesc("", "uptime_seconds", "The number of seconds the mongod has been running"),
+		connections:        newDesc("", "connections", "The number of incoming connections by state", "state"),
+		connectionsCreated: newDesc("", "connections_created_total", "The total number of incoming connections created"),
+		opcounters:         newDesc("", "opcounters_total", "The total number of operations by type", "type"),
+		wiredTigerCache:    newDesc("wiredtiger", "cache_bytes", "The size of the WiredTiger cache by type", "type"),
+		replSetMyState:     newDesc("replset", "my_state", "The replication state of the mongod", "set"),
+		replSetLag:         newDesc("replset", "lag_seconds", "The replication lag of the mongod behind the primary", "set"),
+		dbCollections:      newDesc("db", "collections", "The number of collections in a database", "db"),
+		dbObjects:          newDesc("db", "objects", "The number of objects in a database", "db"),
+		dbDataSizeBytes:    newDesc("db", "data_size_bytes", "The uncompressed size of the data in a database", "db"),
+		dbStorageSizeBytes: newDesc("db", "storage_size_bytes", "The storage size of the data in a database", "db"),
+		dbIndexSizeBytes:   newDesc("db", "index_size_bytes", "The storage size of the indexes in a database", "db"),
+		oplogWindowSeconds: newDesc("oplog", "window_seconds", "The number of seconds between the first and last oplog entries"),
+	}
+}
+
+// Update replaces the stats snapshot exported by the Collector
+func (c *Collector) Update(stats *Stats) {
+	c.Lock()
+	defer c.Unlock()
+	c.stats = stats
+}
+
+func (c *Collector) collectServerStatus(ch chan<- prometheus.Metric, ss *ServerStatus) {
+	ch <- prometheus.MustNewConstMetric(c.uptime, prometheus.CounterValue, ss.Uptime)
+	if ss.Connections != nil {
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(ss.Connections.Current), "current")
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(ss.Connections.Available), "available")
+		ch <- prometheus.MustNewConstMetric(c.connectionsCreated, prometheus.CounterValue, float64(ss.Connections.TotalCreated))
+	}
+	if ss.Opcounters != nil {
+		for opType, count := range map[string]int64{
+			"insert":  ss.Opcounters.Insert,
+			"query":   ss.Opcounters.Query,
+			"update":  ss.Opcounters.Update,
+			"delete":  ss.Opcounters.Delete,
+			"getmore": ss.Opcounters.Getmore,
+			"command": ss.Opcounters.Command,
+		} {
+			ch <- prometheus.MustNewConstMetric(c.opcounters, prometheus.CounterValue, float64(count), opType)
+		}
+	}
+	if ss.WiredTiger != nil && ss.WiredTiger.Cache != nil {
+		ch <- prometheus.MustNewConstMetric(c.wiredTigerCache, prometheus.GaugeValue, float64(ss.WiredTiger.Cache.MaxBytes), "max")
+		ch <- prometheus.MustNewConstMetric(c.wiredTigerCache, prometheus.GaugeValue, float64(ss.WiredTiger.Cache.UsedBytes), "used")
+		ch <- prometheus.MustNewConstMetric(c.wiredTigerCache, prometheus.GaugeValue, float64(ss.WiredTiger.Cache.DirtyBytes), "dirty")
+	}
+}
+
+func (c *Collector) collectReplSetStatus(ch chan<- prometheus.Metric, rs *ReplSetStatus) {
+	ch <- prometheus.MustNewConstMetric(c.replSetMyState, prometheus.GaugeValue, float64(rs.MyState), rs.Set)
+	if lag, err := rs.ReplicationLag(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.replSetLag, prometheus.GaugeValue, lag.Seconds(), rs.Set)
+	}
+}
+
+func (c *Collector) collectDbStats(ch chan<- prometheus.Metric, dbStats []*DbStats) {
+	for _, db := range dbStats {
+		ch <- prometheus.MustNewConstMetric(c.dbCollections, prometheus.GaugeValue, float64(db.Collections), db.Db)
+		ch <- prometheus.MustNewConstMetric(c.dbObjects, prometheus.GaugeValue, float64(db.Objects), db.Db)
+		ch <- prometheus.MustNewConstMetric(c.dbDataSizeBytes, prometheus.GaugeValue, db.DataSize, db.Db)
+		ch <- prometheus.MustNewConstMetric(c.dbStorageSizeBytes, prometheus.GaugeValue, db.StorageSize, db.Db)
+		ch <- prometheus.MustNewConstMetric(c.dbIndexSizeBytes, prometheus.GaugeValue, db.IndexSize, db.Db)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.ScrapeErrorsTotal.Collect(ch)
+
+	c.Lock()
+	defer c.Unlock()
+	if c.stats == nil {
+		return
+	}
+	if c.stats.ServerStatus != nil {
+		c.collectServerStatus(ch, c.stats.ServerStatus)
+	}
+	if c.stats.ReplSetStatus != nil {
+		c.collectReplSetStatus(ch, c.stats.ReplSetStatus)
+	}
+	c.collectDbStats(ch, c.stats.DbStats)
+	if c.stats.OplogStats != nil {
+		ch <- prometheus.MustNewConstMetric(c.oplogWindowSeconds, prometheus.GaugeValue, c.stats.OplogStats.Window().Seconds())
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.ScrapeErrorsTotal.Describe(ch)
+	ch <- c.uptime
+	ch <- c.connections
+	ch <- c.connectionsCreated
+	ch <- c.opcounters
+	ch <- c.wiredTigerCache
+	ch <- c.replSetMyState
+	ch <- c.replSetLag
+	ch <- c.dbCollections
+	ch <- c.dbObjects
+	ch <- c.dbDataSizeBytes
+	ch <- c.dbStorageSizeBytes
+	ch <- c.dbIndexSizeBytes
+	ch <- c.oplogWindowSeconds
+}
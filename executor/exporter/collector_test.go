@@ -0,0 +1,100 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/healthcheck"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/timvaillancourt/go-mongodb-replset/status"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var testStats = &Stats{
+	ServerStatus: &ServerStatus{
+		Uptime:      60,
+		Connections: &ConnectionStats{Current: 5, Available: 100, TotalCreated: 10},
+		Opcounters:  &OpcounterStats{Insert: 1, Query: 2, Command: 3},
+		WiredTiger: &healthcheck.WiredTigerStatus{
+			Cache: &healthcheck.WiredTigerCacheStatus{MaxBytes: 1024, UsedBytes: 512, DirtyBytes: 64},
+		},
+		Ok: 1,
+	},
+	ReplSetStatus: &ReplSetStatus{
+		ReplSetLagStatus: healthcheck.ReplSetLagStatus{
+			Members: []*healthcheck.ReplSetLagMember{
+				{Name: "localhost:27017", State: status.MemberStatePrimary, Self: true},
+			},
+			Ok: 1,
+		},
+		Set:     "rs",
+		MyState: status.MemberStatePrimary,
+	},
+	DbStats: []*DbStats{
+		{Db: "admin", Collections: 2, Objects: 3, DataSize: 1024, StorageSize: 4096, IndexSize: 2048, Ok: 1},
+	},
+	OplogStats: &OplogStats{
+		First: &healthcheck.OpTime{Ts: bson.MongoTimestamp(int64(1000) << 32)},
+		Last:  &healthcheck.OpTime{Ts: bson.MongoTimestamp(int64(1060) << 32)},
+	},
+}
+
+// gatherMetricNames returns the names of the metric families gathered from a Collector
+func gatherMetricNames(t *testing.T, c *Collector) []string {
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(c))
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	return names
+}
+
+func TestExecutorExporterOplogStatsWindow(t *testing.T) {
+	assert.Equal(t, time.Minute, testStats.OplogStats.Window())
+	assert.Zero(t, (&OplogStats{}).Window())
+}
+
+func TestExecutorExporterCollector(t *testing.T) {
+	c := NewCollector()
+	assert.Len(t, gatherMetricNames(t, c), 0, "collector with no stats should not export metrics")
+
+	c.Update(testStats)
+	names := gatherMetricNames(t, c)
+	for _, name := range []string{
+		"mongodb_uptime_seconds",
+		"mongodb_connections",
+		"mongodb_connections_created_total",
+		"mongodb_opcounters_total",
+		"mongodb_wiredtiger_cache_bytes",
+		"mongodb_replset_my_state",
+		"mongodb_replset_lag_seconds",
+		"mongodb_db_data_size_bytes",
+		"mongodb_db_index_size_bytes",
+		"mongodb_oplog_window_seconds",
+	} {
+		assert.Contains(t, names, name)
+	}
+
+	c.ScrapeErrorsTotal.WithLabelValues("serverStatus").Inc()
+	c.Update(&Stats{})
+	assert.Equal(t, []string{"mongodb_exporter_scrape_errors_total"}, gatherMetricNames(t, c))
+}
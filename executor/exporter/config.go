@@ -0,0 +1,32 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"time"
+)
+
+const (
+	DefaultListen   = ":9216"
+	DefaultPath     = "/metrics"
+	DefaultInterval = "10s"
+)
+
+type Config struct {
+	Enabled  bool
+	Listen   string
+	Path     string
+	Interval time.Duration
+}
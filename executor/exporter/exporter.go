@@ -0,0 +1,169 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+const jobName = "Prometheus Exporter"
+
+type Exporter struct {
+	sync.Mutex
+	config     *Config
+	running    bool
+	session    db.Session
+	scraper    Scraper
+	collector  *Collector
+	collectors []prometheus.Collector
+}
+
+func New(config *Config, session db.Session, scraper Scraper) *Exporter {
+	return &Exporter{
+		config:    config,
+		session:   session,
+		scraper:   scraper,
+		collector: NewCollector(),
+	}
+}
+
+func (e *Exporter) Name() string {
+	return jobName
+}
+
+func (e *Exporter) DoRun() bool {
+	return e.config.Enabled
+}
+
+// Register adds prometheus.Collectors of other jobs to be served by the Exporter
+func (e *Exporter) Register(collectors ...prometheus.Collector) {
+	e.Lock()
+	defer e.Unlock()
+	e.collectors = append(e.collectors, collectors...)
+}
+
+func (e *Exporter) setRunning(running bool) {
+	e.Lock()
+	defer e.Unlock()
+	e.running = running
+}
+
+func (e *Exporter) IsRunning() bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.running
+}
+
+// scrapeError logs and counts a failure to scrape stats from 'source'
+func (e *Exporter) scrapeError(source string, err error) {
+	log.Warnf("Failed to get %s for Prometheus Exporter: %s", source, err)
+	e.collector.ScrapeErrorsTotal.WithLabelValues(source).Inc()
+}
+
+// scrape gathers a Stats snapshot using the Scraper and updates the Collector
+func (e *Exporter) scrape() {
+	var err error
+	stats := &Stats{}
+
+	stats.ServerStatus, err = e.scraper.GetServerStatus(e.session)
+	if err != nil {
+		e.scrapeError("serverStatus", err)
+	}
+
+	stats.ReplSetStatus, err = e.scraper.GetReplSetStatus(e.session)
+	if err != nil {
+		e.scrapeError("replSetGetStatus", err)
+	} else {
+		stats.OplogStats, err = e.scraper.GetOplogStats(e.session)
+		if err != nil {
+			e.scrapeError("oplog", err)
+		}
+	}
+
+	stats.DbStats, err = e.scraper.GetDbStats(e.session)
+	if err != nil {
+		e.scrapeError("dbStats", err)
+	}
+
+	e.collector.Update(stats)
+}
+
+func (e *Exporter) handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e.collector)
+	e.Lock()
+	registry.MustRegister(e.collectors...)
+	e.Unlock()
+
+	mux := http.NewServeMux()
+	mux.Handle(e.config.Path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Run serves the metrics until 'ctx' is done. An error is returned if the listen
+// address cannot be bound or the server fails
+func (e *Exporter) Run(ctx context.Context) error {
+	if e.DoRun() == false {
+		log.Warn("Prometheus Exporter disabled! Skipping start")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"interval": e.config.Interval,
+		"listen":   e.config.Listen,
+		"path":     e.config.Path,
+	}).Info("Starting Prometheus Exporter")
+
+	listener, err := net.Listen("tcp", e.config.Listen)
+	if err != nil {
+		return fmt.Errorf("cannot listen for Prometheus Exporter: %v", err)
+	}
+
+	// scrape before serving to avoid exporting an empty snapshot
+	e.scrape()
+
+	server := &http.Server{Handler: e.handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	e.setRunning(true)
+	defer e.setRunning(false)
+	for {
+		select {
+		case <-ticker.C:
+			e.scrape()
+		case err := <-serveErr:
+			return fmt.Errorf("Prometheus Exporter server error: %v", err)
+		case <-ctx.Done():
+			log.Info("Stopping Prometheus Exporter")
+			server.Close()
+			return nil
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/executor/exporter"
+	"github.com/percona/mongodb-orchestration-tools/executor/exporter/mocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testListen = "127.0.0.1:19216"
+
+func newTestScraper() *mocks.Scraper {
+	scraper := &mocks.Scraper{}
+	scraper.On("GetServerStatus", mock.Anything).Return(&exporter.ServerStatus{
+		Uptime:      60,
+		Connections: &exporter.ConnectionStats{Current: 5, Available: 100},
+		Ok:          1,
+	}, nil)
+	scraper.On("GetReplSetStatus", mock.Anything).Return(nil, errors.New("not running with --replSet"))
+	scraper.On("GetDbStats", mock.Anything).Return([]*exporter.DbStats{{Db: "admin", Objects: 1, Ok: 1}}, nil)
+	return scraper
+}
+
+// getMetrics returns the body of the exporter metrics endpoint, retrying until the server is up
+func getMetrics(t *testing.T) string {
+	var err error
+	for tries := 0; tries < 50; tries++ {
+		var resp *http.Response
+		resp, err = http.Get("http://" + testListen + exporter.DefaultPath)
+		if err == nil {
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			return string(body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.FailNow(t, "failed to get exporter metrics", err.Error())
+	return ""
+}
+
+func TestExecutorExporterDoRun(t *testing.T) {
+	e := exporter.New(&exporter.Config{Enabled: true}, nil, newTestScraper())
+	assert.Equal(t, "Prometheus Exporter", e.Name())
+	assert.True(t, e.DoRun())
+	assert.False(t, e.IsRunning())
+
+	dontRun := exporter.New(&exporter.Config{}, nil, newTestScraper())
+	assert.False(t, dontRun.DoRun())
+}
+
+func TestExecutorExporterRun(t *testing.T) {
+	scraper := newTestScraper()
+	e := exporter.New(&exporter.Config{
+		Enabled:  true,
+		Listen:   testListen,
+		Path:     exporter.DefaultPath,
+		Interval: 50 * time.Millisecond,
+	}, nil, scraper)
+	e.Register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "test_registered",
+		Help: "A collector registered by another job",
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan bool)
+	go func() {
+		e.Run(ctx)
+		stopped <- true
+	}()
+
+	metrics := getMetrics(t)
+	assert.Contains(t, metrics, "mongodb_connections{state=\"current\"} 5")
+	assert.Contains(t, metrics, "mongodb_db_objects{db=\"admin\"} 1")
+	assert.Contains(t, metrics, "mongodb_exporter_scrape_errors_total{source=\"replSetGetStatus\"}")
+	assert.NotContains(t, metrics, "mongodb_replset_my_state")
+	assert.Contains(t, metrics, "test_registered 0")
+	scraper.AssertNotCalled(t, "GetOplogStats", mock.Anything)
+
+	cancel()
+	<-stopped
+	assert.False(t, e.IsRunning())
+}
+
+func TestExecutorExporterRunListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", testListen)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	e := exporter.New(&exporter.Config{
+		Enabled:  true,
+		Listen:   testListen,
+		Path:     exporter.DefaultPath,
+		Interval: 50 * time.Millisecond,
+	}, nil, newTestScraper())
+	assert.Error(t, e.Run(context.Background()))
+	assert.False(t, e.IsRunning())
+}
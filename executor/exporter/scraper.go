@@ -0,0 +1,164 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/healthcheck"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/timvaillancourt/go-mongodb-replset/status"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Scraper gathers the stats exported by the Exporter from a mongod
+type Scraper interface {
+	GetServerStatus(session db.Session) (*ServerStatus, error)
+	GetReplSetStatus(session db.Session) (*ReplSetStatus, error)
+	GetDbStats(session db.Session) ([]*DbStats, error)
+	GetOplogStats(session db.Session) (*OplogStats, error)
+}
+
+type ConnectionStats struct {
+	Current      int64 `bson:"current" json:"current"`
+	Available    int64 `bson:"available" json:"available"`
+	TotalCreated int64 `bson:"totalCreated" json:"totalCreated"`
+}
+
+type OpcounterStats struct {
+	Insert  int64 `bson:"insert" json:"insert"`
+	Query   int64 `bson:"query" json:"query"`
+	Update  int64 `bson:"update" json:"update"`
+	Delete  int64 `bson:"delete" json:"delete"`
+	Getmore int64 `bson:"getmore" json:"getmore"`
+	Command int64 `bson:"command" json:"command"`
+}
+
+// ServerStatus is the subset of 'serverStatus' exported by the Exporter
+type ServerStatus struct {
+	Uptime      float64                       `bson:"uptime" json:"uptime"`
+	Connections *ConnectionStats              `bson:"connections,omitempty" json:"connections,omitempty"`
+	Opcounters  *OpcounterStats               `bson:"opcounters,omitempty" json:"opcounters,omitempty"`
+	WiredTiger  *healthcheck.WiredTigerStatus `bson:"wiredTiger,omitempty" json:"wiredTiger,omitempty"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+// ReplSetStatus is the subset of 'replSetGetStatus' exported by the Exporter
+type ReplSetStatus struct {
+	healthcheck.ReplSetLagStatus `bson:",inline"`
+	Set                          string             `bson:"set" json:"set"`
+	MyState                      status.MemberState `bson:"myState" json:"myState"`
+}
+
+// DbStats is the output of 'dbStats' for a single database
+type DbStats struct {
+	Db          string  `bson:"db" json:"db"`
+	Collections int64   `bson:"collections" json:"collections"`
+	Objects     int64   `bson:"objects" json:"objects"`
+	DataSize    float64 `bson:"dataSize" json:"dataSize"`
+	StorageSize float64 `bson:"storageSize" json:"storageSize"`
+	IndexSize   float64 `bson:"indexSize" json:"indexSize"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+// OplogStats are the first and last optimes of the oplog
+type OplogStats struct {
+	First *healthcheck.OpTime
+	Last  *healthcheck.OpTime
+}
+
+// Window returns the amount of time covered by the oplog
+func (s *OplogStats) Window() time.Duration {
+	if s.First == nil || s.Last == nil {
+		return 0
+	}
+	return s.Last.Time().Sub(s.First.Time())
+}
+
+// MongoDBScraper is a Scraper that runs server commands against a mongod
+type MongoDBScraper struct{}
+
+func NewMongoDBScraper() *MongoDBScraper {
+	return &MongoDBScraper{}
+}
+
+func (s *MongoDBScraper) GetServerStatus(session db.Session) (*ServerStatus, error) {
+	resp := &ServerStatus{}
+	if err := session.Run(bson.D{{Name: "serverStatus", Value: 1}}, resp); err != nil {
+		return nil, fmt.Errorf("serverStatus returned error %v", err)
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	return resp, nil
+}
+
+func (s *MongoDBScraper) GetReplSetStatus(session db.Session) (*ReplSetStatus, error) {
+	resp := &ReplSetStatus{}
+	if err := session.Run(bson.D{{Name: "replSetGetStatus", Value: 1}}, resp); err != nil {
+		return nil, fmt.Errorf("replSetGetStatus returned error %v", err)
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	return resp, nil
+}
+
+func (s *MongoDBScraper) GetDbStats(session db.Session) ([]*DbStats, error) {
+	dbNames, err := db.ListDatabases(session)
+	if err != nil {
+		return nil, fmt.Errorf("listDatabases returned error %v", err)
+	}
+	stats := make([]*DbStats, 0)
+	for _, dbName := range dbNames {
+		resp := &DbStats{}
+		if err := session.RunOn(dbName, bson.D{{Name: "dbStats", Value: 1}}, resp); err != nil {
+			return nil, fmt.Errorf("dbStats for %s returned error %v", dbName, err)
+		}
+		if resp.Ok == 0 {
+			return nil, errors.New(resp.Errmsg)
+		}
+		stats = append(stats, resp)
+	}
+	return stats, nil
+}
+
+// findOplogEntry unmarshals the first oplog entry in natural 'order' into 'result'
+func findOplogEntry(session db.Session, order int, result interface{}) error {
+	return db.FindOne(session, "local", bson.D{
+		{Name: "find", Value: "oplog.rs"},
+		{Name: "sort", Value: bson.D{{Name: "$natural", Value: order}}},
+	}, result)
+}
+
+func (s *MongoDBScraper) GetOplogStats(session db.Session) (*OplogStats, error) {
+	stats := &OplogStats{
+		First: &healthcheck.OpTime{},
+		Last:  &healthcheck.OpTime{},
+	}
+	if err := findOplogEntry(session, 1, stats.First); err != nil {
+		return nil, fmt.Errorf("failed to get first oplog entry: %v", err)
+	}
+	if err := findOplogEntry(session, -1, stats.Last); err != nil {
+		return nil, fmt.Errorf("failed to get last oplog entry: %v", err)
+	}
+	return stats, nil
+}
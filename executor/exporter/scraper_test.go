@@ -0,0 +1,45 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorExporterMongoDBScraper(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	scraper := NewMongoDBScraper()
+
+	serverStatus, err := scraper.GetServerStatus(testSession)
+	assert.NoError(t, err, ".GetServerStatus() should not return an error")
+	assert.NotZero(t, serverStatus.Uptime)
+	assert.NotNil(t, serverStatus.Connections)
+
+	rsStatus, err := scraper.GetReplSetStatus(testSession)
+	assert.NoError(t, err, ".GetReplSetStatus() should not return an error")
+	assert.NotEmpty(t, rsStatus.Members)
+
+	dbStats, err := scraper.GetDbStats(testSession)
+	assert.NoError(t, err, ".GetDbStats() should not return an error")
+	assert.NotEmpty(t, dbStats)
+
+	oplogStats, err := scraper.GetOplogStats(testSession)
+	assert.NoError(t, err, ".GetOplogStats() should not return an error")
+	assert.NotNil(t, oplogStats.Last)
+}
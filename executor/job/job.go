@@ -15,13 +15,22 @@
 package job
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/executor/backup"
 	"github.com/percona/mongodb-orchestration-tools/executor/config"
+	"github.com/percona/mongodb-orchestration-tools/executor/exporter"
 	"github.com/percona/mongodb-orchestration-tools/executor/metrics"
-	mgostatsd "github.com/scullxbones/mgo-statsd"
+	"github.com/percona/mongodb-orchestration-tools/executor/pitr"
+	"github.com/percona/mongodb-orchestration-tools/executor/pmm"
+	"github.com/percona/mongodb-orchestration-tools/executor/profiler"
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/mgo.v2"
 )
 
 // BackgroundJob is an interface for background backgroundJobs to be executed against the Daemon
@@ -29,45 +38,171 @@ type BackgroundJob interface {
 	Name() string
 	DoRun() bool
 	IsRunning() bool
-	Run(quit *chan bool)
+	Run(ctx context.Context) error
 }
 
 type Runner struct {
-	config  *config.Config
-	jobs    []BackgroundJob
-	session *mgo.Session
-	quit    *chan bool
+	sync.Mutex
+	config     *config.Config
+	jobs       []BackgroundJob
+	supervised []*supervisedJob
+	pool       *db.Pool
+	collectors []prometheus.Collector
+	ctx        context.Context
+	cancel     context.CancelFunc
 }
 
-// New returns a new Runner for running BackgroundJob jobs
-func New(config *config.Config, session *mgo.Session, quit *chan bool) *Runner {
+// New returns a new Runner for running BackgroundJob jobs, the jobs get their sessions
+// from 'pool'
+func New(config *config.Config, pool *db.Pool) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Runner{
-		config:  config,
-		session: session,
-		quit:    quit,
-		jobs:    make([]BackgroundJob, 0),
+		config:     config,
+		pool:       pool,
+		jobs:       make([]BackgroundJob, 0),
+		supervised: make([]*supervisedJob, 0),
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 }
 
 func (r *Runner) handleDCOSMetrics() {
 	if r.config.Metrics.Enabled {
-		metricsPusher := metrics.NewStatsdPusher(
-			mgostatsd.Statsd{
-				Host: r.config.Metrics.StatsdHost,
-				Port: r.config.Metrics.StatsdPort,
-			},
-			r.config.Verbose,
-		)
-		r.add(metrics.New(r.config.Metrics, r.session.Copy(), metricsPusher))
+		metricsPusher, err := metrics.NewPusher(r.config.Metrics, r.config.Verbose)
+		if err != nil {
+			log.Errorf("Skipping DC/OS Metrics client executor, cannot create metrics pusher: %s", err)
+			return
+		}
+		session, err := r.getSession("", "")
+		if err != nil {
+			log.Errorf("Skipping DC/OS Metrics client executor, cannot get session: %s", err)
+			return
+		}
+		r.add(metrics.New(r.config.Metrics, session, metricsPusher))
 	} else {
 		log.Info("Skipping DC/OS Metrics client executor")
 	}
 }
 
-// runJob runs a single BackgroundJob
+func (r *Runner) handlePMM() {
+	if r.config.PMM != nil && r.config.PMM.Enabled {
+		r.add(pmm.New(r.config.PMM))
+	} else {
+		log.Info("Skipping PMM client executor")
+	}
+}
+
+func (r *Runner) handleBackup() {
+	if r.config.Backup != nil && r.config.Backup.Enabled {
+		schedule, err := backup.ParseSchedule(r.config.Backup.Schedule)
+		if err != nil {
+			log.Errorf("Skipping Backup executor, cannot parse schedule: %s", err)
+			return
+		}
+		storage, err := backupStorage.NewStorage(r.config.Backup.Storage)
+		if err != nil {
+			log.Errorf("Skipping Backup executor, cannot create storage: %s", err)
+			return
+		}
+		session, err := r.getSession("", "")
+		if err != nil {
+			log.Errorf("Skipping Backup executor, cannot get session: %s", err)
+			return
+		}
+		backupJob := backup.New(r.config.Backup, session, schedule, backup.NewMongodumpDumper(r.config.Backup), storage)
+		r.collectors = append(r.collectors, backupJob.Collector())
+		r.add(backupJob)
+	} else {
+		log.Info("Skipping Backup executor")
+	}
+}
+
+// getSession returns a pooled session of the executor user, or of 'username' if set for
+// jobs requiring more privileges than the executor user
+func (r *Runner) getSession(username, password string) (db.Session, error) {
+	cnf := r.config.DB
+	if username != "" {
+		cnf = cnf.WithCredentials(username, password)
+	}
+	return r.pool.Get(cnf)
+}
+
+func (r *Runner) handlePITR() {
+	if r.config.PITR != nil && r.config.PITR.Enabled && r.config.Backup != nil {
+		storage, err := backupStorage.NewStorage(r.config.Backup.Storage)
+		if err != nil {
+			log.Errorf("Skipping PITR executor, cannot create storage: %s", err)
+			return
+		}
+		session, err := r.getSession(r.config.Backup.Username, r.config.Backup.Password)
+		if err != nil {
+			log.Errorf("Skipping PITR executor, cannot login as backup user: %s", err)
+			return
+		}
+		pitrJob := pitr.New(r.config.PITR, session, storage)
+		r.collectors = append(r.collectors, pitrJob.Collector())
+		r.add(pitrJob)
+	} else {
+		log.Info("Skipping PITR executor")
+	}
+}
+
+func (r *Runner) handleProfiler() {
+	if r.config.Profiler != nil && r.config.Profiler.Enabled {
+		session, err := r.getSession(r.config.Profiler.Username, r.config.Profiler.Password)
+		if err != nil {
+			log.Errorf("Skipping Profiler executor, cannot login as profiler user: %s", err)
+			return
+		}
+		r.add(profiler.New(r.config.Profiler, session))
+	} else {
+		log.Info("Skipping Profiler executor")
+	}
+}
+
+func (r *Runner) handlePrometheusExporter() {
+	if r.config.Exporter != nil && r.config.Exporter.Enabled {
+		session, err := r.getSession("", "")
+		if err != nil {
+			log.Errorf("Skipping Prometheus Exporter executor, cannot get session: %s", err)
+			return
+		}
+		promExporter := exporter.New(r.config.Exporter, session, exporter.NewMongoDBScraper())
+		promExporter.Register(r.collectors...)
+		r.add(promExporter)
+	} else {
+		log.Info("Skipping Prometheus Exporter executor")
+	}
+}
+
+// Register adds prometheus.Collectors to be exported by the Prometheus Exporter job
+func (r *Runner) Register(collectors ...prometheus.Collector) {
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// isStopped returns true if .Stop() was called on the Runner or the context
+// passed to .Run() is done
+func (r *Runner) isStopped() bool {
+	return r.ctx.Err() != nil
+}
+
+// runJob runs a single BackgroundJob under supervision of the Runner
 func (r *Runner) runJob(backgroundJob BackgroundJob) {
+	if !backgroundJob.DoRun() {
+		log.Infof("Skipping disabled background job: %s", backgroundJob.Name())
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if r.isStopped() {
+		return
+	}
+
 	log.Infof("Starting background job: %s", backgroundJob.Name())
-	go backgroundJob.Run(r.quit)
+	sj := newSupervisedJob(backgroundJob, r.config)
+	r.supervised = append(r.supervised, sj)
+	go sj.supervise(r.ctx)
 }
 
 // add adds a BackgroundJob to the list of jobs to be ran by .Run()
@@ -76,21 +211,80 @@ func (r *Runner) add(backgroundJob BackgroundJob) {
 	r.jobs = append(r.jobs, backgroundJob)
 }
 
-// Run runs all added BackgroundJobs
-func (r *Runner) Run() {
+// Run runs all added BackgroundJobs, the jobs are stopped when 'ctx' is done
+func (r *Runner) Run(ctx context.Context) {
 	log.Info("Starting background job runner")
 
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.cancel()
+		case <-r.ctx.Done():
+		}
+	}()
+
 	log.WithFields(log.Fields{
 		"delay": r.config.DelayBackgroundJob,
 	}).Info("Delaying the start of the background job runner")
-	time.Sleep(r.config.DelayBackgroundJob)
+	select {
+	case <-time.After(r.config.DelayBackgroundJob):
+	case <-r.ctx.Done():
+		log.Info("Background job runner stopped before starting jobs")
+		return
+	}
 
 	// DC/OS Metrics
 	r.handleDCOSMetrics()
 
+	// Percona Monitoring and Management client
+	r.handlePMM()
+
+	// Backup
+	r.handleBackup()
+
+	// Point-in-time recovery oplog tailing
+	r.handlePITR()
+
+	// Database profiler
+	r.handleProfiler()
+
+	// Prometheus Exporter, after jobs with metrics
+	r.handlePrometheusExporter()
+
 	for _, backgroundJob := range r.jobs {
 		r.runJob(backgroundJob)
 	}
 
 	log.Info("Completed background job runner")
 }
+
+// Status returns the status of all started BackgroundJobs
+func (r *Runner) Status() []JobStatus {
+	r.Lock()
+	defer r.Unlock()
+	statuses := make([]JobStatus, 0, len(r.supervised))
+	for _, sj := range r.supervised {
+		statuses = append(statuses, sj.status())
+	}
+	return statuses
+}
+
+// Stop cancels the context of all BackgroundJobs and waits for them to exit, or
+// for 'ctx' to be done
+func (r *Runner) Stop(ctx context.Context) error {
+	r.Lock()
+	r.cancel()
+	supervised := r.supervised
+	r.Unlock()
+
+	log.Info("Stopping background job runner")
+	for _, sj := range supervised {
+		select {
+		case <-sj.done:
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for background job %s to stop: %v", sj.job.Name(), ctx.Err())
+		}
+	}
+	log.Info("Stopped background job runner")
+	return nil
+}
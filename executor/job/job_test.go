@@ -15,14 +15,18 @@
 package job
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/percona/mongodb-orchestration-tools/executor/config"
 	"github.com/percona/mongodb-orchestration-tools/executor/job/mocks"
 	"github.com/percona/mongodb-orchestration-tools/executor/metrics"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestExecutorJobAdd(t *testing.T) {
@@ -34,26 +38,112 @@ func TestExecutorJobAdd(t *testing.T) {
 	assert.Len(t, r.jobs, 1)
 }
 
+func TestExecutorJobRegister(t *testing.T) {
+	r := New(&config.Config{}, nil)
+	assert.Len(t, r.collectors, 0)
+	r.Register(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_registered"}))
+	assert.Len(t, r.collectors, 1)
+}
+
 func TestExecutorJobRun(t *testing.T) {
 	testutils.DoSkipTest(t)
 
-	quit := make(chan bool, 1)
 	config := &config.Config{
 		DelayBackgroundJob: time.Millisecond,
 		Metrics: &metrics.Config{
 			Enabled:  false,
+			Sinks:    []string{metrics.SinkStatsd},
 			Interval: 500 * time.Millisecond,
 		},
 	}
-	r := New(config, testDBSession, &quit)
+	dialInfo, err := testutils.GetDialInfo(testutils.MongodbPrimaryPort)
+	assert.NoError(t, err)
+	config.DB = &db.Config{DialInfo: dialInfo}
+	pool := db.NewPool(nil)
+	defer pool.Close()
+
+	r := New(config, pool)
 
 	// run with disabled jobs
-	assert.NotPanics(t, func() { r.Run() })
-	quit <- true
+	assert.NotPanics(t, func() { r.Run(context.Background()) })
+	assert.NoError(t, r.Stop(context.Background()))
 
 	// run with enabled jobs
 	config.Metrics.Enabled = true
-	r2 := New(config, testDBSession, &quit)
-	assert.NotPanics(t, func() { r2.Run() })
-	quit <- true
+	r2 := New(config, pool)
+	assert.NotPanics(t, func() { r2.Run(context.Background()) })
+	assert.Len(t, r2.Status(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, r2.Stop(ctx), ".Stop() should wait for jobs to exit")
+	assert.False(t, r2.Status()[0].Running)
+}
+
+func TestExecutorJobStop(t *testing.T) {
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r := New(&config.Config{}, nil)
+	r.runJob(mockJob)
+	waitForJobStatus(t, r, func(s JobStatus) bool { return s.Running })
+
+	assert.NoError(t, r.Stop(context.Background()))
+	status := r.Status()[0]
+	assert.False(t, status.Running)
+	assert.Zero(t, status.Restarts)
+	assert.NoError(t, status.LastError)
+
+	// jobs are not started after .Stop()
+	r.runJob(mockJob)
+	assert.Len(t, r.Status(), 1)
+}
+
+func TestExecutorJobStopTimeout(t *testing.T) {
+	block := make(chan bool)
+	defer close(block)
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+	r := New(&config.Config{}, nil)
+	r.runJob(mockJob)
+	waitForJobStatus(t, r, func(s JobStatus) bool { return s.Running })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, r.Stop(ctx), ".Stop() should return an error when jobs do not exit in time")
+}
+
+func TestExecutorJobRunContextCancel(t *testing.T) {
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r := New(&config.Config{
+		DelayBackgroundJob: time.Millisecond,
+		Metrics:            &metrics.Config{},
+	}, nil)
+	r.add(mockJob)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Run(ctx)
+	waitForJobStatus(t, r, func(s JobStatus) bool { return s.Running })
+
+	cancel()
+	waitForJobStatus(t, r, func(s JobStatus) bool { return !s.Running })
+	assert.NoError(t, r.Stop(context.Background()))
+	assert.Zero(t, r.Status()[0].Restarts)
+	mockJob.AssertNumberOfCalls(t, "Run", 1)
+}
+
+func TestExecutorJobRunDisabled(t *testing.T) {
+	mockJob := &mocks.BackgroundJob{}
+	mockJob.On("Name").Return(t.Name())
+	mockJob.On("DoRun").Return(false)
+	r := New(&config.Config{}, nil)
+	r.runJob(mockJob)
+	assert.Len(t, r.Status(), 0)
+	mockJob.AssertNotCalled(t, "Run", mock.Anything)
 }
@@ -20,27 +20,11 @@ import (
 	"testing"
 
 	"github.com/percona/mongodb-orchestration-tools/internal/logger"
-	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
-	"gopkg.in/mgo.v2"
 )
 
-var (
-	testLogBuffer = new(bytes.Buffer)
-	testDBSession *mgo.Session
-)
+var testLogBuffer = new(bytes.Buffer)
 
 func TestMain(m *testing.M) {
 	logger.SetupLogger(nil, logger.GetLogFormatter(), testLogBuffer)
-	if testutils.Enabled() {
-		var err error
-		testDBSession, err = testutils.GetSession(testutils.MongodbPrimaryPort)
-		if err != nil {
-			panic(err)
-		}
-	}
-	exit := m.Run()
-	if testDBSession != nil {
-		testDBSession.Close()
-	}
-	os.Exit(exit)
+	os.Exit(m.Run())
 }
@@ -1,6 +1,7 @@
 // Code generated by mockery v1.0.0. DO NOT EDIT.
 package mocks
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 
 // BackgroundJob is an autogenerated mock type for the BackgroundJob type
@@ -50,7 +51,16 @@ func (_m *BackgroundJob) Name() string {
 	return r0
 }
 
-// Run provides a mock function with given fields: quit
-func (_m *BackgroundJob) Run(quit *chan bool) {
-	_m.Called(quit)
+// Run provides a mock function with given fields: ctx
+func (_m *BackgroundJob) Run(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
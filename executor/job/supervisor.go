@@ -0,0 +1,186 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/executor/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// RestartPolicy is the policy for restarting a BackgroundJob that exits before the Runner is stopped
+type RestartPolicy string
+
+const (
+	// RestartNever never restarts a BackgroundJob
+	RestartNever RestartPolicy = "never"
+	// RestartOnPanic restarts a BackgroundJob after it panics
+	RestartOnPanic RestartPolicy = "on-panic"
+	// RestartOnFailure restarts a BackgroundJob after it panics or returns an error
+	RestartOnFailure RestartPolicy = "on-failure"
+	// RestartAlways restarts a BackgroundJob after it panics or returns
+	RestartAlways RestartPolicy = "always"
+)
+
+func (p RestartPolicy) String() string {
+	return string(p)
+}
+
+// RestartPolicies is a slice of the supported restart policies
+var RestartPolicies = []string{
+	RestartNever.String(),
+	RestartOnPanic.String(),
+	RestartOnFailure.String(),
+	RestartAlways.String(),
+}
+
+// ErrJobExited is the last error of a BackgroundJob that returned before the Runner was stopped
+var ErrJobExited = errors.New("job exited unexpectedly")
+
+// panicError is the last error of a BackgroundJob that panicked
+type panicError struct {
+	value interface{}
+}
+
+func (e panicError) Error() string {
+	return fmt.Sprintf("job panicked: %v", e.value)
+}
+
+// JobStatus is the status of a supervised BackgroundJob
+type JobStatus struct {
+	Name      string
+	Running   bool
+	Restarts  int
+	LastError error
+}
+
+type supervisedJob struct {
+	sync.Mutex
+	job         BackgroundJob
+	policy      RestartPolicy
+	delay       time.Duration
+	maxRestarts int
+	done        chan struct{}
+	running     bool
+	restarts    int
+	lastErr     error
+}
+
+func newSupervisedJob(job BackgroundJob, config *config.Config) *supervisedJob {
+	policy := RestartPolicy(config.JobRestartPolicy)
+	if policy == "" {
+		policy = RestartOnPanic
+	}
+	return &supervisedJob{
+		job:         job,
+		policy:      policy,
+		delay:       config.JobRestartDelay,
+		maxRestarts: config.JobMaxRestarts,
+		done:        make(chan struct{}),
+	}
+}
+
+func (sj *supervisedJob) status() JobStatus {
+	sj.Lock()
+	defer sj.Unlock()
+	return JobStatus{
+		Name:      sj.job.Name(),
+		Running:   sj.running,
+		Restarts:  sj.restarts,
+		LastError: sj.lastErr,
+	}
+}
+
+func (sj *supervisedJob) setRunning(running bool) {
+	sj.Lock()
+	defer sj.Unlock()
+	sj.running = running
+}
+
+// runOnce runs the job until it returns its error, recovering a panic as a panicError
+func (sj *supervisedJob) runOnce(ctx context.Context) (err error) {
+	sj.setRunning(true)
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError{value: r}
+		}
+		sj.setRunning(false)
+	}()
+	return sj.job.Run(ctx)
+}
+
+// shouldRestart returns true if the restart policy allows a restart after 'err'
+func (sj *supervisedJob) shouldRestart(err error) bool {
+	switch sj.policy {
+	case RestartAlways:
+	case RestartOnFailure:
+		if err == ErrJobExited {
+			return false
+		}
+	case RestartOnPanic:
+		if _, ok := err.(panicError); !ok {
+			return false
+		}
+	default:
+		return false
+	}
+	return sj.maxRestarts <= 0 || sj.restarts < sj.maxRestarts
+}
+
+// supervise runs the job, restarting it according to the restart policy until 'ctx' is done
+func (sj *supervisedJob) supervise(ctx context.Context) {
+	defer close(sj.done)
+	for {
+		err := sj.runOnce(ctx)
+		if ctx.Err() != nil {
+			log.Infof("Stopped background job: %s", sj.job.Name())
+			return
+		}
+		if err == nil {
+			err = ErrJobExited
+		}
+
+		sj.Lock()
+		sj.lastErr = err
+		restart := sj.shouldRestart(err)
+		if restart {
+			sj.restarts++
+		}
+		restarts := sj.restarts
+		sj.Unlock()
+
+		logger := log.WithFields(log.Fields{
+			"job":      sj.job.Name(),
+			"policy":   sj.policy,
+			"restarts": restarts,
+		})
+		if !restart {
+			logger.Errorf("Background job stopped: %s", err)
+			return
+		}
+		logger.Warnf("Restarting background job in %s: %s", sj.delay, err)
+
+		select {
+		case <-time.After(sj.delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
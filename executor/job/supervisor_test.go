@@ -0,0 +1,170 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/executor/config"
+	"github.com/percona/mongodb-orchestration-tools/executor/job/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestJob returns a mock BackgroundJob that calls 'run' when ran
+func newTestJob(name string, run func(ctx context.Context) error) *mocks.BackgroundJob {
+	mockJob := &mocks.BackgroundJob{}
+	mockJob.On("Name").Return(name)
+	mockJob.On("DoRun").Return(true)
+	mockJob.On("Run", mock.Anything).Return(run)
+	return mockJob
+}
+
+// waitForJobStatus waits for the status of the first job of a Runner to match 'check'
+func waitForJobStatus(t *testing.T, r *Runner, check func(JobStatus) bool) JobStatus {
+	for tries := 0; tries < 500; tries++ {
+		statuses := r.Status()
+		if len(statuses) > 0 && check(statuses[0]) {
+			return statuses[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.FailNow(t, "timed out waiting for job status")
+	return JobStatus{}
+}
+
+func TestExecutorJobSupervisorRestartOnPanic(t *testing.T) {
+	var runs int32
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			panic("test panic")
+		}
+		<-ctx.Done()
+		return nil
+	})
+	r := New(&config.Config{
+		JobRestartPolicy: RestartOnPanic.String(),
+		JobRestartDelay:  time.Millisecond,
+	}, nil)
+	r.runJob(mockJob)
+
+	status := waitForJobStatus(t, r, func(s JobStatus) bool { return s.Restarts == 1 && s.Running })
+	assert.EqualError(t, status.LastError, "job panicked: test panic")
+	assert.NoError(t, r.Stop(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestExecutorJobSupervisorRestartOnPanicExited(t *testing.T) {
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error { return nil })
+	r := New(&config.Config{
+		JobRestartPolicy: RestartOnPanic.String(),
+		JobRestartDelay:  time.Millisecond,
+	}, nil)
+	r.runJob(mockJob)
+
+	status := waitForJobStatus(t, r, func(s JobStatus) bool { return s.LastError != nil })
+	assert.Equal(t, ErrJobExited, status.LastError)
+	assert.Zero(t, status.Restarts, "a job that returns should not be restarted by the on-panic policy")
+	assert.NoError(t, r.Stop(context.Background()))
+}
+
+func TestExecutorJobSupervisorRestartOnPanicError(t *testing.T) {
+	runErr := errors.New("test error")
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error { return runErr })
+	r := New(&config.Config{
+		JobRestartPolicy: RestartOnPanic.String(),
+		JobRestartDelay:  time.Millisecond,
+	}, nil)
+	r.runJob(mockJob)
+
+	status := waitForJobStatus(t, r, func(s JobStatus) bool { return s.LastError != nil })
+	assert.Equal(t, runErr, status.LastError)
+	assert.Zero(t, status.Restarts, "a job that returns an error should not be restarted by the on-panic policy")
+	assert.NoError(t, r.Stop(context.Background()))
+}
+
+func TestExecutorJobSupervisorRestartOnFailure(t *testing.T) {
+	var runs int32
+	runErr := errors.New("test error")
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			return runErr
+		}
+		<-ctx.Done()
+		return nil
+	})
+	r := New(&config.Config{
+		JobRestartPolicy: RestartOnFailure.String(),
+		JobRestartDelay:  time.Millisecond,
+	}, nil)
+	r.runJob(mockJob)
+
+	status := waitForJobStatus(t, r, func(s JobStatus) bool { return s.Restarts == 1 && s.Running })
+	assert.Equal(t, runErr, status.LastError)
+	assert.NoError(t, r.Stop(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestExecutorJobSupervisorRestartOnFailureExited(t *testing.T) {
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error { return nil })
+	r := New(&config.Config{
+		JobRestartPolicy: RestartOnFailure.String(),
+		JobRestartDelay:  time.Millisecond,
+	}, nil)
+	r.runJob(mockJob)
+
+	status := waitForJobStatus(t, r, func(s JobStatus) bool { return s.LastError != nil })
+	assert.Equal(t, ErrJobExited, status.LastError)
+	assert.Zero(t, status.Restarts, "a job that returns without an error should not be restarted by the on-failure policy")
+	assert.NoError(t, r.Stop(context.Background()))
+}
+
+func TestExecutorJobSupervisorRestartNever(t *testing.T) {
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error { panic("test panic") })
+	r := New(&config.Config{JobRestartPolicy: RestartNever.String()}, nil)
+	r.runJob(mockJob)
+
+	status := waitForJobStatus(t, r, func(s JobStatus) bool { return s.LastError != nil })
+	assert.Zero(t, status.Restarts)
+	assert.NoError(t, r.Stop(context.Background()))
+	mockJob.AssertNumberOfCalls(t, "Run", 1)
+}
+
+func TestExecutorJobSupervisorRestartAlwaysMaxRestarts(t *testing.T) {
+	mockJob := newTestJob(t.Name(), func(ctx context.Context) error { return nil })
+	r := New(&config.Config{
+		JobRestartPolicy: RestartAlways.String(),
+		JobRestartDelay:  time.Millisecond,
+		JobMaxRestarts:   3,
+	}, nil)
+	r.runJob(mockJob)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	select {
+	case <-r.supervised[0].done:
+	case <-ctx.Done():
+		assert.FailNow(t, "timed out waiting for job to reach max restarts")
+	}
+	status := r.Status()[0]
+	assert.Equal(t, 3, status.Restarts)
+	assert.Equal(t, ErrJobExited, status.LastError)
+	mockJob.AssertNumberOfCalls(t, "Run", 4)
+	assert.NoError(t, r.Stop(ctx))
+}
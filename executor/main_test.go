@@ -29,7 +29,6 @@ var (
 	testExecutorConfig         = &config.Config{
 		NodeType: testExecutorDaemonNodeType,
 	}
-	testQuitChan = make(chan bool)
 )
 
 func TestMain(m *testing.M) {
@@ -22,12 +22,17 @@ import (
 
 const (
 	DefaultInterval = "10s"
+	DefaultSink     = SinkStatsd
 )
 
 type Config struct {
-	DB         *db.Config
-	Enabled    bool
-	StatsdHost string
-	StatsdPort int
-	Interval   time.Duration
+	DB          *db.Config
+	Enabled     bool
+	Sinks       []string
+	Tags        map[string]string
+	StatsdHost  string
+	StatsdPort  int
+	InfluxDBURL string
+	OTLPURL     string
+	Interval    time.Duration
 }
@@ -0,0 +1,92 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+
+	mgostatsd "github.com/scullxbones/mgo-statsd"
+)
+
+// dogStatsdMaxPacketSize is the maximum size of a DogStatsD UDP packet, sized to avoid fragmentation
+const dogStatsdMaxPacketSize = 1432
+
+// DogStatsdPusher is a Pusher that sends gauges with tags to a DogStatsD agent over UDP
+type DogStatsdPusher struct {
+	serverStatusGetter
+	addr string
+	tags map[string]string
+}
+
+func NewDogStatsdPusher(host string, port int, tags map[string]string) *DogStatsdPusher {
+	return &DogStatsdPusher{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		tags: tags,
+	}
+}
+
+// formatDogStatsdTags returns tags in the DogStatsD '|#key:value,...' suffix format
+func formatDogStatsdTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		pairs = append(pairs, key+":"+tags[key])
+	}
+	return "|#" + strings.Join(pairs, ",")
+}
+
+// dogStatsdPackets returns the DogStatsD gauge lines of the metrics, packed into packets
+func dogStatsdPackets(metrics []Metric, tags map[string]string) [][]byte {
+	suffix := "|g" + formatDogStatsdTags(tags)
+	packets := make([][]byte, 0)
+	packet := new(bytes.Buffer)
+	for _, metric := range metrics {
+		line := metric.Name + ":" + strconv.FormatFloat(metric.Value, 'f', -1, 64) + suffix
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > dogStatsdMaxPacketSize {
+			packets = append(packets, packet.Bytes())
+			packet = new(bytes.Buffer)
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		packets = append(packets, packet.Bytes())
+	}
+	return packets
+}
+
+func (p *DogStatsdPusher) Push(status *mgostatsd.ServerStatus) error {
+	metrics, err := flattenServerStatus(status)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("udp", p.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, packet := range dogStatsdPackets(metrics, sinkTags(p.tags, status)) {
+		if _, err := conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
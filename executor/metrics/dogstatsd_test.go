@@ -0,0 +1,78 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listenUDP returns a UDP listener on a random localhost port, used as a stand-in for UDP sinks
+func listenUDP(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		assert.FailNowf(t, "Could not listen on UDP address: %v", err.Error())
+	}
+	return conn
+}
+
+// readUDP returns the next packet received by a UDP listener
+func readUDP(t *testing.T, conn *net.UDPConn) string {
+	buf := make([]byte, 65535)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	assert.NoError(t, err)
+	return string(buf[0:n])
+}
+
+func TestExecutorMetricsDogStatsdPackets(t *testing.T) {
+	tags := map[string]string{"host": "localhost", "env": "test"}
+	packets := dogStatsdPackets([]Metric{
+		{Name: "mongodb.uptime", Value: 60},
+		{Name: "mongodb.connections.current", Value: 1.5},
+	}, tags)
+	assert.Len(t, packets, 1)
+	assert.Equal(t,
+		"mongodb.uptime:60|g|#env:test,host:localhost\nmongodb.connections.current:1.5|g|#env:test,host:localhost",
+		string(packets[0]),
+	)
+
+	metrics := make([]Metric, 100)
+	for i := range metrics {
+		metrics[i] = Metric{Name: "mongodb.wiredTiger.cache.some_long_metric_name", Value: float64(i)}
+	}
+	packets = dogStatsdPackets(metrics, tags)
+	assert.True(t, len(packets) > 1, "metrics should be split into multiple packets")
+	var lines int
+	for _, packet := range packets {
+		assert.True(t, len(packet) <= dogStatsdMaxPacketSize)
+		lines += len(strings.Split(string(packet), "\n"))
+	}
+	assert.Equal(t, len(metrics), lines)
+}
+
+func TestExecutorMetricsDogStatsdPusherPush(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+	addr := conn.LocalAddr().(*net.UDPAddr)
+
+	pusher := NewDogStatsdPusher(addr.IP.String(), addr.Port, map[string]string{"env": "test"})
+	assert.NoError(t, pusher.Push(testSinkServerStatus))
+	assert.Equal(t, "mongodb.uptime:60|g|#env:test,host:localhost:27017", readUDP(t, conn))
+}
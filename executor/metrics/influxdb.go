@@ -0,0 +1,116 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mgostatsd "github.com/scullxbones/mgo-statsd"
+)
+
+// DefaultHTTPTimeout is the timeout of pushes to HTTP-based sinks
+var DefaultHTTPTimeout = 10 * time.Second
+
+var influxDBEscaper = strings.NewReplacer(",", "\\,", " ", "\\ ", "=", "\\=")
+
+// InfluxDBPusher is a Pusher that writes InfluxDB line protocol over UDP or HTTP
+type InfluxDBPusher struct {
+	serverStatusGetter
+	url    *url.URL
+	tags   map[string]string
+	client *http.Client
+}
+
+// NewInfluxDBPusher returns an InfluxDBPusher for a 'udp://host:port' or
+// 'http(s)://host:port/write?db=name' url
+func NewInfluxDBPusher(rawURL string, tags map[string]string) (*InfluxDBPusher, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("an InfluxDB url is required for the %s metrics sink", SinkInfluxDB)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "udp", "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported InfluxDB url scheme: %s", u.Scheme)
+	}
+	return &InfluxDBPusher{
+		url:    u,
+		tags:   tags,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+	}, nil
+}
+
+// influxDBLine returns the metrics as a single InfluxDB line protocol point
+func influxDBLine(metrics []Metric, tags map[string]string, ts time.Time) []byte {
+	line := new(bytes.Buffer)
+	line.WriteString(metricPrefix)
+	for _, key := range sortedKeys(tags) {
+		line.WriteString("," + influxDBEscaper.Replace(key) + "=" + influxDBEscaper.Replace(tags[key]))
+	}
+	for i, metric := range metrics {
+		if i == 0 {
+			line.WriteByte(' ')
+		} else {
+			line.WriteByte(',')
+		}
+		field := strings.TrimPrefix(metric.Name, metricPrefix+".")
+		line.WriteString(influxDBEscaper.Replace(field) + "=" + strconv.FormatFloat(metric.Value, 'f', -1, 64))
+	}
+	line.WriteString(" " + strconv.FormatInt(ts.UnixNano(), 10) + "\n")
+	return line.Bytes()
+}
+
+func (p *InfluxDBPusher) Push(status *mgostatsd.ServerStatus) error {
+	metrics, err := flattenServerStatus(status)
+	if err != nil {
+		return err
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	line := influxDBLine(metrics, sinkTags(p.tags, status), time.Now())
+
+	if p.url.Scheme == "udp" {
+		conn, err := net.Dial("udp", p.url.Host)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.Write(line)
+		return err
+	}
+
+	resp, err := p.client.Post(p.url.String(), "text/plain; charset=utf-8", bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("InfluxDB write returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorMetricsInfluxDBLine(t *testing.T) {
+	line := influxDBLine([]Metric{
+		{Name: "mongodb.uptime", Value: 60},
+		{Name: "mongodb.connections.current", Value: 5},
+	}, map[string]string{"host": "localhost:27017", "svc name": "a,b"}, time.Unix(1, 0))
+	assert.Equal(t, "mongodb,host=localhost:27017,svc\\ name=a\\,b uptime=60,connections.current=5 1000000000\n", string(line))
+}
+
+func TestExecutorMetricsNewInfluxDBPusher(t *testing.T) {
+	_, err := NewInfluxDBPusher("", nil)
+	assert.Error(t, err, ".NewInfluxDBPusher() should fail with an empty url")
+
+	_, err = NewInfluxDBPusher("tcp://localhost:8089", nil)
+	assert.Error(t, err, ".NewInfluxDBPusher() should fail with an unsupported scheme")
+
+	pusher, err := NewInfluxDBPusher("udp://localhost:8089", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:8089", pusher.url.Host)
+}
+
+func TestExecutorMetricsInfluxDBPusherPushUDP(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	pusher, err := NewInfluxDBPusher("udp://"+conn.LocalAddr().String(), nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pusher.Push(testSinkServerStatus))
+	assert.Regexp(t, "^mongodb,host=localhost:27017 uptime=60 [0-9]+\n$", readUDP(t, conn))
+}
+
+func TestExecutorMetricsInfluxDBPusherPushHTTP(t *testing.T) {
+	lines := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/write", r.URL.Path)
+		assert.Equal(t, "mongodb", r.URL.Query().Get("db"))
+		body, _ := ioutil.ReadAll(r.Body)
+		lines <- string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	pusher, err := NewInfluxDBPusher(server.URL+"/write?db=mongodb", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pusher.Push(testSinkServerStatus))
+	assert.Regexp(t, "^mongodb,host=localhost:27017 uptime=60 [0-9]+\n$", <-lines)
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database not found", http.StatusNotFound)
+	}))
+	defer failServer.Close()
+
+	pusher, err = NewInfluxDBPusher(failServer.URL+"/write?db=missing", nil)
+	assert.NoError(t, err)
+	assert.EqualError(t, pusher.Push(testSinkServerStatus), "InfluxDB write returned status 404: database not found")
+}
@@ -20,17 +20,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/logger"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
 	mgostatsd "github.com/scullxbones/mgo-statsd"
-	"gopkg.in/mgo.v2"
 )
 
 var (
 	testMetrics     *Metrics
 	testLogBuffer   = new(bytes.Buffer)
 	testMetricsChan = make(chan *mgostatsd.ServerStatus)
-	testSession     *mgo.Session
+	testSession     db.Session
 	testInterval    = time.Duration(100) * time.Millisecond
 	testConfig      = &Config{
 		Enabled:    true,
@@ -44,11 +44,11 @@ func TestMain(m *testing.M) {
 	logger.SetupLogger(nil, logger.GetLogFormatter(), testLogBuffer)
 
 	if testutils.Enabled() {
-		var err error
-		testSession, err = testutils.GetSession(testutils.MongodbPrimaryPort)
+		mgoSession, err := testutils.GetSession(testutils.MongodbPrimaryPort)
 		if err != nil {
 			panic(err)
 		}
+		testSession = db.NewMgoSession(mgoSession)
 	}
 
 	exit := m.Run()
@@ -15,19 +15,20 @@
 package metrics
 
 import (
+	"context"
 	"sync"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	mgostatsd "github.com/scullxbones/mgo-statsd"
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/mgo.v2"
 )
 
 const jobName = "DC/OS Metrics"
 
 // Pusher is an interface for a DC/OS Metrics pusher
 type Pusher interface {
-	GetServerStatus(session *mgo.Session) (*mgostatsd.ServerStatus, error)
+	GetServerStatus(session db.Session) (*mgostatsd.ServerStatus, error)
 	Push(status *mgostatsd.ServerStatus) error
 }
 
@@ -35,11 +36,11 @@ type Metrics struct {
 	sync.Mutex
 	config  *Config
 	running bool
-	session *mgo.Session
+	session db.Session
 	pusher  Pusher
 }
 
-func New(config *Config, session *mgo.Session, pusher Pusher) *Metrics {
+func New(config *Config, session db.Session, pusher Pusher) *Metrics {
 	return &Metrics{
 		config:  config,
 		session: session,
@@ -67,10 +68,10 @@ func (m *Metrics) IsRunning() bool {
 	return m.running
 }
 
-func (m *Metrics) Run(quit *chan bool) {
+func (m *Metrics) Run(ctx context.Context) error {
 	if m.DoRun() == false {
 		log.Warn("DC/OS Metrics client executor disabled! Skipping start")
-		return
+		return nil
 	}
 
 	log.WithFields(log.Fields{
@@ -99,11 +100,11 @@ func (m *Metrics) Run(quit *chan bool) {
 			if err != nil {
 				log.Errorf("DC/OS Metrics push error: %s", err)
 			}
-		case <-*quit:
+		case <-ctx.Done():
 			log.Info("Stopping DC/OS Metrics pusher")
 			ticker.Stop()
 			m.setRunning(false)
-			break
+			return nil
 		}
 	}
 }
@@ -15,13 +15,14 @@
 package metrics
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
 	mgostatsd "github.com/scullxbones/mgo-statsd"
 	"github.com/stretchr/testify/assert"
-	"gopkg.in/mgo.v2"
 )
 
 type MockPusher struct {
@@ -34,8 +35,8 @@ func NewMockPusher(statusChan chan *mgostatsd.ServerStatus) *MockPusher {
 	}
 }
 
-func (p *MockPusher) GetServerStatus(session *mgo.Session) (*mgostatsd.ServerStatus, error) {
-	return mgostatsd.GetServerStatus(session)
+func (p *MockPusher) GetServerStatus(session db.Session) (*mgostatsd.ServerStatus, error) {
+	return getServerStatus(session)
 }
 
 func (p *MockPusher) Push(status *mgostatsd.ServerStatus) error {
@@ -72,11 +73,11 @@ func TestExecutorMetricsRun(t *testing.T) {
 	testLogBuffer.Reset()
 
 	// start the metrics.Run() in a go routine and wait for ServerStatus struct
-	stop := make(chan bool)
-	go testMetrics.Run(&stop)
+	ctx, cancel := context.WithCancel(context.Background())
+	go testMetrics.Run(ctx)
 	serverStatus := <-testMetricsChan
 	assert.NotZero(t, serverStatus.Uptime, "Uptime field in ServerStatus should be greater than zero")
-	stop <- true
+	cancel()
 
 	// wait for the .Run() goroutine to stop
 	var tries int
@@ -1,7 +1,7 @@
 // Code generated by mockery v1.0.0. DO NOT EDIT.
 package mocks
 
-import mgo "gopkg.in/mgo.v2"
+import db "github.com/percona/mongodb-orchestration-tools/internal/db"
 import mgostatsd "github.com/scullxbones/mgo-statsd"
 import mock "github.com/stretchr/testify/mock"
 
@@ -11,11 +11,11 @@ type Pusher struct {
 }
 
 // GetServerStatus provides a mock function with given fields: session
-func (_m *Pusher) GetServerStatus(session *mgo.Session) (*mgostatsd.ServerStatus, error) {
+func (_m *Pusher) GetServerStatus(session db.Session) (*mgostatsd.ServerStatus, error) {
 	ret := _m.Called(session)
 
 	var r0 *mgostatsd.ServerStatus
-	if rf, ok := ret.Get(0).(func(*mgo.Session) *mgostatsd.ServerStatus); ok {
+	if rf, ok := ret.Get(0).(func(db.Session) *mgostatsd.ServerStatus); ok {
 		r0 = rf(session)
 	} else {
 		if ret.Get(0) != nil {
@@ -24,7 +24,7 @@ func (_m *Pusher) GetServerStatus(session *mgo.Session) (*mgostatsd.ServerStatus
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*mgo.Session) error); ok {
+	if rf, ok := ret.Get(1).(func(db.Session) error); ok {
 		r1 = rf(session)
 	} else {
 		r1 = ret.Error(1)
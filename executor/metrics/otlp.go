@@ -0,0 +1,149 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mgostatsd "github.com/scullxbones/mgo-statsd"
+)
+
+const otlpScopeName = "mongodb-executor"
+
+// The OTLP types below are the subset of the OTLP/HTTP JSON encoding used to export gauges
+//
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/metrics/v1/metrics.proto
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpExportRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// OTLPPusher is a Pusher that exports gauges to an OpenTelemetry collector using OTLP/HTTP
+type OTLPPusher struct {
+	serverStatusGetter
+	url    string
+	tags   map[string]string
+	client *http.Client
+}
+
+// NewOTLPPusher returns an OTLPPusher for an OTLP/HTTP metrics url, eg: 'http://host:4318/v1/metrics'
+func NewOTLPPusher(url string, tags map[string]string) *OTLPPusher {
+	return &OTLPPusher{
+		url:    url,
+		tags:   tags,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+	}
+}
+
+// otlpRequest returns the metrics as an OTLP export request, with tags as resource attributes
+func otlpRequest(metrics []Metric, tags map[string]string, ts time.Time) *otlpExportRequest {
+	attributes := []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{StringValue: metricPrefix}}}
+	for _, key := range sortedKeys(tags) {
+		attributes = append(attributes, otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: tags[key]}})
+	}
+
+	timeUnixNano := strconv.FormatInt(ts.UnixNano(), 10)
+	otlpMetrics := make([]otlpMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		otlpMetrics = append(otlpMetrics, otlpMetric{
+			Name: metric.Name,
+			Gauge: otlpGauge{
+				DataPoints: []otlpDataPoint{{TimeUnixNano: timeUnixNano, AsDouble: metric.Value}},
+			},
+		})
+	}
+
+	return &otlpExportRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{Attributes: attributes},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: otlpScopeName},
+				Metrics: otlpMetrics,
+			}},
+		}},
+	}
+}
+
+func (p *OTLPPusher) Push(status *mgostatsd.ServerStatus) error {
+	metrics, err := flattenServerStatus(status)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(otlpRequest(metrics, sinkTags(p.tags, status), time.Now()))
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("OTLP export returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorMetricsOTLPRequest(t *testing.T) {
+	req := otlpRequest([]Metric{{Name: "mongodb.uptime", Value: 60}}, map[string]string{"host": "localhost"}, time.Unix(1, 0))
+	assert.Len(t, req.ResourceMetrics, 1)
+
+	resource := req.ResourceMetrics[0].Resource
+	assert.Equal(t, []otlpKeyValue{
+		{Key: "service.name", Value: otlpAnyValue{StringValue: "mongodb"}},
+		{Key: "host", Value: otlpAnyValue{StringValue: "localhost"}},
+	}, resource.Attributes)
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "mongodb.uptime", metrics[0].Name)
+	assert.Equal(t, "1000000000", metrics[0].Gauge.DataPoints[0].TimeUnixNano)
+	assert.Equal(t, float64(60), metrics[0].Gauge.DataPoints[0].AsDouble)
+}
+
+func TestExecutorMetricsOTLPPusherPush(t *testing.T) {
+	requests := make(chan *otlpExportRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		req := &otlpExportRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		requests <- req
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	pusher := NewOTLPPusher(server.URL+"/v1/metrics", map[string]string{"env": "test"})
+	assert.NoError(t, pusher.Push(testSinkServerStatus))
+
+	req := <-requests
+	assert.Len(t, req.ResourceMetrics, 1)
+	assert.Contains(t, req.ResourceMetrics[0].Resource.Attributes, otlpKeyValue{Key: "env", Value: otlpAnyValue{StringValue: "test"}})
+	assert.Contains(t, req.ResourceMetrics[0].Resource.Attributes, otlpKeyValue{Key: "host", Value: otlpAnyValue{StringValue: "localhost:27017"}})
+	assert.Equal(t, "mongodb.uptime", req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer failServer.Close()
+	assert.Error(t, NewOTLPPusher(failServer.URL, nil).Push(testSinkServerStatus))
+}
@@ -15,10 +15,24 @@
 package metrics
 
 import (
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	mgostatsd "github.com/scullxbones/mgo-statsd"
-	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
+// getServerStatus runs the 'serverStatus' server command on 'session'
+func getServerStatus(session db.Session) (*mgostatsd.ServerStatus, error) {
+	status := &mgostatsd.ServerStatus{}
+	err := session.Run(bson.D{
+		{Name: "serverStatus", Value: 1},
+		{Name: "recordStats", Value: 0},
+	}, status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
 // StatsdPusher is a metrics pusher to Statsd
 type StatsdPusher struct {
 	statsdConfig mgostatsd.Statsd
@@ -34,8 +48,8 @@ func NewStatsdPusher(statsdCnf mgostatsd.Statsd, verbose bool) *StatsdPusher {
 }
 
 // GetServerStatus returns a *mgostatsd.ServerStatus
-func (p *StatsdPusher) GetServerStatus(session *mgo.Session) (*mgostatsd.ServerStatus, error) {
-	return mgostatsd.GetServerStatus(session)
+func (p *StatsdPusher) GetServerStatus(session db.Session) (*mgostatsd.ServerStatus, error) {
+	return getServerStatus(session)
 }
 
 // PushStats pushes metrics to Statsd
@@ -0,0 +1,171 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	mgostatsd "github.com/scullxbones/mgo-statsd"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	SinkStatsd    = "statsd"
+	SinkDogStatsd = "dogstatsd"
+	SinkInfluxDB  = "influxdb"
+	SinkOTLP      = "otlp"
+
+	metricPrefix = "mongodb"
+)
+
+// Sinks is a slice of the supported metrics sinks
+var Sinks = []string{SinkStatsd, SinkDogStatsd, SinkInfluxDB, SinkOTLP}
+
+var invalidMetricNameChars = regexp.MustCompile("[^a-zA-Z0-9_.]+")
+
+// Metric is a single numeric value of a serverStatus
+type Metric struct {
+	Name  string
+	Value float64
+}
+
+// flattenDoc appends the numeric values of a nested document to 'metrics', with
+// names made of the document keys joined by '.'
+func flattenDoc(prefix string, doc bson.M, metrics []Metric) []Metric {
+	for key, value := range doc {
+		name := prefix + "." + invalidMetricNameChars.ReplaceAllString(key, "_")
+		switch v := value.(type) {
+		case bson.M:
+			metrics = flattenDoc(name, v, metrics)
+		case int:
+			metrics = append(metrics, Metric{Name: name, Value: float64(v)})
+		case int32:
+			metrics = append(metrics, Metric{Name: name, Value: float64(v)})
+		case int64:
+			metrics = append(metrics, Metric{Name: name, Value: float64(v)})
+		case float64:
+			metrics = append(metrics, Metric{Name: name, Value: v})
+		}
+	}
+	return metrics
+}
+
+// flattenServerStatus returns the numeric values of a serverStatus as a slice of
+// Metric, sorted by name
+func flattenServerStatus(status *mgostatsd.ServerStatus) ([]Metric, error) {
+	bytes, err := bson.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	if err := bson.Unmarshal(bytes, &doc); err != nil {
+		return nil, err
+	}
+	metrics := flattenDoc(metricPrefix, doc, make([]Metric, 0))
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics, nil
+}
+
+// sinkTags returns the configured tags of a sink, plus a 'host' tag for the serverStatus host
+func sinkTags(tags map[string]string, status *mgostatsd.ServerStatus) map[string]string {
+	out := map[string]string{"host": status.Host}
+	for key, value := range tags {
+		out[key] = value
+	}
+	return out
+}
+
+// sortedKeys returns the keys of a map of tags in sorted order
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// serverStatusGetter implements the GetServerStatus method of Pusher for the sinks
+type serverStatusGetter struct{}
+
+func (g serverStatusGetter) GetServerStatus(session db.Session) (*mgostatsd.ServerStatus, error) {
+	return getServerStatus(session)
+}
+
+// MultiPusher is a Pusher that fans out to multiple Pushers
+type MultiPusher struct {
+	serverStatusGetter
+	pushers []Pusher
+}
+
+func NewMultiPusher(pushers ...Pusher) *MultiPusher {
+	return &MultiPusher{pushers: pushers}
+}
+
+// Push pushes the serverStatus to all Pushers, returning an error if any push failed
+func (p *MultiPusher) Push(status *mgostatsd.ServerStatus) error {
+	errs := make([]string, 0)
+	for _, pusher := range p.pushers {
+		if err := pusher.Push(status); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// NewPusher returns a Pusher for the sinks in the config, fanning out with a
+// MultiPusher if more than one sink is configured
+func NewPusher(config *Config, verbose bool) (Pusher, error) {
+	pushers := make([]Pusher, 0)
+	for _, sink := range config.Sinks {
+		switch sink {
+		case SinkStatsd:
+			pushers = append(pushers, NewStatsdPusher(mgostatsd.Statsd{
+				Host: config.StatsdHost,
+				Port: config.StatsdPort,
+			}, verbose))
+		case SinkDogStatsd:
+			pushers = append(pushers, NewDogStatsdPusher(config.StatsdHost, config.StatsdPort, config.Tags))
+		case SinkInfluxDB:
+			pusher, err := NewInfluxDBPusher(config.InfluxDBURL, config.Tags)
+			if err != nil {
+				return nil, err
+			}
+			pushers = append(pushers, pusher)
+		case SinkOTLP:
+			if config.OTLPURL == "" {
+				return nil, errors.New("an OTLP url is required for the otlp metrics sink")
+			}
+			pushers = append(pushers, NewOTLPPusher(config.OTLPURL, config.Tags))
+		default:
+			return nil, fmt.Errorf("unsupported metrics sink: %s", sink)
+		}
+	}
+	switch len(pushers) {
+	case 0:
+		return nil, errors.New("no metrics sinks configured")
+	case 1:
+		return pushers[0], nil
+	}
+	return NewMultiPusher(pushers...), nil
+}
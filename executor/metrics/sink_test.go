@@ -0,0 +1,105 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/percona/mongodb-orchestration-tools/executor/metrics/mocks"
+	mgostatsd "github.com/scullxbones/mgo-statsd"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var testSinkServerStatus = &mgostatsd.ServerStatus{
+	Host:   "localhost:27017",
+	Uptime: 60,
+}
+
+func TestExecutorMetricsFlattenDoc(t *testing.T) {
+	metrics := flattenDoc(metricPrefix, bson.M{
+		"host":   "localhost",
+		"uptime": float64(60),
+		"connections": bson.M{
+			"current": 5,
+			"active":  int32(2),
+		},
+		"wiredTiger": bson.M{
+			"cache": bson.M{"bytes currently in the cache": int64(1024)},
+		},
+	}, make([]Metric, 0))
+	assert.Len(t, metrics, 4)
+	assert.Contains(t, metrics, Metric{Name: "mongodb.uptime", Value: 60})
+	assert.Contains(t, metrics, Metric{Name: "mongodb.connections.current", Value: 5})
+	assert.Contains(t, metrics, Metric{Name: "mongodb.connections.active", Value: 2})
+	assert.Contains(t, metrics, Metric{Name: "mongodb.wiredTiger.cache.bytes_currently_in_the_cache", Value: 1024})
+}
+
+func TestExecutorMetricsFlattenServerStatus(t *testing.T) {
+	metrics, err := flattenServerStatus(testSinkServerStatus)
+	assert.NoError(t, err)
+	assert.Contains(t, metrics, Metric{Name: "mongodb.uptime", Value: 60})
+	for _, metric := range metrics {
+		assert.NotEqual(t, "mongodb.host", metric.Name, "string values should not be flattened")
+	}
+}
+
+func TestExecutorMetricsSinkTags(t *testing.T) {
+	tags := sinkTags(map[string]string{"service": "mongo"}, testSinkServerStatus)
+	assert.Equal(t, map[string]string{"host": "localhost:27017", "service": "mongo"}, tags)
+	assert.Equal(t, []string{"host", "service"}, sortedKeys(tags))
+}
+
+func TestExecutorMetricsMultiPusher(t *testing.T) {
+	pusher1 := &mocks.Pusher{}
+	pusher1.On("Push", testSinkServerStatus).Return(nil)
+	pusher2 := &mocks.Pusher{}
+	pusher2.On("Push", testSinkServerStatus).Return(errors.New("test error"))
+
+	assert.NoError(t, NewMultiPusher(pusher1).Push(testSinkServerStatus))
+	assert.EqualError(t, NewMultiPusher(pusher1, pusher2).Push(testSinkServerStatus), "test error")
+	pusher1.AssertNumberOfCalls(t, "Push", 2)
+	pusher2.AssertNumberOfCalls(t, "Push", 1)
+}
+
+func TestExecutorMetricsNewPusher(t *testing.T) {
+	pusher, err := NewPusher(&Config{Sinks: []string{SinkStatsd}}, false)
+	assert.NoError(t, err)
+	assert.IsType(t, &StatsdPusher{}, pusher)
+
+	pusher, err = NewPusher(&Config{
+		Sinks:       []string{SinkDogStatsd, SinkInfluxDB, SinkOTLP},
+		StatsdHost:  "localhost",
+		StatsdPort:  8125,
+		InfluxDBURL: "udp://localhost:8089",
+		OTLPURL:     "http://localhost:4318/v1/metrics",
+	}, false)
+	assert.NoError(t, err)
+	assert.IsType(t, &MultiPusher{}, pusher)
+	assert.Len(t, pusher.(*MultiPusher).pushers, 3)
+
+	_, err = NewPusher(&Config{}, false)
+	assert.Error(t, err, ".NewPusher() should fail with no sinks")
+
+	_, err = NewPusher(&Config{Sinks: []string{SinkInfluxDB}}, false)
+	assert.Error(t, err, ".NewPusher() should fail with no influxdb url")
+
+	_, err = NewPusher(&Config{Sinks: []string{SinkOTLP}}, false)
+	assert.Error(t, err, ".NewPusher() should fail with no otlp url")
+
+	_, err = NewPusher(&Config{Sinks: []string{"graphite"}}, false)
+	assert.Error(t, err, ".NewPusher() should fail with an unsupported sink")
+}
@@ -21,14 +21,23 @@ const (
 	DefaultUser                 = "mongodb"
 	DefaultGroup                = "root"
 	DefaultWiredTigerCacheRatio = "0.5"
+	DefaultLogMaxSizeMB         = "100"
+	DefaultLogMaxBackups        = "5"
 )
 
 type Config struct {
 	ConfigDir            string
+	ConfigOverlayFile    string
 	BinDir               string
 	TmpDir               string
 	User                 string
 	Group                string
 	TotalMemoryMB        uint
 	WiredTigerCacheRatio float64
+	PreflightMode        string
+	LogFile              string
+	LogMaxSizeMB         int
+	LogMaxBackups        int
+	LogPrefix            string
+	ParseJSONLog         bool
 }
@@ -0,0 +1,132 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	MemorySourceFlag     = "flag"
+	MemorySourceCgroupV2 = "cgroup v2"
+	MemorySourceCgroupV1 = "cgroup v1"
+	MemorySourceMeminfo  = "/proc/meminfo"
+)
+
+var (
+	cgroupV2MemoryMaxFile   = "/sys/fs/cgroup/memory.max"
+	cgroupV1MemoryLimitFile = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	procMeminfoFile         = "/proc/meminfo"
+
+	ErrNoMemoryLimit = errors.New("no memory limit")
+)
+
+// readCgroupMemoryLimit returns the memory limit in bytes of a cgroup memory limit
+// file, or ErrNoMemoryLimit if the cgroup is unlimited
+func readCgroupMemoryLimit(file string) (uint64, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(bytes))
+	if value == "max" {
+		return 0, ErrNoMemoryLimit
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readMeminfoTotal returns the 'MemTotal' of a /proc/meminfo file, in bytes
+func readMeminfoTotal(file string) (uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no MemTotal in " + file)
+}
+
+// DetectTotalMemoryBytes returns the memory available to the container and its
+// source. The cgroup v2 and v1 memory limits are used if set and lower than the
+// host memory, otherwise the host memory from /proc/meminfo is used
+func DetectTotalMemoryBytes() (uint64, string, error) {
+	hostBytes, err := readMeminfoTotal(procMeminfoFile)
+	if err != nil {
+		return 0, "", err
+	}
+
+	for _, cgroup := range []struct {
+		source string
+		file   string
+	}{
+		{MemorySourceCgroupV2, cgroupV2MemoryMaxFile},
+		{MemorySourceCgroupV1, cgroupV1MemoryLimitFile},
+	} {
+		limitBytes, err := readCgroupMemoryLimit(cgroup.file)
+		if err != nil {
+			if err != ErrNoMemoryLimit && !os.IsNotExist(err) {
+				log.Warnf("Cannot read %s memory limit from %s: %s", cgroup.source, cgroup.file, err)
+			}
+			continue
+		}
+		// cgroup v1 reports a huge number when unlimited
+		if limitBytes > 0 && limitBytes < hostBytes {
+			return limitBytes, cgroup.source, nil
+		}
+	}
+	return hostBytes, MemorySourceMeminfo, nil
+}
+
+// loadTotalMemoryMB sets the total memory from the memory limits of the container
+// if it was not set by flag
+func (m *Mongod) loadTotalMemoryMB() error {
+	source := MemorySourceFlag
+	if m.config.TotalMemoryMB == 0 {
+		totalBytes, detectedSource, err := DetectTotalMemoryBytes()
+		if err != nil {
+			return err
+		}
+		m.config.TotalMemoryMB = uint(totalBytes / 1024 / 1024)
+		source = detectedSource
+	}
+
+	log.WithFields(log.Fields{
+		"total_mb": m.config.TotalMemoryMB,
+		"source":   source,
+	}).Info("Using total memory")
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setTestMemoryFiles points the memory detection at files in 'dir' with the
+// given contents, an empty content means the file does not exist
+func setTestMemoryFiles(t *testing.T, dir, cgroupV2, cgroupV1, meminfo string) func() {
+	origV2, origV1, origMeminfo := cgroupV2MemoryMaxFile, cgroupV1MemoryLimitFile, procMeminfoFile
+	cgroupV2MemoryMaxFile = filepath.Join(dir, "memory.max")
+	cgroupV1MemoryLimitFile = filepath.Join(dir, "memory.limit_in_bytes")
+	procMeminfoFile = filepath.Join(dir, "meminfo")
+	for file, content := range map[string]string{
+		cgroupV2MemoryMaxFile:   cgroupV2,
+		cgroupV1MemoryLimitFile: cgroupV1,
+		procMeminfoFile:         meminfo,
+	} {
+		os.Remove(file)
+		if content != "" {
+			assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		}
+	}
+	return func() {
+		cgroupV2MemoryMaxFile, cgroupV1MemoryLimitFile, procMeminfoFile = origV2, origV1, origMeminfo
+	}
+}
+
+const testMeminfo = "MemTotal:       16384000 kB\nMemFree:         8192000 kB\n"
+
+func TestExecutorMongoDBDetectTotalMemoryBytes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	// cgroup v2
+	defer setTestMemoryFiles(t, dir, "2147483648\n", "", testMeminfo)()
+	bytes, source, err := DetectTotalMemoryBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2147483648), bytes)
+	assert.Equal(t, MemorySourceCgroupV2, source)
+
+	// unlimited cgroup v2, limited cgroup v1
+	setTestMemoryFiles(t, dir, "max\n", "1073741824\n", testMeminfo)
+	bytes, source, err = DetectTotalMemoryBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1073741824), bytes)
+	assert.Equal(t, MemorySourceCgroupV1, source)
+
+	// unlimited cgroup v1
+	setTestMemoryFiles(t, dir, "", "9223372036854771712\n", testMeminfo)
+	bytes, source, err = DetectTotalMemoryBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(16384000*1024), bytes)
+	assert.Equal(t, MemorySourceMeminfo, source)
+
+	// no cgroups
+	setTestMemoryFiles(t, dir, "", "", testMeminfo)
+	_, source, err = DetectTotalMemoryBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, MemorySourceMeminfo, source)
+
+	// no meminfo
+	setTestMemoryFiles(t, dir, "", "", "")
+	_, _, err = DetectTotalMemoryBytes()
+	assert.Error(t, err)
+
+	setTestMemoryFiles(t, dir, "", "", "MemFree: 100 kB\n")
+	_, _, err = DetectTotalMemoryBytes()
+	assert.Error(t, err)
+}
+
+func TestExecutorMongoDBLoadTotalMemoryMB(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	defer setTestMemoryFiles(t, dir, "4294967296", "", testMeminfo)()
+
+	mongod := &Mongod{config: &Config{TotalMemoryMB: 1024}}
+	assert.NoError(t, mongod.loadTotalMemoryMB())
+	assert.Equal(t, uint(1024), mongod.config.TotalMemoryMB, "the flag should have precedence")
+
+	mongod = &Mongod{config: &Config{}}
+	assert.NoError(t, mongod.loadTotalMemoryMB())
+	assert.Equal(t, uint(4096), mongod.config.TotalMemoryMB)
+}
+
+func TestExecutorMongoDBLoadTotalMemoryMBSmallCgroup(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	defer setTestMemoryFiles(t, dir, "536870912", "", testMeminfo)()
+
+	// a cgroup limit under 1GB should use the minimum cache size
+	mongod := &Mongod{config: &Config{WiredTigerCacheRatio: 0.5}}
+	assert.NoError(t, mongod.loadTotalMemoryMB())
+	assert.Equal(t, uint(512), mongod.config.TotalMemoryMB)
+	assert.Equal(t, minWiredTigerCacheSizeGB, mongod.getWiredTigerCacheSizeGB())
+}
@@ -16,6 +16,7 @@ package mongodb
 
 import (
 	"errors"
+	"io"
 	"math"
 	"os"
 	"os/user"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/percona/mongodb-orchestration-tools/internal"
 	"github.com/percona/mongodb-orchestration-tools/internal/command"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	mongoConfig "github.com/timvaillancourt/go-mongodb-config/config"
 )
@@ -52,6 +54,9 @@ type Mongod struct {
 	commandBin string
 	command    *command.Command
 	procState  chan *os.ProcessState
+	logParser  *command.MongoDBLogParser
+	logFile    io.Closer
+	exited     chan struct{}
 }
 
 func NewMongod(config *Config, procState chan *os.ProcessState) *Mongod {
@@ -60,9 +65,55 @@ func NewMongod(config *Config, procState chan *os.ProcessState) *Mongod {
 		configFile: filepath.Join(config.ConfigDir, "mongod.conf"),
 		commandBin: filepath.Join(config.BinDir, "mongod"),
 		procState:  procState,
+		logParser:  command.NewMongoDBLogParser(),
 	}
 }
 
+// Collector returns the prometheus.Collector of the mongod log metrics
+func (m *Mongod) Collector() prometheus.Collector {
+	return m.logParser
+}
+
+// getOutputWriters returns the stdout and stderr sinks of the mongod command: the
+// executor output with an optional line prefix, the optional rotated log file and
+// the optional parser of structured JSON logs. The optional sinks are best-effort, so
+// their failures never stop the executor output
+func (m *Mongod) getOutputWriters() (io.Writer, io.Writer, error) {
+	stdout := []io.Writer{os.Stdout}
+	stderr := []io.Writer{os.Stderr}
+	if m.config.LogPrefix != "" {
+		stdout[0] = command.NewPrefixWriter(os.Stdout, m.config.LogPrefix)
+		stderr[0] = command.NewPrefixWriter(os.Stderr, m.config.LogPrefix)
+	}
+
+	if m.config.LogFile != "" {
+		log.WithFields(log.Fields{
+			"file":        m.config.LogFile,
+			"max_size_mb": m.config.LogMaxSizeMB,
+			"max_backups": m.config.LogMaxBackups,
+		}).Info("Writing mongod output to rotated log file")
+
+		logFile, err := command.NewRotatingFile(
+			m.config.LogFile,
+			int64(m.config.LogMaxSizeMB)*1024*1024,
+			m.config.LogMaxBackups,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.logFile = logFile
+		logFileWriter := command.NewBestEffortWriter("log file", logFile)
+		stdout = append(stdout, logFileWriter)
+		stderr = append(stderr, logFileWriter)
+	}
+
+	if m.config.ParseJSONLog {
+		stdout = append(stdout, command.NewBestEffortWriter("log parser", m.logParser.NewWriter()))
+		stderr = append(stderr, command.NewBestEffortWriter("log parser", m.logParser.NewWriter()))
+	}
+	return io.MultiWriter(stdout...), io.MultiWriter(stderr...), nil
+}
+
 // The WiredTiger internal cache, by default, will use the larger of either 50% of
 // (RAM - 1 GB), or 256 MB. For example, on a system with a total of 4GB of RAM the
 // WiredTiger cache will use 1.5GB of RAM (0.5 * (4 GB - 1 GB) = 1.5 GB).
@@ -70,8 +121,9 @@ func NewMongod(config *Config, procState chan *os.ProcessState) *Mongod {
 // https://docs.mongodb.com/manual/reference/configuration-options/#storage.wiredTiger.engineConfig.cacheSizeGB
 //
 func (m *Mongod) getWiredTigerCacheSizeGB() float64 {
-	limitBytes := m.config.TotalMemoryMB * 1024 * 1024
-	size := math.Floor(m.config.WiredTigerCacheRatio * float64(limitBytes-gigaByte))
+	// float64 avoids a uint underflow for limits under 1GB, such as small cgroups
+	limitBytes := float64(m.config.TotalMemoryMB) * 1024 * 1024
+	size := math.Floor(m.config.WiredTigerCacheRatio * (limitBytes - float64(gigaByte)))
 	sizeGB := size / float64(gigaByte)
 	if sizeGB < minWiredTigerCacheSizeGB {
 		sizeGB = minWiredTigerCacheSizeGB
@@ -82,8 +134,24 @@ func (m *Mongod) getWiredTigerCacheSizeGB() float64 {
 // monitorMongodCommand() waits for the mongod command to be killed or exit,
 // returning the *os.ProcessState of the completed process over the procState
 // channel
+// closeLogFile closes the rotated log file of the mongod output, if any
+func (m *Mongod) closeLogFile() {
+	if m.logFile == nil {
+		return
+	}
+	err := m.logFile.Close()
+	if err != nil {
+		log.Errorf("Error closing mongod log file: %s", err)
+	}
+	m.logFile = nil
+}
+
 func (m *Mongod) monitorMongodCommand() {
 	state, err := m.command.Wait()
+	m.Lock()
+	m.closeLogFile()
+	close(m.exited)
+	m.Unlock()
 	if err != nil {
 		log.Errorf("Error receiving mongod exit-state: %s", err)
 		return
@@ -124,6 +192,10 @@ func (m *Mongod) processMongodConfig(config *mongoConfig.Config) error {
 }
 
 func (m *Mongod) processWiredTigerConfig(config *mongoConfig.Config) error {
+	err := m.loadTotalMemoryMB()
+	if err != nil {
+		return err
+	}
 	cacheSizeGB := m.getWiredTigerCacheSizeGB()
 
 	log.WithFields(log.Fields{
@@ -203,7 +275,22 @@ func (m *Mongod) Initiate() error {
 		return err
 	}
 
-	return m.initiateFilePaths(config)
+	err = m.applyConfigOverrides(os.Environ())
+	if err != nil {
+		log.Errorf("Could not apply mongod config overrides: %v", err)
+		return err
+	}
+
+	err = m.initiateFilePaths(config)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"mode": m.config.PreflightMode,
+	}).Info("Running host preflight checks")
+	_, err = RunPreflightChecks(m.config.PreflightMode, config.Storage.DbPath)
+	return err
 }
 
 func (m *Mongod) IsStarted() bool {
@@ -243,29 +330,41 @@ func (m *Mongod) Start() error {
 		return err
 	}
 
+	m.command.Stdout, m.command.Stderr, err = m.getOutputWriters()
+	if err != nil {
+		return err
+	}
+
 	err = m.command.Start()
 	if err != nil {
+		m.closeLogFile()
 		return err
 	}
 
+	m.exited = make(chan struct{})
 	go m.monitorMongodCommand()
 	return nil
 }
 
+// Wait waits for the started mongod command to exit and its log file to be closed
 func (m *Mongod) Wait() {
 	m.Lock()
-	defer m.Unlock()
+	exited := m.exited
+	m.Unlock()
 
-	if m.command != nil && m.command.IsRunning() {
-		m.command.Wait()
+	if exited != nil {
+		<-exited
 	}
 }
 
+// Kill kills the mongod command. The log file is closed once the output of the
+// killed command is written, or right away if the command is not running
 func (m *Mongod) Kill() error {
 	m.Lock()
 	defer m.Unlock()
 
-	if m.command == nil {
+	if m.command == nil || !m.command.IsRunning() {
+		m.closeLogFile()
 		return nil
 	}
 	return m.command.Kill()
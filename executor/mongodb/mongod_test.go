@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/command"
 	"github.com/stretchr/testify/assert"
 	mdbconfig "github.com/timvaillancourt/go-mongodb-config/config"
 	"gopkg.in/mgo.v2"
@@ -90,6 +91,69 @@ func TestExecutorMongoDBProcessWiredTigerConfig(t *testing.T) {
 	assert.Equal(t, 3.5, mongodConfig.Storage.WiredTiger.EngineConfig.CacheSizeGB)
 }
 
+func TestExecutorMongoDBGetOutputWriters(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(tempDir)
+
+	testStateChan := make(chan *os.ProcessState)
+	mongod := NewMongod(&Config{
+		LogFile:       filepath.Join(tempDir, "mongod.log"),
+		LogMaxSizeMB:  1,
+		LogMaxBackups: 1,
+		LogPrefix:     "[mongod] ",
+		ParseJSONLog:  true,
+	}, testStateChan)
+	assert.NotNil(t, mongod.Collector())
+
+	stdout, stderr, err := mongod.getOutputWriters()
+	assert.NoError(t, err)
+	line := `{"t":{"$date":"2020-08-01T10:00:00.000+00:00"},"s":"E","c":"STORAGE","id":1,"ctx":"main","msg":"test"}` + "\n"
+	_, err = stdout.Write([]byte(line))
+	assert.NoError(t, err)
+	_, err = stderr.Write([]byte("stderr line\n"))
+	assert.NoError(t, err)
+
+	data, err := ioutil.ReadFile(mongod.config.LogFile)
+	assert.NoError(t, err)
+	assert.Equal(t, line+"stderr line\n", string(data), "the log file should not be prefixed")
+
+	// log file error
+	mongod.config.LogFile = filepath.Join(tempDir, "missing", "mongod.log")
+	_, _, err = mongod.getOutputWriters()
+	assert.Error(t, err)
+}
+
+func TestExecutorMongoDBMonitorClosesLogFile(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(tempDir)
+
+	testStateChan := make(chan *os.ProcessState, 1)
+	mongod := NewMongod(&Config{LogFile: filepath.Join(tempDir, "mongod.log")}, testStateChan)
+
+	var err error
+	mongod.command, err = command.New("echo", []string{"hello"}, currentUser, currentGroup)
+	assert.NoError(t, err)
+	mongod.command.Stdout, mongod.command.Stderr, err = mongod.getOutputWriters()
+	assert.NoError(t, err)
+	assert.NotNil(t, mongod.logFile)
+	assert.NoError(t, mongod.command.Start())
+	mongod.exited = make(chan struct{})
+	go mongod.monitorMongodCommand()
+
+	mongod.Wait()
+	assert.Nil(t, mongod.logFile, "the log file should be closed when mongod exits")
+	assert.False(t, mongod.IsStarted())
+	assert.True(t, (<-testStateChan).Success())
+	data, _ := ioutil.ReadFile(mongod.config.LogFile)
+	assert.Equal(t, "hello\n", string(data), "the output should be written before the log file is closed")
+
+	// a command that was never started
+	mongod.command, _ = command.New("echo", []string{"hello"}, currentUser, currentGroup)
+	mongod.getOutputWriters()
+	assert.NoError(t, mongod.Kill())
+	assert.Nil(t, mongod.logFile)
+}
+
 // Test .getWiredTigerCacheSizeGB() mimics the cache-sizing logic described in the documentation:
 // https://docs.mongodb.com/manual/reference/configuration-options/#storage.wiredTiger.engineConfig.cacheSizeGB
 func TestExecutorMongoDBGetWiredTigerCacheSizeGB(t *testing.T) {
@@ -99,6 +163,9 @@ func TestExecutorMongoDBGetWiredTigerCacheSizeGB(t *testing.T) {
 		},
 	}
 
+	mongod.config.TotalMemoryMB = 512
+	assert.Equal(t, minWiredTigerCacheSizeGB, mongod.getWiredTigerCacheSizeGB())
+
 	mongod.config.TotalMemoryMB = 1024
 	assert.Equal(t, minWiredTigerCacheSizeGB, mongod.getWiredTigerCacheSizeGB())
 
@@ -0,0 +1,303 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const configOverrideEnvPrefix = "MONGODB_"
+
+type configKeyType int
+
+const (
+	configKeyString configKeyType = iota
+	configKeyInt
+	configKeyFloat
+	configKeyBool
+	configKeyMap
+)
+
+type configKey struct {
+	keyType configKeyType
+	values  []string
+}
+
+// knownConfigKeys are the mongod config keys, by dotted path, that may be set by
+// an overlay file or by env var overrides. The keys of 'setParameter' are not
+// validated as they depend on the server version. 'net.port' and 'net.bindIp' are
+// not overridable, the executor connects to mongod with its own config
+var knownConfigKeys = map[string]configKey{
+	"net.maxIncomingConnections":                          {keyType: configKeyInt},
+	"net.wireObjectCheck":                                 {keyType: configKeyBool},
+	"net.serviceExecutor":                                 {keyType: configKeyString, values: []string{"synchronous", "adaptive"}},
+	"net.compression.compressors":                         {keyType: configKeyString},
+	"operationProfiling.mode":                             {keyType: configKeyString, values: []string{"off", "slowOp", "all"}},
+	"operationProfiling.slowOpThresholdMs":                {keyType: configKeyInt},
+	"operationProfiling.slowOpSampleRate":                 {keyType: configKeyFloat},
+	"replication.oplogSizeMB":                             {keyType: configKeyInt},
+	"replication.enableMajorityReadConcern":               {keyType: configKeyBool},
+	"storage.directoryPerDB":                              {keyType: configKeyBool},
+	"storage.syncPeriodSecs":                              {keyType: configKeyInt},
+	"storage.journal.enabled":                             {keyType: configKeyBool},
+	"storage.journal.commitIntervalMs":                    {keyType: configKeyInt},
+	"storage.wiredTiger.engineConfig.cacheSizeGB":         {keyType: configKeyFloat},
+	"storage.wiredTiger.engineConfig.journalCompressor":   {keyType: configKeyString, values: []string{"none", "snappy", "zlib", "zstd"}},
+	"storage.wiredTiger.engineConfig.directoryForIndexes": {keyType: configKeyBool},
+	"storage.wiredTiger.collectionConfig.blockCompressor": {keyType: configKeyString, values: []string{"none", "snappy", "zlib", "zstd"}},
+	"storage.wiredTiger.indexConfig.prefixCompression":    {keyType: configKeyBool},
+	"setParameter":                                        {keyType: configKeyMap},
+}
+
+// ConfigKeyEnvVar returns the name of the env var overriding the mongod config key
+// 'path', eg: 'net.maxIncomingConnections' is overridden by MONGODB_NET_MAXINCOMINGCONNECTIONS
+func ConfigKeyEnvVar(path string) string {
+	return configOverrideEnvPrefix + strings.ToUpper(strings.Replace(path, ".", "_", -1))
+}
+
+// normalizeYAML converts the map[interface{}]interface{} maps returned by the yaml
+// package to map[string]interface{}
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprintf("%v", key)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeYAML(val)
+		}
+		return v
+	}
+	return value
+}
+
+func loadConfigMap(file string) (map[string]interface{}, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	err = yaml.Unmarshal(bytes, &config)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range config {
+		config[key] = normalizeYAML(val)
+	}
+	return config, nil
+}
+
+func writeConfigMap(file string, config map[string]interface{}) error {
+	bytes, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, bytes, 0644)
+}
+
+// hasKnownChildKeys returns true if a known config key is nested under 'path'
+func hasKnownChildKeys(path string) bool {
+	for known := range knownConfigKeys {
+		if strings.HasPrefix(known, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func validateConfigValue(path string, key configKey, value interface{}) error {
+	var ok bool
+	switch key.keyType {
+	case configKeyString:
+		var str string
+		str, ok = value.(string)
+		if ok && len(key.values) > 0 {
+			for _, v := range key.values {
+				if str == v {
+					return nil
+				}
+			}
+			return fmt.Errorf("mongod config key %s must be one of: %s", path, strings.Join(key.values, ", "))
+		}
+	case configKeyInt:
+		_, ok = value.(int)
+	case configKeyFloat:
+		switch value.(type) {
+		case float64, int:
+			ok = true
+		}
+	case configKeyBool:
+		_, ok = value.(bool)
+	case configKeyMap:
+		_, ok = value.(map[string]interface{})
+	}
+	if !ok {
+		return fmt.Errorf("mongod config key %s has an invalid value: %v", path, value)
+	}
+	return nil
+}
+
+// validateConfigMap returns an error if 'config' has keys that are not known or
+// values of the wrong type
+func validateConfigMap(config map[string]interface{}, prefix string) error {
+	for name, value := range config {
+		path := prefix + name
+		if key, ok := knownConfigKeys[path]; ok {
+			err := validateConfigValue(path, key, value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		section, isMap := value.(map[string]interface{})
+		if !isMap || !hasKnownChildKeys(path) {
+			return fmt.Errorf("unknown mongod config key: %s", path)
+		}
+		err := validateConfigMap(section, path+".")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setConfigPath sets the value of the dotted 'path' in 'config', creating sections as needed
+func setConfigPath(config map[string]interface{}, path string, value interface{}) {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		section, ok := config[name].(map[string]interface{})
+		if !ok {
+			section = make(map[string]interface{})
+			config[name] = section
+		}
+		config = section
+	}
+	config[names[len(names)-1]] = value
+}
+
+// mergeConfigMaps merges 'src' into 'dst', the values of 'src' have precedence
+func mergeConfigMaps(dst, src map[string]interface{}) {
+	for name, value := range src {
+		srcSection, srcIsMap := value.(map[string]interface{})
+		dstSection, dstIsMap := dst[name].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeConfigMaps(dstSection, srcSection)
+			continue
+		}
+		dst[name] = value
+	}
+}
+
+func parseConfigEnvValue(path string, key configKey, str string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch key.keyType {
+	case configKeyInt:
+		value, err = strconv.Atoi(str)
+	case configKeyFloat:
+		value, err = strconv.ParseFloat(str, 64)
+	case configKeyBool:
+		value, err = strconv.ParseBool(str)
+	default:
+		value = str
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse env var %s: %v", ConfigKeyEnvVar(path), err)
+	}
+	return value, validateConfigValue(path, key, value)
+}
+
+// envConfigMap returns the mongod config keys overridden by env vars in 'environ'
+func envConfigMap(environ []string) (map[string]interface{}, error) {
+	env := make(map[string]string)
+	for _, keyVal := range environ {
+		pair := strings.SplitN(keyVal, "=", 2)
+		if len(pair) == 2 && strings.HasPrefix(pair[0], configOverrideEnvPrefix) {
+			env[pair[0]] = pair[1]
+		}
+	}
+
+	paths := make([]string, 0, len(knownConfigKeys))
+	for path := range knownConfigKeys {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	config := make(map[string]interface{})
+	for _, path := range paths {
+		key := knownConfigKeys[path]
+		str, ok := env[ConfigKeyEnvVar(path)]
+		if !ok || key.keyType == configKeyMap {
+			continue
+		}
+		value, err := parseConfigEnvValue(path, key, str)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"key":   path,
+			"value": value,
+		}).Info("Overriding mongod config key from env var")
+		setConfigPath(config, path, value)
+	}
+	return config, nil
+}
+
+// applyConfigOverrides layers the overlay file, if set, and the env var overrides on
+// top of the mongod config file. Env var overrides have precedence over the overlay
+func (m *Mongod) applyConfigOverrides(environ []string) error {
+	overrides := make(map[string]interface{})
+	if m.config.ConfigOverlayFile != "" {
+		log.WithFields(log.Fields{
+			"overlay": m.config.ConfigOverlayFile,
+		}).Info("Loading mongodb config overlay file")
+
+		overlay, err := loadConfigMap(m.config.ConfigOverlayFile)
+		if err != nil {
+			return err
+		}
+		err = validateConfigMap(overlay, "")
+		if err != nil {
+			return fmt.Errorf("invalid config overlay file %s: %v", m.config.ConfigOverlayFile, err)
+		}
+		mergeConfigMaps(overrides, overlay)
+	}
+
+	envOverrides, err := envConfigMap(environ)
+	if err != nil {
+		return err
+	}
+	mergeConfigMaps(overrides, envOverrides)
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	config, err := loadConfigMap(m.configFile)
+	if err != nil {
+		return err
+	}
+	mergeConfigMaps(config, overrides)
+	return writeConfigMap(m.configFile, config)
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorMongoDBConfigKeyEnvVar(t *testing.T) {
+	assert.Equal(t, "MONGODB_NET_MAXINCOMINGCONNECTIONS", ConfigKeyEnvVar("net.maxIncomingConnections"))
+	assert.Equal(t, "MONGODB_REPLICATION_OPLOGSIZEMB", ConfigKeyEnvVar("replication.oplogSizeMB"))
+}
+
+func TestExecutorMongoDBValidateConfigMap(t *testing.T) {
+	overlay, err := loadConfigMap("testdata/overlay.yaml")
+	assert.NoError(t, err)
+	assert.NoError(t, validateConfigMap(overlay, ""))
+
+	assert.EqualError(t, validateConfigMap(map[string]interface{}{
+		"net": map[string]interface{}{"maxConnections": 100},
+	}, ""), "unknown mongod config key: net.maxConnections")
+	assert.EqualError(t, validateConfigMap(map[string]interface{}{
+		"security": map[string]interface{}{"keyFile": "/tmp/key"},
+	}, ""), "unknown mongod config key: security")
+	assert.EqualError(t, validateConfigMap(map[string]interface{}{
+		"net": map[string]interface{}{"port": 27018},
+	}, ""), "unknown mongod config key: net.port")
+	assert.EqualError(t, validateConfigMap(map[string]interface{}{
+		"net": map[string]interface{}{"bindIp": "0.0.0.0"},
+	}, ""), "unknown mongod config key: net.bindIp")
+	assert.EqualError(t, validateConfigMap(map[string]interface{}{
+		"net": map[string]interface{}{"maxIncomingConnections": "many"},
+	}, ""), "mongod config key net.maxIncomingConnections has an invalid value: many")
+	assert.EqualError(t, validateConfigMap(map[string]interface{}{
+		"operationProfiling": map[string]interface{}{"mode": "sometimes"},
+	}, ""), "mongod config key operationProfiling.mode must be one of: off, slowOp, all")
+	assert.Error(t, validateConfigMap(map[string]interface{}{"setParameter": 1}, ""))
+	assert.NoError(t, validateConfigMap(map[string]interface{}{
+		"storage": map[string]interface{}{
+			"wiredTiger": map[string]interface{}{
+				"engineConfig": map[string]interface{}{"cacheSizeGB": 2},
+			},
+		},
+	}, ""), "an int is a valid float value")
+}
+
+func TestExecutorMongoDBMergeConfigMaps(t *testing.T) {
+	config := map[string]interface{}{
+		"net": map[string]interface{}{
+			"port":   27017,
+			"bindIp": "127.0.0.1",
+		},
+	}
+	mergeConfigMaps(config, map[string]interface{}{
+		"net": map[string]interface{}{
+			"port":                   27018,
+			"maxIncomingConnections": 100,
+		},
+		"replication": map[string]interface{}{
+			"oplogSizeMB": 1024,
+		},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"net": map[string]interface{}{
+			"port":                   27018,
+			"bindIp":                 "127.0.0.1",
+			"maxIncomingConnections": 100,
+		},
+		"replication": map[string]interface{}{
+			"oplogSizeMB": 1024,
+		},
+	}, config)
+}
+
+func TestExecutorMongoDBEnvConfigMap(t *testing.T) {
+	config, err := envConfigMap([]string{
+		"MONGODB_NET_MAXINCOMINGCONNECTIONS=500",
+		"MONGODB_OPERATIONPROFILING_SLOWOPSAMPLERATE=0.5",
+		"MONGODB_STORAGE_JOURNAL_ENABLED=false",
+		"MONGODB_PORT=27017",
+		"MONGODB_SETPARAMETER=ignored",
+		"PATH=/usr/bin",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"net": map[string]interface{}{
+			"maxIncomingConnections": 500,
+		},
+		"operationProfiling": map[string]interface{}{
+			"slowOpSampleRate": 0.5,
+		},
+		"storage": map[string]interface{}{
+			"journal": map[string]interface{}{
+				"enabled": false,
+			},
+		},
+	}, config)
+
+	_, err = envConfigMap([]string{"MONGODB_NET_MAXINCOMINGCONNECTIONS=many"})
+	assert.Error(t, err)
+	_, err = envConfigMap([]string{"MONGODB_OPERATIONPROFILING_MODE=sometimes"})
+	assert.Error(t, err)
+}
+
+func TestExecutorMongoDBApplyConfigOverrides(t *testing.T) {
+	tempDir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(tempDir)
+	src, err := ioutil.ReadFile("testdata/mongod.conf")
+	assert.NoError(t, err)
+	configFile := filepath.Join(tempDir, "mongod.conf")
+	assert.NoError(t, ioutil.WriteFile(configFile, src, 0644))
+
+	testStateChan := make(chan *os.ProcessState)
+	mongod := NewMongod(&Config{
+		ConfigDir:         tempDir,
+		ConfigOverlayFile: "testdata/overlay.yaml",
+	}, testStateChan)
+	assert.NoError(t, mongod.applyConfigOverrides([]string{
+		"MONGODB_NET_MAXINCOMINGCONNECTIONS=500",
+	}))
+
+	config, err := loadConfigMap(configFile)
+	assert.NoError(t, err)
+	net := config["net"].(map[string]interface{})
+	assert.Equal(t, 500, net["maxIncomingConnections"], "env vars should have precedence over the overlay")
+	assert.Equal(t, 27017, net["port"], "the base config should be kept")
+	assert.Equal(t, 10240, config["replication"].(map[string]interface{})["oplogSizeMB"])
+	assert.Equal(t, 600000, config["setParameter"].(map[string]interface{})["cursorTimeoutMillis"])
+
+	typedConfig, err := mongod.loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/lib/mongo", typedConfig.Storage.DbPath)
+
+	// invalid overlay
+	invalidOverlay := filepath.Join(tempDir, "invalid.yaml")
+	assert.NoError(t, ioutil.WriteFile(invalidOverlay, []byte("net:\n  maxConnections: 100\n"), 0644))
+	mongod.config.ConfigOverlayFile = invalidOverlay
+	assert.Error(t, mongod.applyConfigOverrides([]string{}))
+}
@@ -0,0 +1,298 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	PreflightModeWarn    = "warn"
+	PreflightModeFix     = "fix"
+	PreflightModeStrict  = "strict"
+	DefaultPreflightMode = PreflightModeWarn
+
+	PreflightStatusOK      = "ok"
+	PreflightStatusFixed   = "fixed"
+	PreflightStatusWarning = "warning"
+	PreflightStatusSkipped = "skipped"
+
+	minRlimitNofile     = 64000
+	minRlimitNproc      = 64000
+	maxReadaheadKB      = 16
+	thpDisabledValue    = "never"
+	zoneReclaimDisabled = "0"
+)
+
+// PreflightModes is a slice of the supported preflight check modes
+var PreflightModes = []string{
+	PreflightModeWarn,
+	PreflightModeFix,
+	PreflightModeStrict,
+}
+
+var (
+	thpEnabledFile  = "/sys/kernel/mm/transparent_hugepage/enabled"
+	thpDefragFile   = "/sys/kernel/mm/transparent_hugepage/defrag"
+	sysDevBlockDir  = "/sys/dev/block"
+	numaNodeDir     = "/sys/devices/system/node"
+	zoneReclaimFile = "/proc/sys/vm/zone_reclaim_mode"
+
+	thpSelectedRegexp = regexp.MustCompile(`\[(\w+)\]`)
+	numaNodeRegexp    = regexp.MustCompile(`^node\d+$`)
+
+	ErrPreflightFailed = errors.New("preflight checks failed in strict mode")
+)
+
+// PreflightResult is the result of a host tuning check
+type PreflightResult struct {
+	Check    string
+	Status   string
+	Value    string
+	Expected string
+	Message  string
+}
+
+func (r *PreflightResult) log() {
+	entry := log.WithFields(log.Fields{
+		"check":    r.Check,
+		"status":   r.Status,
+		"value":    r.Value,
+		"expected": r.Expected,
+	})
+	switch r.Status {
+	case PreflightStatusWarning:
+		entry.Warn(r.Message)
+	default:
+		entry.Info(r.Message)
+	}
+}
+
+func skippedResult(check string, err error) *PreflightResult {
+	return &PreflightResult{
+		Check:   check,
+		Status:  PreflightStatusSkipped,
+		Message: fmt.Sprintf("Cannot run preflight check: %s", err),
+	}
+}
+
+// checkTransparentHugepage checks the selected transparent hugepage setting of a
+// sysfs file is 'never'
+func checkTransparentHugepage(check, file string) *PreflightResult {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return skippedResult(check, err)
+	}
+	match := thpSelectedRegexp.FindStringSubmatch(string(bytes))
+	if match == nil {
+		return skippedResult(check, fmt.Errorf("unexpected content of %s", file))
+	}
+
+	result := &PreflightResult{
+		Check:    check,
+		Status:   PreflightStatusOK,
+		Value:    match[1],
+		Expected: thpDisabledValue,
+		Message:  "Transparent hugepages are disabled",
+	}
+	if result.Value != thpDisabledValue {
+		result.Status = PreflightStatusWarning
+		result.Message = "Transparent hugepages are enabled, this degrades MongoDB performance"
+	}
+	return result
+}
+
+// checkRlimit checks the soft limit of rlimit 'resource' is at least 'min'. If 'fix'
+// is true the limit is raised, up to the hard limit unless the process may raise it
+func checkRlimit(check string, resource int, min uint64, fix bool) *PreflightResult {
+	limit := &unix.Rlimit{}
+	err := unix.Getrlimit(resource, limit)
+	if err != nil {
+		return skippedResult(check, err)
+	}
+
+	result := &PreflightResult{
+		Check:    check,
+		Status:   PreflightStatusOK,
+		Value:    strconv.FormatUint(limit.Cur, 10),
+		Expected: ">= " + strconv.FormatUint(min, 10),
+		Message:  "Resource limit is sufficient",
+	}
+	if limit.Cur >= min {
+		return result
+	}
+
+	result.Status = PreflightStatusWarning
+	result.Message = "Resource limit is lower than recommended for MongoDB"
+	if !fix {
+		return result
+	}
+
+	raised := &unix.Rlimit{Cur: min, Max: limit.Max}
+	if raised.Max < min {
+		raised.Max = min
+	}
+	err = unix.Setrlimit(resource, raised)
+	if err != nil && limit.Max > limit.Cur {
+		// cannot raise the hard limit, raise the soft limit to the hard limit
+		raised = &unix.Rlimit{Cur: limit.Max, Max: limit.Max}
+		err = unix.Setrlimit(resource, raised)
+	}
+	if err != nil {
+		result.Message = fmt.Sprintf("Resource limit is lower than recommended for MongoDB and cannot be raised: %s", err)
+		return result
+	}
+
+	result.Value = strconv.FormatUint(raised.Cur, 10)
+	if raised.Cur >= min {
+		result.Status = PreflightStatusFixed
+		result.Message = "Raised resource limit"
+	} else {
+		result.Message = "Raised resource limit to the hard limit, which is lower than recommended for MongoDB"
+	}
+	return result
+}
+
+// getBlockDeviceQueueDir returns the sysfs queue dir of the block device of 'path',
+// partitions use the queue of their parent device
+func getBlockDeviceQueueDir(path string) (string, error) {
+	stat := &syscall.Stat_t{}
+	err := syscall.Stat(path, stat)
+	if err != nil {
+		return "", err
+	}
+	devDir, err := filepath.EvalSymlinks(filepath.Join(
+		sysDevBlockDir,
+		fmt.Sprintf("%d:%d", unix.Major(uint64(stat.Dev)), unix.Minor(uint64(stat.Dev))),
+	))
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range []string{devDir, filepath.Dir(devDir)} {
+		queueDir := filepath.Join(dir, "queue")
+		if _, err := os.Stat(queueDir); err == nil {
+			return queueDir, nil
+		}
+	}
+	return "", fmt.Errorf("no block device queue for %s", path)
+}
+
+// checkReadahead checks the readahead of the block device of 'dbPath'
+func checkReadahead(dbPath string) *PreflightResult {
+	check := "readahead"
+	queueDir, err := getBlockDeviceQueueDir(dbPath)
+	if err != nil {
+		return skippedResult(check, err)
+	}
+	bytes, err := ioutil.ReadFile(filepath.Join(queueDir, "read_ahead_kb"))
+	if err != nil {
+		return skippedResult(check, err)
+	}
+	readaheadKB, err := strconv.Atoi(strings.TrimSpace(string(bytes)))
+	if err != nil {
+		return skippedResult(check, err)
+	}
+
+	result := &PreflightResult{
+		Check:    check,
+		Status:   PreflightStatusOK,
+		Value:    strconv.Itoa(readaheadKB) + "kb",
+		Expected: "<= " + strconv.Itoa(maxReadaheadKB) + "kb",
+		Message:  "Block device readahead of the dbPath is sufficient",
+	}
+	if readaheadKB > maxReadaheadKB {
+		result.Status = PreflightStatusWarning
+		result.Message = "Block device readahead of the dbPath is higher than recommended for MongoDB"
+	}
+	return result
+}
+
+// checkNUMA checks zone reclaim is disabled on hosts with more than one NUMA node
+func checkNUMA() *PreflightResult {
+	check := "numa"
+	files, err := ioutil.ReadDir(numaNodeDir)
+	if err != nil {
+		return skippedResult(check, err)
+	}
+	nodes := 0
+	for _, file := range files {
+		if numaNodeRegexp.MatchString(file.Name()) {
+			nodes++
+		}
+	}
+
+	result := &PreflightResult{
+		Check:    check,
+		Status:   PreflightStatusOK,
+		Value:    strconv.Itoa(nodes) + " node(s)",
+		Expected: "1 node or zone_reclaim_mode " + zoneReclaimDisabled,
+		Message:  "NUMA is not used",
+	}
+	if nodes <= 1 {
+		return result
+	}
+
+	bytes, err := ioutil.ReadFile(zoneReclaimFile)
+	if err != nil {
+		return skippedResult(check, err)
+	}
+	zoneReclaim := strings.TrimSpace(string(bytes))
+	result.Value = result.Value + ", zone_reclaim_mode " + zoneReclaim
+	if zoneReclaim != zoneReclaimDisabled {
+		result.Status = PreflightStatusWarning
+		result.Message = "NUMA zone reclaim is enabled, this degrades MongoDB performance"
+	} else {
+		result.Message = "NUMA zone reclaim is disabled, consider running mongod with 'numactl --interleave=all'"
+	}
+	return result
+}
+
+// RunPreflightChecks runs the host tuning checks for a mongod using 'dbPath'. In
+// 'fix' and 'strict' mode the resource limits are raised, inherited by the mongod
+// process. In 'strict' mode an error is returned if any check has a warning
+func RunPreflightChecks(mode, dbPath string) ([]*PreflightResult, error) {
+	fix := mode == PreflightModeFix || mode == PreflightModeStrict
+	results := []*PreflightResult{
+		checkTransparentHugepage("transparent_hugepage_enabled", thpEnabledFile),
+		checkTransparentHugepage("transparent_hugepage_defrag", thpDefragFile),
+		checkRlimit("rlimit_nofile", unix.RLIMIT_NOFILE, minRlimitNofile, fix),
+		checkRlimit("rlimit_nproc", unix.RLIMIT_NPROC, minRlimitNproc, fix),
+		checkReadahead(dbPath),
+		checkNUMA(),
+	}
+
+	warnings := 0
+	for _, result := range results {
+		result.log()
+		if result.Status == PreflightStatusWarning {
+			warnings++
+		}
+	}
+	if warnings > 0 && mode == PreflightModeStrict {
+		return results, ErrPreflightFailed
+	}
+	return results, nil
+}
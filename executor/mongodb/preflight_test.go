@@ -0,0 +1,111 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestExecutorMongoDBCheckTransparentHugepage(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "enabled")
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("always madvise [never]\n"), 0644))
+	result := checkTransparentHugepage("thp", file)
+	assert.Equal(t, PreflightStatusOK, result.Status)
+	assert.Equal(t, "never", result.Value)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("[always] madvise never\n"), 0644))
+	result = checkTransparentHugepage("thp", file)
+	assert.Equal(t, PreflightStatusWarning, result.Status)
+	assert.Equal(t, "always", result.Value)
+
+	assert.Equal(t, PreflightStatusSkipped, checkTransparentHugepage("thp", filepath.Join(dir, "missing")).Status)
+}
+
+func TestExecutorMongoDBCheckRlimit(t *testing.T) {
+	limit := &unix.Rlimit{}
+	assert.NoError(t, unix.Getrlimit(unix.RLIMIT_NOFILE, limit))
+	defer unix.Setrlimit(unix.RLIMIT_NOFILE, limit)
+	if limit.Cur < 2 {
+		t.Skip("Skipping test, the nofile soft limit is too low")
+	}
+
+	result := checkRlimit("nofile", unix.RLIMIT_NOFILE, 1, false)
+	assert.Equal(t, PreflightStatusOK, result.Status)
+
+	// lower the soft limit then fix it
+	assert.NoError(t, unix.Setrlimit(unix.RLIMIT_NOFILE, &unix.Rlimit{Cur: limit.Cur - 1, Max: limit.Max}))
+	result = checkRlimit("nofile", unix.RLIMIT_NOFILE, limit.Cur, false)
+	assert.Equal(t, PreflightStatusWarning, result.Status)
+
+	result = checkRlimit("nofile", unix.RLIMIT_NOFILE, limit.Cur, true)
+	assert.Equal(t, PreflightStatusFixed, result.Status)
+	raised := &unix.Rlimit{}
+	assert.NoError(t, unix.Getrlimit(unix.RLIMIT_NOFILE, raised))
+	assert.Equal(t, limit.Cur, raised.Cur)
+}
+
+func TestExecutorMongoDBCheckNUMA(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	origNodeDir, origZoneReclaimFile := numaNodeDir, zoneReclaimFile
+	defer func() {
+		numaNodeDir, zoneReclaimFile = origNodeDir, origZoneReclaimFile
+	}()
+	numaNodeDir = filepath.Join(dir, "node")
+	zoneReclaimFile = filepath.Join(dir, "zone_reclaim_mode")
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(numaNodeDir, "node0"), 0755))
+	assert.Equal(t, PreflightStatusOK, checkNUMA().Status)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(numaNodeDir, "node1"), 0755))
+	assert.NoError(t, ioutil.WriteFile(zoneReclaimFile, []byte("1\n"), 0644))
+	result := checkNUMA()
+	assert.Equal(t, PreflightStatusWarning, result.Status)
+	assert.Equal(t, "2 node(s), zone_reclaim_mode 1", result.Value)
+
+	assert.NoError(t, ioutil.WriteFile(zoneReclaimFile, []byte("0\n"), 0644))
+	assert.Equal(t, PreflightStatusOK, checkNUMA().Status)
+}
+
+func TestExecutorMongoDBRunPreflightChecks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	origEnabledFile, origDefragFile := thpEnabledFile, thpDefragFile
+	defer func() {
+		thpEnabledFile, thpDefragFile = origEnabledFile, origDefragFile
+	}()
+	thpEnabledFile = filepath.Join(dir, "enabled")
+	thpDefragFile = filepath.Join(dir, "defrag")
+	assert.NoError(t, ioutil.WriteFile(thpEnabledFile, []byte("[always] madvise never\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(thpDefragFile, []byte("always madvise [never]\n"), 0644))
+
+	results, err := RunPreflightChecks(PreflightModeWarn, dir)
+	assert.NoError(t, err, "warn mode should not fail")
+	assert.Len(t, results, 6)
+	assert.Equal(t, "transparent_hugepage_enabled", results[0].Check)
+	assert.Equal(t, PreflightStatusWarning, results[0].Status)
+
+	_, err = RunPreflightChecks(PreflightModeStrict, dir)
+	assert.Equal(t, ErrPreflightFailed, err)
+}
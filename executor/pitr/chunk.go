@@ -0,0 +1,77 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pitr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"time"
+
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// chunkWriter compresses oplog entries in memory until they are stored as a Chunk
+type chunkWriter struct {
+	rsName  string
+	buffer  *bytes.Buffer
+	gzip    *gzip.Writer
+	start   bson.MongoTimestamp
+	end     bson.MongoTimestamp
+	size    int
+	created time.Time
+}
+
+func newChunkWriter(rsName string) *chunkWriter {
+	buffer := new(bytes.Buffer)
+	return &chunkWriter{
+		rsName: rsName,
+		buffer: buffer,
+		gzip:   gzip.NewWriter(buffer),
+	}
+}
+
+// write adds a raw BSON oplog entry with timestamp 'ts' to the chunk
+func (c *chunkWriter) write(doc []byte, ts bson.MongoTimestamp) error {
+	if c.isEmpty() {
+		c.start = ts
+		c.created = time.Now()
+	}
+	c.end = ts
+	n, err := c.gzip.Write(doc)
+	c.size += n
+	return err
+}
+
+func (c *chunkWriter) isEmpty() bool {
+	return c.start == 0
+}
+
+// isDue returns true if the chunk is not empty and its first entry was written
+// 'interval' or longer ago
+func (c *chunkWriter) isDue(interval time.Duration) bool {
+	return !c.isEmpty() && time.Since(c.created) >= interval
+}
+
+func (c *chunkWriter) name() string {
+	return backupStorage.ChunkName(c.rsName, c.start, c.end)
+}
+
+// close flushes the compressed chunk and returns a reader of it
+func (c *chunkWriter) close() (io.Reader, error) {
+	err := c.gzip.Close()
+	return c.buffer, err
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pitr
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorPITRChunkWriter(t *testing.T) {
+	chunk := newChunkWriter("rs")
+	assert.True(t, chunk.isEmpty())
+	assert.False(t, chunk.isDue(0))
+
+	assert.NoError(t, chunk.write([]byte("entry1"), backupStorage.NewTimestamp(100, 1)))
+	assert.NoError(t, chunk.write([]byte("entry2"), backupStorage.NewTimestamp(100, 2)))
+	assert.False(t, chunk.isEmpty())
+	assert.True(t, chunk.isDue(0))
+	assert.False(t, chunk.isDue(time.Minute))
+	assert.Equal(t, 12, chunk.size)
+	assert.Equal(t, backupStorage.ChunkName("rs", backupStorage.NewTimestamp(100, 1), backupStorage.NewTimestamp(100, 2)), chunk.name())
+
+	reader, err := chunk.close()
+	assert.NoError(t, err)
+	gz, err := gzip.NewReader(reader)
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(gz)
+	assert.Equal(t, "entry1entry2", string(data))
+	_, err = backupStorage.ParseChunkName(chunk.name())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pitr
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "mongodb"
+	subsystem = "pitr"
+)
+
+type Collector struct {
+	ChunksTotal        *prometheus.CounterVec
+	GapsTotal          prometheus.Counter
+	LastTimestamp      prometheus.Gauge
+	LastChunkSizeBytes prometheus.Gauge
+	StoredChunks       prometheus.Gauge
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		ChunksTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "chunks_total",
+			Help:      "The total number of stored oplog chunks by status",
+		}, []string{"status"}),
+		GapsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "gaps_total",
+			Help:      "The total number of oplog gaps caused by the oplog rolling over before it was captured",
+		}),
+		LastTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "last_timestamp_seconds",
+			Help:      "The unix time of the last stored oplog entry",
+		}),
+		LastChunkSizeBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "last_chunk_size_bytes",
+			Help:      "The compressed size of the last stored oplog chunk, in bytes",
+		}),
+		StoredChunks: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stored_chunks",
+			Help:      "The number of oplog chunks of the replset in the storage after the retention",
+		}),
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.ChunksTotal.Collect(ch)
+	c.GapsTotal.Collect(ch)
+	c.LastTimestamp.Collect(ch)
+	c.LastChunkSizeBytes.Collect(ch)
+	c.StoredChunks.Collect(ch)
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.ChunksTotal.Describe(ch)
+	c.GapsTotal.Describe(ch)
+	c.LastTimestamp.Describe(ch)
+	c.LastChunkSizeBytes.Describe(ch)
+	c.StoredChunks.Describe(ch)
+}
@@ -0,0 +1,321 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pitr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/executor/backup"
+	backupStorage "github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	jobName         = "PITR"
+	oplogDB         = "local"
+	oplogCollection = "oplog.rs"
+	tailTimeout     = time.Second
+	flushTimeout    = 30 * time.Second
+)
+
+var ErrCursorClosed = errors.New("oplog cursor was closed")
+
+type oplogEntry struct {
+	Timestamp bson.MongoTimestamp `bson:"ts"`
+}
+
+// isGap returns true if the oldest oplog entry is newer than the last captured
+// entry, meaning the oplog rolled over before the entries between them were captured
+func isGap(last, oldest bson.MongoTimestamp) bool {
+	return oldest > last
+}
+
+// PITR is a BackgroundJob that continuously stores the oplog of the dedicated backup
+// member in compressed chunks for point-in-time recovery
+type PITR struct {
+	sync.Mutex
+	config    *Config
+	session   db.Session
+	storage   backupStorage.Storage
+	collector *Collector
+	running   bool
+}
+
+func New(config *Config, session db.Session, storage backupStorage.Storage) *PITR {
+	return &PITR{
+		config:    config,
+		session:   session,
+		storage:   storage,
+		collector: NewCollector(),
+	}
+}
+
+func (p *PITR) Name() string {
+	return jobName
+}
+
+func (p *PITR) DoRun() bool {
+	return p.config.Enabled
+}
+
+func (p *PITR) setRunning(running bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.running = running
+}
+
+func (p *PITR) IsRunning() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.running
+}
+
+// Collector returns the prometheus.Collector of the oplog tailing metrics
+func (p *PITR) Collector() *Collector {
+	return p.collector
+}
+
+// getOplogTimestamp returns the timestamp of the first oplog entry in natural 'order',
+// 1 for the oldest entry and -1 for the newest
+func (p *PITR) getOplogTimestamp(order int) (bson.MongoTimestamp, error) {
+	entry := &oplogEntry{}
+	err := db.FindOne(p.session, oplogDB, bson.D{
+		{Name: "find", Value: oplogCollection},
+		{Name: "sort", Value: bson.D{{Name: "$natural", Value: order}}},
+		{Name: "projection", Value: bson.M{"ts": 1}},
+	}, entry)
+	return entry.Timestamp, err
+}
+
+// getResumeTimestamp returns the end timestamp of the newest stored chunk of
+// replset 'rsName', or zero if there are no chunks
+func (p *PITR) getResumeTimestamp(ctx context.Context, rsName string) (bson.MongoTimestamp, error) {
+	names, err := p.storage.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	chunks := backupStorage.FilterChunks(names, rsName)
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+	return chunks[len(chunks)-1].End, nil
+}
+
+// getTailQuery returns the oplog query for entries after 'resume'. A zero 'resume'
+// starts after the newest oplog entry. If the oplog rolled over past 'resume' the
+// gap is recorded and the query starts at the oldest oplog entry
+func (p *PITR) getTailQuery(resume bson.MongoTimestamp) (bson.M, error) {
+	if resume == 0 {
+		newest, err := p.getOplogTimestamp(-1)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"ts": bson.M{"$gt": newest}}, nil
+	}
+
+	oldest, err := p.getOplogTimestamp(1)
+	if err != nil {
+		return nil, err
+	}
+	if isGap(resume, oldest) {
+		p.collector.GapsTotal.Inc()
+		log.WithFields(log.Fields{
+			"last_stored":  backupStorage.TimestampTime(resume),
+			"oldest_oplog": backupStorage.TimestampTime(oldest),
+		}).Error("Oplog rolled over before it was captured, point-in-time recovery is not possible within the gap")
+		return bson.M{"ts": bson.M{"$gte": oldest}}, nil
+	}
+	return bson.M{"ts": bson.M{"$gt": resume}}, nil
+}
+
+// prune deletes the chunks of replset 'rsName' with entries older than the retention,
+// the newest chunk is kept to resume from
+func (p *PITR) prune(ctx context.Context, rsName string) error {
+	names, err := p.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	chunks := backupStorage.FilterChunks(names, rsName)
+	if p.config.Retention > 0 {
+		expiry := time.Now().Add(-p.config.Retention)
+		for len(chunks) > 1 && backupStorage.TimestampTime(chunks[0].End).Before(expiry) {
+			log.WithFields(log.Fields{
+				"storage": p.storage.Name(),
+				"chunk":   chunks[0].Name,
+			}).Debug("Deleting expired oplog chunk")
+			err = p.storage.Delete(ctx, chunks[0].Name)
+			if err != nil {
+				return fmt.Errorf("cannot delete expired oplog chunk %s: %v", chunks[0].Name, err)
+			}
+			chunks = chunks[1:]
+		}
+	}
+	p.collector.StoredChunks.Set(float64(len(chunks)))
+	return nil
+}
+
+// flush stores a chunk and applies the retention, empty chunks are skipped
+func (p *PITR) flush(ctx context.Context, chunk *chunkWriter) error {
+	if chunk.isEmpty() {
+		return nil
+	}
+	reader, err := chunk.close()
+	if err != nil {
+		return err
+	}
+
+	name := chunk.name()
+	size, err := p.storage.Put(ctx, name, reader)
+	if err != nil {
+		p.collector.ChunksTotal.WithLabelValues("failed").Inc()
+		return err
+	}
+	p.collector.ChunksTotal.WithLabelValues("success").Inc()
+	p.collector.LastTimestamp.Set(float64(backupStorage.TimestampTime(chunk.end).Unix()))
+	p.collector.LastChunkSizeBytes.Set(float64(size))
+	log.WithFields(log.Fields{
+		"storage": p.storage.Name(),
+		"chunk":   name,
+		"size":    size,
+	}).Debug("Stored oplog chunk")
+
+	err = p.prune(ctx, chunk.rsName)
+	if err != nil {
+		log.Errorf("Cannot apply oplog chunk retention: %s", err)
+	}
+	return nil
+}
+
+// tail stores the oplog entries of replset 'rsName' after 'resume' in chunks until
+// 'ctx' is done or the cursor fails. It returns the end timestamp of the last stored chunk
+func (p *PITR) tail(ctx context.Context, rsName string, resume bson.MongoTimestamp) (bson.MongoTimestamp, error) {
+	query, err := p.getTailQuery(resume)
+	if err != nil {
+		return resume, err
+	}
+
+	iter, err := db.NewCursor(p.session, oplogDB, bson.D{
+		{Name: "find", Value: oplogCollection},
+		{Name: "filter", Value: query},
+		{Name: "tailable", Value: true},
+		{Name: "awaitData", Value: true},
+		{Name: "oplogReplay", Value: true},
+	}, tailTimeout)
+	if err != nil {
+		return resume, err
+	}
+	defer iter.Close()
+
+	chunk := newChunkWriter(rsName)
+	for ctx.Err() == nil {
+		raw := bson.Raw{}
+		if iter.Next(&raw) {
+			entry := &oplogEntry{}
+			if err = raw.Unmarshal(entry); err != nil {
+				break
+			}
+			if err = chunk.write(raw.Data, entry.Timestamp); err != nil {
+				break
+			}
+		} else if iter.Err() != nil {
+			err = iter.Err()
+			break
+		} else if !iter.Timeout() {
+			err = ErrCursorClosed
+			break
+		}
+
+		if chunk.size >= p.config.ChunkMaxBytes || chunk.isDue(p.config.ChunkInterval) {
+			if err := p.flush(ctx, chunk); err != nil {
+				// the entries of the failed chunk are read again on resume
+				return resume, err
+			}
+			resume = chunk.end
+			chunk = newChunkWriter(rsName)
+		}
+	}
+
+	// store the entries read so far, 'ctx' may be done already
+	flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if fErr := p.flush(flushCtx, chunk); fErr != nil {
+		return resume, fErr
+	}
+	if !chunk.isEmpty() {
+		resume = chunk.end
+	}
+	return resume, err
+}
+
+// runTail tails the oplog if this instance is the backup member of the replset
+func (p *PITR) runTail(ctx context.Context) {
+	isMaster, err := backup.GetIsMaster(p.session)
+	if err != nil {
+		log.Errorf("Cannot check if backup member: %s", err)
+		return
+	}
+	if !isMaster.IsBackupMember() {
+		log.Debug("Skipping oplog tailing on non-backup member")
+		return
+	}
+
+	resume, err := p.getResumeTimestamp(ctx, isMaster.SetName)
+	if err != nil {
+		log.Errorf("Cannot get oplog resume timestamp: %s", err)
+		return
+	}
+	if resume > 0 {
+		log.Infof("Resuming oplog tailing after %s", backupStorage.TimestampTime(resume))
+	}
+
+	_, err = p.tail(ctx, isMaster.SetName, resume)
+	if err != nil && ctx.Err() == nil {
+		log.Errorf("Oplog tailing failed: %s", err)
+	}
+}
+
+func (p *PITR) Run(ctx context.Context) error {
+	if p.DoRun() == false {
+		log.Warn("PITR disabled! Skipping start")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"storage":         p.storage.Name(),
+		"chunk_interval":  p.config.ChunkInterval,
+		"chunk_max_bytes": p.config.ChunkMaxBytes,
+		"retention":       p.config.Retention,
+	}).Info("Starting oplog tailer")
+
+	p.setRunning(true)
+	defer p.setRunning(false)
+	for {
+		p.runTail(ctx)
+
+		select {
+		case <-time.After(p.config.RetrySleep):
+		case <-ctx.Done():
+			log.Info("Stopping oplog tailer")
+			return nil
+		}
+	}
+}
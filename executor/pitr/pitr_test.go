@@ -0,0 +1,201 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pitr
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/backup"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestExecutorPITRIsGap(t *testing.T) {
+	assert.False(t, isGap(backup.NewTimestamp(100, 2), backup.NewTimestamp(100, 1)))
+	assert.False(t, isGap(backup.NewTimestamp(100, 2), backup.NewTimestamp(100, 2)))
+	assert.True(t, isGap(backup.NewTimestamp(100, 2), backup.NewTimestamp(100, 3)))
+}
+
+func TestExecutorPITRGetResumeTimestamp(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	storage := backup.NewLocalStorage(dir)
+	p := New(&Config{}, nil, storage)
+	resume, err := p.getResumeTimestamp(ctx, "rs")
+	assert.NoError(t, err)
+	assert.Zero(t, resume)
+
+	for _, name := range []string{
+		backup.ChunkName("rs", backup.NewTimestamp(100, 1), backup.NewTimestamp(200, 1)),
+		backup.ChunkName("rs", backup.NewTimestamp(200, 2), backup.NewTimestamp(300, 1)),
+		backup.ChunkName("other", backup.NewTimestamp(300, 2), backup.NewTimestamp(400, 1)),
+	} {
+		_, err := storage.Put(ctx, name, strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+	resume, err = p.getResumeTimestamp(ctx, "rs")
+	assert.NoError(t, err)
+	assert.Equal(t, backup.NewTimestamp(300, 1), resume)
+
+	// a failed storage
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, []byte{}, 0644))
+	p = New(&Config{}, nil, backup.NewLocalStorage(file))
+	_, err = p.getResumeTimestamp(ctx, "rs")
+	assert.Error(t, err)
+}
+
+func TestExecutorPITRFlush(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	storage := backup.NewLocalStorage(dir)
+	p := New(&Config{}, nil, storage)
+	assert.NoError(t, p.flush(ctx, newChunkWriter("rs")))
+	names, _ := storage.List(ctx)
+	assert.Len(t, names, 0, "an empty chunk should not be stored")
+
+	chunk := newChunkWriter("rs")
+	assert.NoError(t, chunk.write([]byte("entry"), backup.NewTimestamp(1546398245, 1)))
+	assert.NoError(t, p.flush(ctx, chunk))
+	names, _ = storage.List(ctx)
+	assert.Equal(t, []string{chunk.name()}, names)
+	assert.Equal(t, float64(1), testutils.MetricValue(t, p.collector.ChunksTotal.WithLabelValues("success")))
+	assert.Equal(t, float64(1546398245), testutils.MetricValue(t, p.collector.LastTimestamp))
+	assert.NotZero(t, testutils.MetricValue(t, p.collector.LastChunkSizeBytes))
+}
+
+func TestExecutorPITRGetTailQuery(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	p := New(&Config{}, testSession, nil)
+	oldest, err := p.getOplogTimestamp(1)
+	assert.NoError(t, err)
+	newest, err := p.getOplogTimestamp(-1)
+	assert.NoError(t, err)
+	assert.True(t, newest >= oldest)
+
+	query, err := p.getTailQuery(0)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"ts": bson.M{"$gt": newest}}, query)
+
+	query, err = p.getTailQuery(newest)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"ts": bson.M{"$gt": newest}}, query)
+
+	// the oplog rolled over
+	query, err = p.getTailQuery(oldest - 1)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"ts": bson.M{"$gte": oldest}}, query)
+	assert.Equal(t, float64(1), testutils.MetricValue(t, p.collector.GapsTotal))
+}
+
+func TestExecutorPITRTail(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	oldest, err := New(&Config{}, testSession, nil).getOplogTimestamp(1)
+	assert.NoError(t, err)
+
+	storage := backup.NewLocalStorage(dir)
+	p := New(&Config{ChunkInterval: time.Hour, ChunkMaxBytes: 64 * 1024 * 1024}, testSession, storage)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*tailTimeout)
+	defer cancel()
+	resume, err := p.tail(ctx, testutils.MongodbReplsetName, oldest)
+	assert.NoError(t, err)
+
+	chunks := backup.FilterChunks(listNames(t, storage), testutils.MongodbReplsetName)
+	if assert.Len(t, chunks, 1, "the pending chunk should be stored on stop") {
+		assert.Equal(t, resume, chunks[0].End)
+		assert.True(t, chunks[0].Start > oldest)
+	}
+}
+
+func TestExecutorPITRRun(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	p := New(&Config{Enabled: true, RetrySleep: time.Second}, testSession, backup.NewLocalStorage(dir))
+	assert.Equal(t, "PITR", p.Name())
+	assert.True(t, p.DoRun())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan bool)
+	go func() {
+		p.Run(ctx)
+		stopped <- true
+	}()
+	for tries := 0; !p.IsRunning() && tries < 100; tries++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, p.IsRunning())
+
+	cancel()
+	<-stopped
+	assert.False(t, p.IsRunning())
+}
+
+func TestExecutorPITRPrune(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	now := uint32(time.Now().Unix())
+	expired := backup.ChunkName("rs", backup.NewTimestamp(now-7200, 1), backup.NewTimestamp(now-5400, 1))
+	kept := backup.ChunkName("rs", backup.NewTimestamp(now-5400, 2), backup.NewTimestamp(now-1800, 1))
+	other := backup.ChunkName("other", backup.NewTimestamp(now-7200, 1), backup.NewTimestamp(now-5400, 1))
+
+	storage := backup.NewLocalStorage(dir)
+	for _, name := range []string{expired, kept, other} {
+		_, err := storage.Put(ctx, name, strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+
+	// no retention
+	p := New(&Config{}, nil, storage)
+	assert.NoError(t, p.prune(ctx, "rs"))
+	assert.Len(t, listNames(t, storage), 3)
+	assert.Equal(t, float64(2), testutils.MetricValue(t, p.collector.StoredChunks))
+
+	p.config.Retention = time.Hour
+	assert.NoError(t, p.prune(ctx, "rs"))
+	assert.Equal(t, []string{other, kept}, listNames(t, storage))
+	assert.Equal(t, float64(1), testutils.MetricValue(t, p.collector.StoredChunks))
+
+	// the newest chunk is kept to resume from
+	p.config.Retention = time.Minute
+	assert.NoError(t, p.prune(ctx, "rs"))
+	assert.Equal(t, []string{other, kept}, listNames(t, storage))
+}
+
+func listNames(t *testing.T, storage backup.Storage) []string {
+	names, err := storage.List(context.Background())
+	assert.NoError(t, err)
+	return names
+}
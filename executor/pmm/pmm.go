@@ -0,0 +1,228 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmm
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal"
+	"github.com/percona/mongodb-orchestration-tools/internal/command"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	jobName                    = "PMM"
+	serviceLinuxMetrics        = "linux:metrics"
+	serviceMongoDBMetrics      = "mongodb:metrics"
+	serviceMongoDBQueries      = "mongodb:queries"
+	pmmAdminCmdConfig          = "config"
+	pmmAdminCmdAdd             = "add"
+	pmmAdminCmdRemove          = "remove"
+	pmmAdminFlagRemoveServices = "--all"
+	pmmAdminEnvMongoDBURI      = "MONGODB_URI"
+)
+
+// PMM is a BackgroundJob that registers the node and its exporters with a
+// Percona Monitoring and Management server using 'pmm-admin'
+type PMM struct {
+	sync.Mutex
+	config  *Config
+	running bool
+}
+
+func New(config *Config) *PMM {
+	return &PMM{config: config}
+}
+
+func (p *PMM) Name() string {
+	return jobName
+}
+
+func (p *PMM) DoRun() bool {
+	return p.config.Enabled
+}
+
+func (p *PMM) setRunning(running bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.running = running
+}
+
+func (p *PMM) IsRunning() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.running
+}
+
+// runPMMAdmin runs 'pmm-admin' as the current user with 'args' and the extra
+// environment variables 'env'
+func (p *PMM) runPMMAdmin(env []string, args ...string) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return err
+	}
+	currentGroup, err := user.LookupGroupId(currentUser.Gid)
+	if err != nil {
+		return err
+	}
+
+	cmd, err := command.New(p.config.PMMAdminBin, args, currentUser, currentGroup)
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pmm-admin %s failed: %v: %s", args[0], err, internal.RedactUri(strings.TrimSpace(string(out))))
+	}
+	return nil
+}
+
+func (p *PMM) configArgs() []string {
+	args := []string{pmmAdminCmdConfig, "--server", p.config.ServerAddress}
+	if p.config.ClientName != "" {
+		args = append(args, "--client-name", p.config.ClientName)
+	}
+	if p.config.ServerInsecureSSL {
+		args = append(args, "--server-insecure-ssl")
+	} else if p.config.ServerSSL {
+		args = append(args, "--server-ssl")
+	}
+	return append(args, "--force")
+}
+
+// addArgs returns the 'pmm-admin add' arguments of the monitoring 'service'
+func (p *PMM) addArgs(service string) []string {
+	args := []string{pmmAdminCmdAdd, service}
+	switch service {
+	case serviceLinuxMetrics:
+		args = append(args, "--service-port", strconv.Itoa(int(p.config.LinuxMetricsExporterPort)))
+	case serviceMongoDBMetrics:
+		args = append(args, "--service-port", strconv.Itoa(int(p.config.MongoDBMetricsExporterPort)))
+		if p.config.MongoDBClusterName != "" {
+			args = append(args, "--cluster", p.config.MongoDBClusterName)
+		}
+	}
+	return args
+}
+
+// addEnv returns the 'pmm-admin add' environment variables of the monitoring 'service'.
+// The mongodb connection string has the password, so it is passed in the environment
+// rather than the '--uri' flag to keep it out of the process list
+func (p *PMM) addEnv(service string) []string {
+	switch service {
+	case serviceMongoDBMetrics, serviceMongoDBQueries:
+		return []string{pmmAdminEnvMongoDBURI + "=" + p.config.DB.Uri()}
+	}
+	return nil
+}
+
+// services returns the monitoring services to add to the PMM server
+func (p *PMM) services() []string {
+	services := []string{serviceLinuxMetrics, serviceMongoDBMetrics}
+	if p.config.EnableQueryAnalytics {
+		services = append(services, serviceMongoDBQueries)
+	}
+	return services
+}
+
+// register configures the PMM client and adds the monitoring services, services
+// left behind by a previous run are removed first
+func (p *PMM) register() error {
+	log.WithFields(log.Fields{
+		"server": p.config.ServerAddress,
+		"client": p.config.ClientName,
+	}).Info("Registering with PMM server")
+
+	err := p.runPMMAdmin(nil, p.configArgs()...)
+	if err != nil {
+		return err
+	}
+
+	err = p.runPMMAdmin(nil, pmmAdminCmdRemove, pmmAdminFlagRemoveServices)
+	if err != nil {
+		log.Debugf("Cannot remove previous PMM services: %s", err)
+	}
+
+	for _, service := range p.services() {
+		log.Infof("Adding PMM service: %s", service)
+		err = p.runPMMAdmin(p.addEnv(service), p.addArgs(service)...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deregister removes all monitoring services of the PMM client
+func (p *PMM) deregister() error {
+	log.WithFields(log.Fields{
+		"server": p.config.ServerAddress,
+	}).Info("Deregistering from PMM server")
+	return p.runPMMAdmin(nil, pmmAdminCmdRemove, pmmAdminFlagRemoveServices)
+}
+
+// registerWithRetry retries the registration until it succeeds or 'ctx' is done, with
+// a backoff doubled on each failure. It returns false if 'ctx' is done first
+func (p *PMM) registerWithRetry(ctx context.Context) bool {
+	backoff := p.config.RegisterBackoffMin
+	for {
+		err := p.register()
+		if err == nil {
+			return true
+		}
+		log.WithFields(log.Fields{
+			"retry_in": backoff,
+		}).Errorf("Cannot register with PMM server: %s", err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if p.config.RegisterBackoffMax > 0 && backoff > p.config.RegisterBackoffMax {
+			backoff = p.config.RegisterBackoffMax
+		}
+	}
+}
+
+func (p *PMM) Run(ctx context.Context) error {
+	if p.DoRun() == false {
+		log.Warn("PMM disabled! Skipping start")
+		return nil
+	}
+
+	p.setRunning(true)
+	defer p.setRunning(false)
+
+	if !p.registerWithRetry(ctx) {
+		return nil
+	}
+	log.Info("Completed PMM server registration")
+
+	<-ctx.Done()
+	err := p.deregister()
+	if err != nil {
+		log.Errorf("Cannot deregister from PMM server: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	jobName           = "Profiler"
+	profileCollection = "system.profile"
+	drainBatchSize    = 1000
+)
+
+// skipDatabases are databases the profiler cannot be enabled on
+var skipDatabases = map[string]bool{
+	"local": true,
+}
+
+// ProfileResp is the response of the 'profile' server command
+type ProfileResp struct {
+	Was        int     `bson:"was" json:"was"`
+	SlowMs     int     `bson:"slowms" json:"slowms"`
+	SampleRate float64 `bson:"sampleRate,omitempty" json:"sampleRate,omitempty"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+// drainPosition is the timestamp of the last drained 'system.profile' entry of a
+// database and the number of drained entries with that timestamp. Profile entries
+// have no _id, entries sharing the timestamp are paged by skipping them
+type drainPosition struct {
+	ts    time.Time
+	count int
+}
+
+// advance moves the position past the drained 'entries', in timestamp order. It
+// returns false if no entry moved the position
+func (d *drainPosition) advance(entries []bson.M) bool {
+	advanced := false
+	for _, entry := range entries {
+		ts, ok := entry["ts"].(time.Time)
+		if !ok {
+			continue
+		}
+		if ts.Equal(d.ts) {
+			d.count++
+		} else if ts.After(d.ts) {
+			d.ts = ts
+			d.count = 1
+		} else {
+			continue
+		}
+		advanced = true
+	}
+	return advanced
+}
+
+// Profiler is a BackgroundJob that applies the desired database profiler settings
+// to all databases and optionally drains 'system.profile' to a JSON-lines file
+type Profiler struct {
+	sync.Mutex
+	config      *Config
+	session     db.Session
+	lastDrained map[string]*drainPosition
+	started     time.Time
+	running     bool
+}
+
+func New(config *Config, session db.Session) *Profiler {
+	return &Profiler{
+		config:      config,
+		session:     session,
+		lastDrained: make(map[string]*drainPosition),
+	}
+}
+
+func (p *Profiler) Name() string {
+	return jobName
+}
+
+func (p *Profiler) DoRun() bool {
+	return p.config.Enabled
+}
+
+func (p *Profiler) setRunning(running bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.running = running
+}
+
+func (p *Profiler) IsRunning() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.running
+}
+
+// isApplied returns true if the profiler settings of 'resp' match the desired settings
+func (p *Profiler) isApplied(resp *ProfileResp) bool {
+	if resp.Was != p.config.Level || resp.SlowMs != p.config.SlowMs {
+		return false
+	}
+	// servers before 3.6 do not return a sample rate
+	return resp.SampleRate == 0 || resp.SampleRate == p.config.SampleRate
+}
+
+// profileCmd returns the 'profile' server command applying the desired settings, the
+// sample rate is only set on servers that support it
+func (p *Profiler) profileCmd(current *ProfileResp) bson.D {
+	cmd := bson.D{
+		{Name: "profile", Value: p.config.Level},
+		{Name: "slowms", Value: p.config.SlowMs},
+	}
+	if current.SampleRate > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "sampleRate", Value: p.config.SampleRate})
+	}
+	return cmd
+}
+
+func (p *Profiler) runProfileCmd(dbName string, cmd bson.D) (*ProfileResp, error) {
+	resp := &ProfileResp{}
+	err := p.session.RunOn(dbName, cmd, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	return resp, nil
+}
+
+// getDatabases returns the names of the databases to profile
+func (p *Profiler) getDatabases() ([]string, error) {
+	names, err := db.ListDatabases(p.session)
+	if err != nil {
+		return nil, err
+	}
+	databases := make([]string, 0)
+	for _, name := range names {
+		if !skipDatabases[name] {
+			databases = append(databases, name)
+		}
+	}
+	return databases, nil
+}
+
+// apply sets the desired profiler settings on database 'dbName' if they differ
+func (p *Profiler) apply(dbName string) error {
+	current, err := p.runProfileCmd(dbName, bson.D{{Name: "profile", Value: -1}})
+	if err != nil {
+		return err
+	}
+	if p.isApplied(current) {
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"db":     dbName,
+		"level":  p.config.Level,
+		"slowms": p.config.SlowMs,
+	}).Info("Setting database profiler")
+	_, err = p.runProfileCmd(dbName, p.profileCmd(current))
+	return err
+}
+
+// writeEntries writes 'system.profile' entries of database 'dbName' to 'out' as JSON lines
+func writeEntries(out io.Writer, dbName string, entries []bson.M) error {
+	encoder := json.NewEncoder(out)
+	for _, entry := range entries {
+		entry["db"] = dbName
+		err := encoder.Encode(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findEntries returns up to 'drainBatchSize' 'system.profile' entries of database 'dbName'
+// after drain position 'pos', in timestamp order
+func (p *Profiler) findEntries(dbName string, pos *drainPosition) ([]bson.M, error) {
+	cursor, err := db.NewCursor(p.session, dbName, bson.D{
+		{Name: "find", Value: profileCollection},
+		{Name: "filter", Value: bson.M{"ts": bson.M{"$gte": pos.ts}}},
+		{Name: "sort", Value: bson.D{{Name: "ts", Value: 1}}},
+		{Name: "skip", Value: pos.count},
+		{Name: "limit", Value: drainBatchSize},
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	entries := make([]bson.M, 0)
+	entry := bson.M{}
+	for cursor.Next(&entry) {
+		entries = append(entries, entry)
+		entry = bson.M{}
+	}
+	return entries, cursor.Err()
+}
+
+// drain writes the 'system.profile' entries of database 'dbName' newer than the last
+// drained entry to 'out', in batches until a batch is not full. Entries before the
+// start of the Profiler are not drained
+func (p *Profiler) drain(out io.Writer, dbName string) error {
+	pos, ok := p.lastDrained[dbName]
+	if !ok {
+		pos = &drainPosition{ts: p.started}
+		p.lastDrained[dbName] = pos
+	}
+
+	for {
+		entries, err := p.findEntries(dbName, pos)
+		if err != nil {
+			return err
+		}
+		advanced := pos.advance(entries)
+		err = writeEntries(out, dbName, entries)
+		if err != nil || len(entries) < drainBatchSize || !advanced {
+			return err
+		}
+	}
+}
+
+// run applies the profiler settings to all databases and drains them if enabled
+func (p *Profiler) run(out io.Writer) {
+	databases, err := p.getDatabases()
+	if err != nil {
+		log.Errorf("Cannot list databases: %s", err)
+		return
+	}
+	for _, dbName := range databases {
+		err = p.apply(dbName)
+		if err != nil {
+			log.Errorf("Cannot set profiler on database %s: %s", dbName, err)
+			continue
+		}
+		if out != nil && p.config.Level > 0 {
+			err = p.drain(out, dbName)
+			if err != nil {
+				log.Errorf("Cannot drain profiler of database %s: %s", dbName, err)
+			}
+		}
+	}
+}
+
+func (p *Profiler) Run(ctx context.Context) error {
+	if p.DoRun() == false {
+		log.Warn("Profiler disabled! Skipping start")
+		return nil
+	}
+
+	var out io.Writer
+	if p.config.DrainFile != "" {
+		file, err := os.OpenFile(p.config.DrainFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("cannot open profiler drain file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	log.WithFields(log.Fields{
+		"level":       p.config.Level,
+		"slowms":      p.config.SlowMs,
+		"sample_rate": p.config.SampleRate,
+		"interval":    p.config.Interval,
+		"drain_file":  p.config.DrainFile,
+	}).Info("Starting profiler manager")
+
+	p.setRunning(true)
+	defer p.setRunning(false)
+
+	p.started = time.Now()
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+	for {
+		p.run(out)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Info("Stopping profiler manager")
+			return nil
+		}
+	}
+}
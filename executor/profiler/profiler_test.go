@@ -0,0 +1,178 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestExecutorProfilerIsApplied(t *testing.T) {
+	p := New(&Config{Level: 1, SlowMs: 100, SampleRate: 0.5}, nil)
+	assert.True(t, p.isApplied(&ProfileResp{Was: 1, SlowMs: 100, SampleRate: 0.5}))
+	assert.True(t, p.isApplied(&ProfileResp{Was: 1, SlowMs: 100}), "servers without a sample rate should ignore it")
+	assert.False(t, p.isApplied(&ProfileResp{Was: 0, SlowMs: 100, SampleRate: 0.5}))
+	assert.False(t, p.isApplied(&ProfileResp{Was: 1, SlowMs: 200, SampleRate: 0.5}))
+	assert.False(t, p.isApplied(&ProfileResp{Was: 1, SlowMs: 100, SampleRate: 1.0}))
+}
+
+func TestExecutorProfilerProfileCmd(t *testing.T) {
+	p := New(&Config{Level: 2, SlowMs: 50, SampleRate: 0.5}, nil)
+	assert.Equal(t, bson.D{
+		{Name: "profile", Value: 2},
+		{Name: "slowms", Value: 50},
+	}, p.profileCmd(&ProfileResp{}))
+	assert.Equal(t, bson.D{
+		{Name: "profile", Value: 2},
+		{Name: "slowms", Value: 50},
+		{Name: "sampleRate", Value: 0.5},
+	}, p.profileCmd(&ProfileResp{SampleRate: 1.0}))
+}
+
+func TestExecutorProfilerWriteEntries(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NoError(t, writeEntries(out, "test", []bson.M{
+		{"op": "query", "millis": 120},
+		{"op": "update", "millis": 250},
+	}))
+	assert.Equal(t, "{\"db\":\"test\",\"millis\":120,\"op\":\"query\"}\n{\"db\":\"test\",\"millis\":250,\"op\":\"update\"}\n", out.String())
+}
+
+func TestExecutorProfilerRunDrainFileError(t *testing.T) {
+	p := New(&Config{Enabled: true, DrainFile: "/does/not/exist/profile.json"}, nil)
+	assert.Equal(t, "Profiler", p.Name())
+	assert.True(t, p.DoRun())
+	p.Run(context.Background())
+	assert.False(t, p.IsRunning())
+}
+
+func TestExecutorProfilerApply(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	p := New(&Config{Level: 1, SlowMs: 150, SampleRate: 1.0}, db.NewMgoSession(testSession))
+	databases, err := p.getDatabases()
+	assert.NoError(t, err)
+	assert.NotContains(t, databases, "local")
+
+	assert.NoError(t, p.apply(t.Name()))
+	current, err := p.runProfileCmd(t.Name(), bson.D{{Name: "profile", Value: -1}})
+	assert.NoError(t, err)
+	assert.True(t, p.isApplied(current))
+
+	p.config.Level = 0
+	assert.NoError(t, p.apply(t.Name()))
+	assert.NoError(t, testSession.DB(t.Name()).DropDatabase())
+}
+
+func TestExecutorProfilerDrainPosition(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	pos := &drainPosition{ts: start}
+	assert.False(t, pos.advance([]bson.M{}))
+	assert.False(t, pos.advance([]bson.M{{"op": "query"}}), "entries without a timestamp should not move the position")
+
+	// entries sharing the timestamp of a batch boundary are counted to be skipped
+	assert.True(t, pos.advance([]bson.M{
+		{"ts": start},
+		{"ts": start.Add(time.Millisecond)},
+		{"ts": start.Add(time.Millisecond)},
+	}))
+	assert.Equal(t, &drainPosition{ts: start.Add(time.Millisecond), count: 2}, pos)
+
+	assert.True(t, pos.advance([]bson.M{{"ts": start.Add(time.Millisecond)}}))
+	assert.Equal(t, &drainPosition{ts: start.Add(time.Millisecond), count: 3}, pos)
+
+	assert.True(t, pos.advance([]bson.M{{"ts": start.Add(time.Second)}}))
+	assert.Equal(t, &drainPosition{ts: start.Add(time.Second), count: 1}, pos)
+}
+
+func TestExecutorProfilerDrain(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	p := New(&Config{Level: 2, SlowMs: 100, SampleRate: 1.0}, db.NewMgoSession(testSession))
+	p.started = time.Now().Add(-time.Second)
+	defer testSession.DB(t.Name()).DropDatabase()
+
+	assert.NoError(t, p.apply(t.Name()))
+	assert.NoError(t, testSession.DB(t.Name()).C("test").Insert(bson.M{"test": true}))
+	p.config.Level = 0
+	assert.NoError(t, p.apply(t.Name()))
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, p.drain(out, t.Name()))
+	assert.Contains(t, out.String(), "\"db\":\""+t.Name()+"\"")
+
+	// drained entries are not written again
+	out.Reset()
+	assert.NoError(t, p.drain(out, t.Name()))
+	assert.Empty(t, out.String())
+}
+
+func TestExecutorProfilerDrainBatches(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	p := New(&Config{Level: 2, SlowMs: 100, SampleRate: 1.0}, db.NewMgoSession(testSession))
+	p.started = time.Now().Add(-time.Second)
+	defer testSession.DB(t.Name()).DropDatabase()
+
+	assert.NoError(t, p.apply(t.Name()))
+	for i := 0; i < 2*drainBatchSize; i++ {
+		assert.NoError(t, testSession.DB(t.Name()).C("test").Insert(bson.M{"test": i}))
+	}
+	p.config.Level = 0
+	assert.NoError(t, p.apply(t.Name()))
+
+	// all the entries are drained at once, not only the first batch
+	out := new(bytes.Buffer)
+	assert.NoError(t, p.drain(out, t.Name()))
+	assert.True(t, strings.Count(out.String(), "\n") > drainBatchSize)
+}
+
+func TestExecutorProfilerRun(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	drainFile := filepath.Join(dir, "profile.json")
+	p := New(&Config{Enabled: true, Level: 0, SlowMs: 100, SampleRate: 1.0, Interval: time.Second, DrainFile: drainFile}, db.NewMgoSession(testSession))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan bool)
+	go func() {
+		p.Run(ctx)
+		stopped <- true
+	}()
+	for tries := 0; !p.IsRunning() && tries < 100; tries++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, p.IsRunning())
+
+	cancel()
+	<-stopped
+	assert.False(t, p.IsRunning())
+	_, err := os.Stat(drainFile)
+	assert.NoError(t, err)
+}
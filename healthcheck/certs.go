@@ -0,0 +1,61 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"github.com/alecthomas/kingpin"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+)
+
+var DefaultCertExpiryWarnDays = "30"
+
+// CertExpiryConfig is the configuration of the SSL/TLS certificate expiry warning
+type CertExpiryConfig struct {
+	WarnDays float64
+}
+
+// NewCertExpiryConfig returns a CertExpiryConfig with command-line flags registered on 'app'
+func NewCertExpiryConfig(app *kingpin.Application) *CertExpiryConfig {
+	cnf := &CertExpiryConfig{}
+	app.Flag(
+		"ssl.expiryWarnDays",
+		"warn when an SSL/TLS certificate used by, or presented to, the check expires within this number of days, 0 disables the warning",
+	).Default(DefaultCertExpiryWarnDays).Float64Var(&cnf.WarnDays)
+	return cnf
+}
+
+// CertExpiryCheck returns the SSL/TLS certificates of 'sslCnf' and of the sessions that
+// expire within the warning period. Expiring certificates do not fail health checks,
+// they are a warning of an upcoming failure
+func CertExpiryCheck(sslCnf *db.SSLConfig, cnf *CertExpiryConfig) ([]db.CertExpiry, error) {
+	expiring := []db.CertExpiry{}
+	if cnf.WarnDays <= 0 {
+		return expiring, nil
+	}
+	if sslCnf != nil && sslCnf.Enabled {
+		_, err := sslCnf.CertManager()
+		if err != nil {
+			return expiring, err
+		}
+	}
+	for _, cm := range db.CertManagers() {
+		for _, expiry := range cm.Expiries() {
+			if expiry.DaysUntilExpiry() < cnf.WarnDays {
+				expiring = append(expiring, expiry)
+			}
+		}
+	}
+	return expiring, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/percona/mongodb-orchestration-tools/internal"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthcheckNewCertExpiryConfig(t *testing.T) {
+	app := kingpin.New(t.Name(), t.Name())
+	cnf := NewCertExpiryConfig(app)
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(30), cnf.WarnDays)
+}
+
+func TestHealthcheckCertExpiryCheck(t *testing.T) {
+	sslCnf := &db.SSLConfig{
+		Enabled:    true,
+		PEMKeyFile: internal.RelPathToAbs(filepath.Join("../docker/test/ssl", "client.pem")),
+	}
+
+	// the test certificates have expired
+	expiring, err := CertExpiryCheck(sslCnf, &CertExpiryConfig{WarnDays: 30})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, expiring)
+	for _, expiry := range expiring {
+		assert.True(t, expiry.DaysUntilExpiry() < 30)
+	}
+
+	expiring, err = CertExpiryCheck(sslCnf, &CertExpiryConfig{})
+	assert.NoError(t, err)
+	assert.Len(t, expiring, 0, "a warning period of 0 should disable the check")
+
+	_, err = CertExpiryCheck(&db.SSLConfig{Enabled: true, PEMKeyFile: "/does/not/exist.pem"}, &CertExpiryConfig{WarnDays: 30})
+	assert.Error(t, err)
+}
@@ -17,9 +17,10 @@ package healthcheck
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/timvaillancourt/go-mongodb-replset/status"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -52,25 +53,36 @@ func isStateOk(memberState *status.MemberState, okMemberStates []status.MemberSt
 	return false
 }
 
-// HealthCheck checks the replication member state of the local MongoDB member
-func HealthCheck(session *mgo.Session, okMemberStates []status.MemberState) (State, *status.MemberState, error) {
-	rsStatus, err := status.New(session)
-	if err != nil {
+// HealthCheck checks the replication member state of the local MongoDB member. If
+// 'replCnf' enables them, the replication lag and majority commit point are also checked
+func HealthCheck(session db.Session, okMemberStates []status.MemberState, replCnf *ReplicationConfig) (State, *status.MemberState, error) {
+	rsStatus := &status.Status{}
+	if err := session.Run(bson.D{{Name: "replSetGetStatus", Value: 1}}, rsStatus); err != nil {
 		return StateFailed, nil, fmt.Errorf("error getting replica set status: %s", err)
 	}
+	if rsStatus.Ok == 0 {
+		return StateFailed, nil, fmt.Errorf("error getting replica set status: %s", rsStatus.Errmsg)
+	}
 
 	state := getSelfMemberState(rsStatus)
 	if state == nil {
 		return StateFailed, state, fmt.Errorf("found no member state for self in replica set status")
 	}
-	if isStateOk(state, okMemberStates) {
-		return StateOk, state, nil
+	if !isStateOk(state, okMemberStates) {
+		return StateFailed, state, fmt.Errorf("member has unhealthy replication state: %s", state)
+	}
+
+	if replCnf.Enabled() {
+		replState, err := ReplicationCheck(session, replCnf)
+		if err != nil {
+			return replState, state, err
+		}
 	}
 
-	return StateFailed, state, fmt.Errorf("member has unhealthy replication state: %s", state)
+	return StateOk, state, nil
 }
 
-func HealthCheckMongosLiveness(session *mgo.Session) error {
+func HealthCheckMongosLiveness(session db.Session) error {
 	isMasterResp := IsMasterResp{}
 
 	if err := session.Run(bson.D{{Name: "isMaster", Value: 1}}, &isMasterResp); err != nil {
@@ -88,16 +100,9 @@ func HealthCheckMongosLiveness(session *mgo.Session) error {
 	return nil
 }
 
-func HealthCheckMongodLiveness(session *mgo.Session, startupDelaySeconds int64) (*status.MemberState, error) {
-	isMasterResp := IsMasterResp{}
-	if err := session.Run(bson.D{{Name: "isMaster", Value: 1}}, &isMasterResp); err != nil {
-		return nil, fmt.Errorf("isMaster returned error %v", err)
-	}
-	if isMasterResp.Ok == 0 {
-		return nil, errors.New(isMasterResp.Errmsg)
-	}
-
-	info, err := session.BuildInfo()
+// getReplSetStatus runs 'replSetGetStatus', including the initial sync status on versions that require it
+func getReplSetStatus(session db.Session) (*ReplSetStatus, error) {
+	info, err := db.GetBuildInfo(session)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get mongo build info: %v", err)
 	}
@@ -108,13 +113,29 @@ func HealthCheckMongodLiveness(session *mgo.Session, startupDelaySeconds int64)
 		replSetStatusCommand = append(replSetStatusCommand, bson.DocElem{Name: "initialSync", Value: 1})
 	}
 
-	replSetGetStatusResp := ReplSetStatus{}
-	if err := session.Run(replSetStatusCommand, &replSetGetStatusResp); err != nil {
+	replSetGetStatusResp := &ReplSetStatus{}
+	if err := session.Run(replSetStatusCommand, replSetGetStatusResp); err != nil {
 		return nil, fmt.Errorf("replSetGetStatus returned error %v", err)
 	}
+	return replSetGetStatusResp, nil
+}
+
+func HealthCheckMongodLiveness(session db.Session, startupDelaySeconds int64) (*status.MemberState, error) {
+	isMasterResp := IsMasterResp{}
+	if err := session.Run(bson.D{{Name: "isMaster", Value: 1}}, &isMasterResp); err != nil {
+		return nil, fmt.Errorf("isMaster returned error %v", err)
+	}
+	if isMasterResp.Ok == 0 {
+		return nil, errors.New(isMasterResp.Errmsg)
+	}
+
+	replSetGetStatusResp, err := getReplSetStatus(session)
+	if err != nil {
+		return nil, err
+	}
 
 	oplogRs := OplogRs{}
-	if err := session.DB("local").Run(bson.D{
+	if err := session.RunOn("local", bson.D{
 		{Name: "collStats", Value: "oplog.rs"},
 		{Name: "scale", Value: 1024 * 1024 * 1024}, // scale size to gigabytes
 	}, &oplogRs); err != nil {
@@ -153,10 +174,28 @@ type OplogRs struct {
 
 type ReplSetStatus struct {
 	status.Status     `bson:",inline"`
-	InitialSyncStatus InitialSyncStatus `bson:"initialSyncStatus" json:"initialSyncStatus"`
+	InitialSyncStatus *InitialSyncStatus `bson:"initialSyncStatus,omitempty" json:"initialSyncStatus,omitempty"`
 }
 
-type InitialSyncStatus interface{}
+// InitialSyncDatabases is the summary of databases cloned by an initial sync
+type InitialSyncDatabases struct {
+	DatabasesToClone int64 `bson:"databasesToClone" json:"databasesToClone"`
+	DatabasesCloned  int64 `bson:"databasesCloned" json:"databasesCloned"`
+}
+
+// InitialSyncStatus is the 'initialSyncStatus' document of 'replSetGetStatus'
+//
+// https://docs.mongodb.com/manual/reference/command/replSetGetStatus/#replSetGetStatus.initialSyncStatus
+type InitialSyncStatus struct {
+	FailedInitialSyncAttempts     int64                `bson:"failedInitialSyncAttempts" json:"failedInitialSyncAttempts"`
+	MaxFailedInitialSyncAttempts  int64                `bson:"maxFailedInitialSyncAttempts" json:"maxFailedInitialSyncAttempts"`
+	InitialSyncStart              time.Time            `bson:"initialSyncStart" json:"initialSyncStart"`
+	TotalInitialSyncElapsedMillis int64                `bson:"totalInitialSyncElapsedMillis" json:"totalInitialSyncElapsedMillis"`
+	AppliedOps                    int64                `bson:"appliedOps" json:"appliedOps"`
+	ApproxTotalDataSize           int64                `bson:"approxTotalDataSize,omitempty" json:"approxTotalDataSize,omitempty"`
+	ApproxTotalBytesCopied        int64                `bson:"approxTotalBytesCopied,omitempty" json:"approxTotalBytesCopied,omitempty"`
+	Databases                     InitialSyncDatabases `bson:"databases" json:"databases"`
+}
 
 func (rs ReplSetStatus) CheckState(startupDelaySeconds int64, oplogSize int64) error {
 	if rs.Ok == 0 {
@@ -64,12 +64,12 @@ func TestHealthcheckIsMemberStateOk(t *testing.T) {
 func TestHealthcheckHealthCheck(t *testing.T) {
 	testutils.DoSkipTest(t)
 
-	state, memberState, err := HealthCheck(testDBSession, OkMemberStates)
+	state, memberState, err := HealthCheck(testDBSession, OkMemberStates, nil)
 	assert.NoError(t, err, "healthcheck.HealthCheck() returned an error")
 	assert.Equal(t, state, StateOk, "healthcheck.HealthCheck() returned non-ok state")
 	assert.Equal(t, *memberState, status.MemberStatePrimary, "healthcheck.HealthCheck() returned non-primary member state")
 
-	state, _, err = HealthCheck(testDBSession, []status.MemberState{status.MemberStateRemoved})
+	state, _, err = HealthCheck(testDBSession, []status.MemberState{status.MemberStateRemoved}, nil)
 	assert.EqualError(t, err,
 		"member has unhealthy replication state: "+status.MemberStatePrimary.String(),
 		"healthcheck.HealthCheck() returned an expected error",
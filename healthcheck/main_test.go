@@ -18,19 +18,19 @@ import (
 	"os"
 	"testing"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
-	"gopkg.in/mgo.v2"
 )
 
-var testDBSession *mgo.Session
+var testDBSession db.Session
 
 func TestMain(m *testing.M) {
 	if testutils.Enabled() {
-		var err error
-		testDBSession, err = testutils.GetSession(testutils.MongodbPrimaryPort)
+		mgoSession, err := testutils.GetSession(testutils.MongodbPrimaryPort)
 		if err != nil {
 			panic(err)
 		}
+		testDBSession = db.NewMgoSession(mgoSession)
 	}
 	exit := m.Run()
 	if testDBSession != nil {
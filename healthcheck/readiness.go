@@ -18,12 +18,12 @@ import (
 	"errors"
 	"fmt"
 
-	"gopkg.in/mgo.v2"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"gopkg.in/mgo.v2/bson"
 )
 
-// ReadinessCheck runs a ping on a pmgo.SessionManager to check server readiness
-func ReadinessCheck(session *mgo.Session) (State, error) {
+// ReadinessCheck runs a ping on a db.Session to check server readiness
+func ReadinessCheck(session db.Session) (State, error) {
 	err := session.Ping()
 
 	if err != nil {
@@ -33,7 +33,7 @@ func ReadinessCheck(session *mgo.Session) (State, error) {
 	return StateOk, nil
 }
 
-func MongosReadinessCheck(session *mgo.Session) error {
+func MongosReadinessCheck(session db.Session) error {
 	ss := ServerStatus{}
 
 	if err := session.Run(bson.D{{Name: "listDatabases", Value: 1}}, &ss); err != nil {
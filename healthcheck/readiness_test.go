@@ -18,26 +18,42 @@ import (
 	"errors"
 	"testing"
 
-	"github.com/golang/mock/gomock"
+	"github.com/percona/mongodb-orchestration-tools/internal/db/mocks"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
-	"github.com/percona/pmgo"
-	"github.com/percona/pmgo/pmgomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestHealthcheckReadinessCheck(t *testing.T) {
 	testutils.DoSkipTest(t)
 
 	assert.NoError(t, testDBSession.Ping(), "Database ping error")
-	state, err := ReadinessCheck(pmgo.NewSessionManager(testDBSession))
+	state, err := ReadinessCheck(testDBSession)
 	assert.NoError(t, err, "healthcheck.ReadinessCheck() returned an error")
 	assert.Equal(t, state, StateOk, "healthcheck.ReadinessCheck() returned incorrect state")
 
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockSession := pmgomock.NewMockSessionManager(ctrl)
-	mockSession.EXPECT().Ping().Return(errors.New("fake ping failure"))
+	mockSession := &mocks.Session{}
+	mockSession.On("Ping").Return(errors.New("fake ping failure"))
 	_, err = ReadinessCheck(mockSession)
 	assert.Error(t, err, "healthcheck.ReadinessCheck() did not return an expected error")
+	mockSession.AssertExpectations(t)
+}
+
+func TestHealthcheckMongosReadinessCheck(t *testing.T) {
+	mockSession := &mocks.Session{}
+	mockSession.On("Run", mock.Anything, mock.Anything).Return(func(cmd interface{}, result interface{}) error {
+		result.(*ServerStatus).Ok = 1
+		return nil
+	}).Once()
+	assert.NoError(t, MongosReadinessCheck(mockSession))
+
+	mockSession.On("Run", mock.Anything, mock.Anything).Return(func(cmd interface{}, result interface{}) error {
+		result.(*ServerStatus).Errmsg = "not ready"
+		return nil
+	}).Once()
+	assert.EqualError(t, MongosReadinessCheck(mockSession), "not ready")
+
+	mockSession.On("Run", mock.Anything, mock.Anything).Return(errors.New("fake run failure")).Once()
+	assert.Error(t, MongosReadinessCheck(mockSession))
+	mockSession.AssertExpectations(t)
 }
@@ -0,0 +1,179 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	"github.com/timvaillancourt/go-mongodb-replset/status"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	DefaultReplicationMaxLag               = "0s"
+	DefaultReplicationMaxMajorityCommitLag = "0s"
+)
+
+// ReplicationConfig is the configuration of the optional replication checks, a zero
+// duration disables a check
+type ReplicationConfig struct {
+	MaxLag               time.Duration
+	MaxMajorityCommitLag time.Duration
+}
+
+// NewReplicationConfig returns a ReplicationConfig with command-line flags registered on 'app'
+func NewReplicationConfig(app *kingpin.Application) *ReplicationConfig {
+	cnf := &ReplicationConfig{}
+	app.Flag(
+		"replication.maxLag",
+		"maximum replication lag of this member behind the primary, 0s disables the check",
+	).Default(DefaultReplicationMaxLag).DurationVar(&cnf.MaxLag)
+	app.Flag(
+		"replication.maxMajorityCommitLag",
+		"maximum time the majority commit point may trail the last applied operation, 0s disables the check",
+	).Default(DefaultReplicationMaxMajorityCommitLag).DurationVar(&cnf.MaxMajorityCommitLag)
+	return cnf
+}
+
+// Enabled returns true if any replication check is enabled
+func (cnf *ReplicationConfig) Enabled() bool {
+	return cnf != nil && (cnf.MaxLag > 0 || cnf.MaxMajorityCommitLag > 0)
+}
+
+// OpTime is a replication optime, as reported in 'replSetGetStatus'
+type OpTime struct {
+	Ts   bson.MongoTimestamp `bson:"ts" json:"ts"`
+	Term int64               `bson:"t" json:"t"`
+}
+
+// Time returns the wall-clock time of the optime
+func (o *OpTime) Time() time.Time {
+	return time.Unix(int64(o.Ts)>>32, 0)
+}
+
+// ReplSetOpTimes is the 'optimes' document of 'replSetGetStatus'
+type ReplSetOpTimes struct {
+	LastCommittedOpTime *OpTime `bson:"lastCommittedOpTime" json:"lastCommittedOpTime"`
+	AppliedOpTime       *OpTime `bson:"appliedOpTime" json:"appliedOpTime"`
+}
+
+// ReplSetLagMember is the subset of a 'replSetGetStatus' member used for lag checks
+type ReplSetLagMember struct {
+	Name       string             `bson:"name" json:"name"`
+	State      status.MemberState `bson:"state" json:"state"`
+	OptimeDate time.Time          `bson:"optimeDate" json:"optimeDate"`
+	Self       bool               `bson:"self,omitempty" json:"self,omitempty"`
+}
+
+// ReplSetLagStatus is the subset of 'replSetGetStatus' used for replication checks
+type ReplSetLagStatus struct {
+	Members []*ReplSetLagMember `bson:"members" json:"members"`
+	OpTimes *ReplSetOpTimes     `bson:"optimes,omitempty" json:"optimes,omitempty"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+func (s *ReplSetLagStatus) getSelf() *ReplSetLagMember {
+	for _, member := range s.Members {
+		if member.Self {
+			return member
+		}
+	}
+	return nil
+}
+
+func (s *ReplSetLagStatus) getPrimary() *ReplSetLagMember {
+	for _, member := range s.Members {
+		if member.State == status.MemberStatePrimary {
+			return member
+		}
+	}
+	return nil
+}
+
+// ReplicationLag returns the replication lag of this member behind the primary
+func (s *ReplSetLagStatus) ReplicationLag() (time.Duration, error) {
+	self := s.getSelf()
+	if self == nil {
+		return 0, errors.New("found no member for self in replica set status")
+	}
+	if self.State == status.MemberStatePrimary || self.State == status.MemberStateArbiter {
+		return 0, nil
+	}
+	primary := s.getPrimary()
+	if primary == nil {
+		return 0, errors.New("found no primary in replica set status")
+	}
+	if lag := primary.OptimeDate.Sub(self.OptimeDate); lag > 0 {
+		return lag, nil
+	}
+	return 0, nil
+}
+
+// MajorityCommitLag returns the amount of time the majority commit point trails the
+// last applied operation of this member
+func (s *ReplSetLagStatus) MajorityCommitLag() (time.Duration, error) {
+	if s.OpTimes == nil || s.OpTimes.LastCommittedOpTime == nil || s.OpTimes.AppliedOpTime == nil {
+		return 0, errors.New("found no optimes in replica set status")
+	}
+	lag := s.OpTimes.AppliedOpTime.Time().Sub(s.OpTimes.LastCommittedOpTime.Time())
+	if lag > 0 {
+		return lag, nil
+	}
+	return 0, nil
+}
+
+// checkLagStatus checks replication lag and majority commit lag against the configured maximums
+func (cnf *ReplicationConfig) checkLagStatus(rsStatus *ReplSetLagStatus) error {
+	if cnf.MaxLag > 0 {
+		lag, err := rsStatus.ReplicationLag()
+		if err != nil {
+			return err
+		}
+		if lag > cnf.MaxLag {
+			return fmt.Errorf("member replication lag is %s, above maximum of %s", lag, cnf.MaxLag)
+		}
+	}
+	if cnf.MaxMajorityCommitLag > 0 {
+		lag, err := rsStatus.MajorityCommitLag()
+		if err != nil {
+			return err
+		}
+		if lag > cnf.MaxMajorityCommitLag {
+			return fmt.Errorf("majority commit point is %s behind, above maximum of %s", lag, cnf.MaxMajorityCommitLag)
+		}
+	}
+	return nil
+}
+
+// ReplicationCheck checks the replication lag and majority commit point of the local MongoDB member
+func ReplicationCheck(session db.Session, cnf *ReplicationConfig) (State, error) {
+	rsStatus := &ReplSetLagStatus{}
+	if err := session.Run(bson.D{{Name: "replSetGetStatus", Value: 1}}, rsStatus); err != nil {
+		return StateFailed, fmt.Errorf("replSetGetStatus returned error %v", err)
+	}
+	if rsStatus.Ok == 0 {
+		return StateFailed, errors.New(rsStatus.Errmsg)
+	}
+	if err := cnf.checkLagStatus(rsStatus); err != nil {
+		return StateFailed, err
+	}
+	return StateOk, nil
+}
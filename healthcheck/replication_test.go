@@ -0,0 +1,108 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/timvaillancourt/go-mongodb-replset/status"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func testOpTime(t time.Time) *OpTime {
+	return &OpTime{Ts: bson.MongoTimestamp(t.Unix() << 32), Term: 1}
+}
+
+func TestHealthcheckReplicationConfigEnabled(t *testing.T) {
+	var cnf *ReplicationConfig
+	assert.False(t, cnf.Enabled())
+	assert.False(t, (&ReplicationConfig{}).Enabled())
+	assert.True(t, (&ReplicationConfig{MaxLag: time.Second}).Enabled())
+	assert.True(t, (&ReplicationConfig{MaxMajorityCommitLag: time.Second}).Enabled())
+}
+
+func TestHealthcheckReplSetLagStatusReplicationLag(t *testing.T) {
+	now := time.Now()
+	self := &ReplSetLagMember{Name: "host2:27017", State: status.MemberStateSecondary, OptimeDate: now.Add(-time.Minute), Self: true}
+	rsStatus := &ReplSetLagStatus{
+		Members: []*ReplSetLagMember{
+			{Name: "host1:27017", State: status.MemberStatePrimary, OptimeDate: now},
+			self,
+		},
+	}
+	lag, err := rsStatus.ReplicationLag()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, lag)
+
+	// secondary ahead of a primary optime is not lag
+	self.OptimeDate = now.Add(time.Second)
+	lag, err = rsStatus.ReplicationLag()
+	assert.NoError(t, err)
+	assert.Zero(t, lag)
+
+	rsStatus.Members[0].State = status.MemberStateSecondary
+	_, err = rsStatus.ReplicationLag()
+	assert.Error(t, err, ".ReplicationLag() should fail with no primary")
+
+	self.State = status.MemberStatePrimary
+	lag, err = rsStatus.ReplicationLag()
+	assert.NoError(t, err)
+	assert.Zero(t, lag)
+
+	self.Self = false
+	_, err = rsStatus.ReplicationLag()
+	assert.Error(t, err, ".ReplicationLag() should fail with no self member")
+}
+
+func TestHealthcheckReplicationCheckLagStatus(t *testing.T) {
+	now := time.Now()
+	cnf := &ReplicationConfig{MaxLag: 30 * time.Second, MaxMajorityCommitLag: 30 * time.Second}
+	rsStatus := &ReplSetLagStatus{
+		Members: []*ReplSetLagMember{
+			{Name: "host1:27017", State: status.MemberStatePrimary, OptimeDate: now},
+			{Name: "host2:27017", State: status.MemberStateSecondary, OptimeDate: now.Add(-10 * time.Second), Self: true},
+		},
+		OpTimes: &ReplSetOpTimes{
+			LastCommittedOpTime: testOpTime(now.Add(-10 * time.Second)),
+			AppliedOpTime:       testOpTime(now),
+		},
+	}
+	assert.NoError(t, cnf.checkLagStatus(rsStatus))
+
+	rsStatus.Members[1].OptimeDate = now.Add(-time.Minute)
+	assert.Error(t, cnf.checkLagStatus(rsStatus), ".checkLagStatus() should fail on high replication lag")
+	rsStatus.Members[1].OptimeDate = now
+
+	rsStatus.OpTimes.LastCommittedOpTime = testOpTime(now.Add(-time.Minute))
+	assert.Error(t, cnf.checkLagStatus(rsStatus), ".checkLagStatus() should fail on high majority commit lag")
+
+	cnf.MaxMajorityCommitLag = 0
+	assert.NoError(t, cnf.checkLagStatus(rsStatus), ".checkLagStatus() should skip a disabled majority commit check")
+
+	rsStatus.OpTimes = nil
+	cnf.MaxMajorityCommitLag = time.Second
+	assert.Error(t, cnf.checkLagStatus(rsStatus), ".checkLagStatus() should fail with no optimes")
+}
+
+func TestHealthcheckReplicationCheck(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	state, err := ReplicationCheck(testDBSession, &ReplicationConfig{MaxLag: time.Minute, MaxMajorityCommitLag: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, StateOk, state)
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/timvaillancourt/go-mongodb-replset/status"
+)
+
+var (
+	DefaultStartupStallTimeout = "10m"
+	DefaultStartupStateFile    = filepath.Join(os.TempDir(), "mongodb-healthcheck-startup.json")
+)
+
+// StartupProgress is a snapshot of initial sync progress, persisted between startup check runs
+type StartupProgress struct {
+	DatabasesToClone int64     `json:"databasesToClone"`
+	DatabasesCloned  int64     `json:"databasesCloned"`
+	BytesToCopy      int64     `json:"bytesToCopy"`
+	BytesCopied      int64     `json:"bytesCopied"`
+	AppliedOps       int64     `json:"appliedOps"`
+	FailedAttempts   int64     `json:"failedAttempts"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Progress returns a StartupProgress snapshot of the initial sync status
+func (s *InitialSyncStatus) Progress() *StartupProgress {
+	return &StartupProgress{
+		DatabasesToClone: s.Databases.DatabasesToClone,
+		DatabasesCloned:  s.Databases.DatabasesCloned,
+		BytesToCopy:      s.ApproxTotalDataSize,
+		BytesCopied:      s.ApproxTotalBytesCopied,
+		AppliedOps:       s.AppliedOps,
+		FailedAttempts:   s.FailedInitialSyncAttempts,
+	}
+}
+
+// hasAdvanced returns true if the initial sync made progress since the 'prev' snapshot
+func (p *StartupProgress) hasAdvanced(prev *StartupProgress) bool {
+	return p.DatabasesCloned != prev.DatabasesCloned ||
+		p.BytesCopied != prev.BytesCopied ||
+		p.AppliedOps != prev.AppliedOps ||
+		p.FailedAttempts != prev.FailedAttempts
+}
+
+// Fields returns the progress as log fields
+func (p *StartupProgress) Fields() log.Fields {
+	return log.Fields{
+		"databases_cloned":   p.DatabasesCloned,
+		"databases_to_clone": p.DatabasesToClone,
+		"bytes_copied":       p.BytesCopied,
+		"bytes_to_copy":      p.BytesToCopy,
+		"applied_ops":        p.AppliedOps,
+		"failed_attempts":    p.FailedAttempts,
+	}
+}
+
+func loadStartupProgress(file string) (*StartupProgress, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	progress := &StartupProgress{}
+	if err := json.Unmarshal(bytes, progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (p *StartupProgress) save(file string) error {
+	bytes, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, bytes, 0600)
+}
+
+// checkStartupProgress compares the current initial sync progress to the previous
+// snapshot, returning the snapshot to be persisted. An error is returned if no
+// progress was made for longer than 'stallTimeout'
+func checkStartupProgress(prev, cur *StartupProgress, now time.Time, stallTimeout time.Duration) (*StartupProgress, error) {
+	if prev == nil || cur.hasAdvanced(prev) {
+		cur.UpdatedAt = now
+		return cur, nil
+	}
+	cur.UpdatedAt = prev.UpdatedAt
+	if stalled := now.Sub(prev.UpdatedAt); stalled > stallTimeout {
+		return cur, fmt.Errorf("initial sync made no progress for %s", stalled)
+	}
+	return cur, nil
+}
+
+// HealthCheckMongodStartup checks the startup of a mongod, failing only if an initial
+// sync stops making progress for longer than 'stallTimeout'. Progress is persisted to
+// 'stateFile' between runs of the check
+func HealthCheckMongodStartup(session db.Session, stateFile string, stallTimeout time.Duration) (*status.MemberState, *StartupProgress, error) {
+	rsStatus, err := getReplSetStatus(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rsStatus.Ok == 0 {
+		return nil, nil, errors.New(rsStatus.Errmsg)
+	}
+
+	switch rsStatus.MyState {
+	case status.MemberStatePrimary, status.MemberStateSecondary, status.MemberStateArbiter:
+		if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).Warnf("Cannot remove startup state file %s", stateFile)
+		}
+		return &rsStatus.MyState, nil, nil
+	case status.MemberStateStartup, status.MemberStateStartup2:
+		if rsStatus.InitialSyncStatus == nil {
+			return &rsStatus.MyState, nil, nil
+		}
+	case status.MemberStateRecovering:
+		return &rsStatus.MyState, nil, nil
+	default:
+		return &rsStatus.MyState, nil, fmt.Errorf("invalid state %s", rsStatus.MyState)
+	}
+
+	prev, err := loadStartupProgress(stateFile)
+	if err != nil {
+		log.WithError(err).Warnf("Cannot load startup state file %s, resetting progress", stateFile)
+	}
+
+	progress, err := checkStartupProgress(prev, rsStatus.InitialSyncStatus.Progress(), time.Now(), stallTimeout)
+	if saveErr := progress.save(stateFile); saveErr != nil {
+		log.WithError(saveErr).Warnf("Cannot save startup state file %s", stateFile)
+	}
+	return &rsStatus.MyState, progress, err
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthcheckInitialSyncStatusProgress(t *testing.T) {
+	progress := (&InitialSyncStatus{
+		FailedInitialSyncAttempts: 1,
+		AppliedOps:                100,
+		ApproxTotalDataSize:       2048,
+		ApproxTotalBytesCopied:    1024,
+		Databases: InitialSyncDatabases{
+			DatabasesToClone: 3,
+			DatabasesCloned:  2,
+		},
+	}).Progress()
+	assert.Equal(t, int64(1), progress.FailedAttempts)
+	assert.Equal(t, int64(100), progress.AppliedOps)
+	assert.Equal(t, int64(2048), progress.BytesToCopy)
+	assert.Equal(t, int64(1024), progress.BytesCopied)
+	assert.Equal(t, int64(3), progress.DatabasesToClone)
+	assert.Equal(t, int64(2), progress.DatabasesCloned)
+}
+
+func TestHealthcheckCheckStartupProgress(t *testing.T) {
+	now := time.Now()
+	stallTimeout := 10 * time.Minute
+
+	// first run
+	progress, err := checkStartupProgress(nil, &StartupProgress{BytesCopied: 1}, now, stallTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, now, progress.UpdatedAt)
+
+	// progress made
+	later := now.Add(time.Hour)
+	progress, err = checkStartupProgress(progress, &StartupProgress{BytesCopied: 2}, later, stallTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, later, progress.UpdatedAt)
+
+	// no progress, within stall timeout
+	progress, err = checkStartupProgress(progress, &StartupProgress{BytesCopied: 2}, later.Add(time.Minute), stallTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, later, progress.UpdatedAt)
+
+	// no progress, past stall timeout
+	_, err = checkStartupProgress(progress, &StartupProgress{BytesCopied: 2}, later.Add(stallTimeout+time.Second), stallTimeout)
+	assert.Error(t, err)
+}
+
+func TestHealthcheckStartupProgressSaveLoad(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", t.Name())
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	stateFile := filepath.Join(tmpDir, "startup.json")
+
+	progress, err := loadStartupProgress(stateFile)
+	assert.NoError(t, err, "missing state file should not return an error")
+	assert.Nil(t, progress)
+
+	saved := &StartupProgress{DatabasesCloned: 2, UpdatedAt: time.Now().UTC().Round(time.Second)}
+	assert.NoError(t, saved.save(stateFile))
+	progress, err = loadStartupProgress(stateFile)
+	assert.NoError(t, err)
+	assert.Equal(t, saved.DatabasesCloned, progress.DatabasesCloned)
+	assert.True(t, saved.UpdatedAt.Equal(progress.UpdatedAt))
+
+	assert.NoError(t, ioutil.WriteFile(stateFile, []byte("not json"), 0600))
+	progress, err = loadStartupProgress(stateFile)
+	assert.Error(t, err)
+	assert.Nil(t, progress)
+}
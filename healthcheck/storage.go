@@ -0,0 +1,253 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	DefaultStorageDbPath               = "/data/db"
+	DefaultStorageMinFreePercent       = "10"
+	DefaultStorageMinFreeInodesPercent = "5"
+	DefaultStorageMaxCacheUsedPercent  = "95"
+	DefaultStorageMaxCacheDirtyPercent = "20"
+)
+
+// StorageConfig is the configuration of the optional storage check
+type StorageConfig struct {
+	Enabled              bool
+	DbPath               string
+	MinFreePercent       float64
+	MinFreeInodesPercent float64
+	MaxCacheUsedPercent  float64
+	MaxCacheDirtyPercent float64
+}
+
+// NewStorageConfig returns a StorageConfig with command-line flags registered on 'app'
+func NewStorageConfig(app *kingpin.Application) *StorageConfig {
+	cnf := &StorageConfig{}
+	app.Flag(
+		"storage.check",
+		"enable checks of the mongod storage.dbPath filesystem and WiredTiger cache as part of readiness checks",
+	).BoolVar(&cnf.Enabled)
+	app.Flag(
+		"storage.dbPath",
+		"path to the mongod storage.dbPath, defaults to the value reported by 'getCmdLineOpts'",
+	).StringVar(&cnf.DbPath)
+	app.Flag(
+		"storage.minFreePercent",
+		"minimum percentage of free space on the storage.dbPath filesystem",
+	).Default(DefaultStorageMinFreePercent).Float64Var(&cnf.MinFreePercent)
+	app.Flag(
+		"storage.minFreeInodesPercent",
+		"minimum percentage of free inodes on the storage.dbPath filesystem",
+	).Default(DefaultStorageMinFreeInodesPercent).Float64Var(&cnf.MinFreeInodesPercent)
+	app.Flag(
+		"storage.maxCacheUsedPercent",
+		"maximum percentage of the WiredTiger cache in use, 0 disables the check",
+	).Default(DefaultStorageMaxCacheUsedPercent).Float64Var(&cnf.MaxCacheUsedPercent)
+	app.Flag(
+		"storage.maxCacheDirtyPercent",
+		"maximum percentage of the WiredTiger cache that is dirty, 0 disables the check",
+	).Default(DefaultStorageMaxCacheDirtyPercent).Float64Var(&cnf.MaxCacheDirtyPercent)
+	return cnf
+}
+
+// WiredTigerCacheStatus is the 'wiredTiger.cache' document of 'serverStatus'
+type WiredTigerCacheStatus struct {
+	MaxBytes   int64 `bson:"maximum bytes configured" json:"maximum bytes configured"`
+	UsedBytes  int64 `bson:"bytes currently in the cache" json:"bytes currently in the cache"`
+	DirtyBytes int64 `bson:"tracked dirty bytes in the cache" json:"tracked dirty bytes in the cache"`
+}
+
+type WiredTigerStatus struct {
+	Cache *WiredTigerCacheStatus `bson:"cache" json:"cache"`
+}
+
+type StorageServerStatus struct {
+	WiredTiger *WiredTigerStatus `bson:"wiredTiger,omitempty" json:"wiredTiger,omitempty"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+type CmdLineOpts struct {
+	Parsed struct {
+		Storage struct {
+			DbPath string `bson:"dbPath" json:"dbPath"`
+		} `bson:"storage" json:"storage"`
+	} `bson:"parsed" json:"parsed"`
+
+	Ok     int    `bson:"ok" json:"ok"`
+	Errmsg string `bson:"errmsg,omitempty" json:"errmsg,omitempty"`
+}
+
+// StorageStats are the filesystem and WiredTiger cache stats of a mongod
+type StorageStats struct {
+	DbPath      string
+	TotalBytes  uint64
+	FreeBytes   uint64
+	TotalInodes uint64
+	FreeInodes  uint64
+	Cache       *WiredTigerCacheStatus
+}
+
+func percent(part, total float64) float64 {
+	if total <= 0 {
+		return 100
+	}
+	return part / total * 100
+}
+
+// FreePercent returns the percentage of free space on the filesystem
+func (s *StorageStats) FreePercent() float64 {
+	return percent(float64(s.FreeBytes), float64(s.TotalBytes))
+}
+
+// FreeInodesPercent returns the percentage of free inodes on the filesystem
+func (s *StorageStats) FreeInodesPercent() float64 {
+	return percent(float64(s.FreeInodes), float64(s.TotalInodes))
+}
+
+// CacheUsedPercent returns the percentage of the WiredTiger cache in use
+func (s *StorageStats) CacheUsedPercent() float64 {
+	if s.Cache == nil || s.Cache.MaxBytes <= 0 {
+		return 0
+	}
+	return percent(float64(s.Cache.UsedBytes), float64(s.Cache.MaxBytes))
+}
+
+// CacheDirtyPercent returns the percentage of the WiredTiger cache that is dirty
+func (s *StorageStats) CacheDirtyPercent() float64 {
+	if s.Cache == nil || s.Cache.MaxBytes <= 0 {
+		return 0
+	}
+	return percent(float64(s.Cache.DirtyBytes), float64(s.Cache.MaxBytes))
+}
+
+// Fields returns the storage stats as log fields
+func (s *StorageStats) Fields() log.Fields {
+	return log.Fields{
+		"db_path":             s.DbPath,
+		"free_percent":        s.FreePercent(),
+		"free_inodes_percent": s.FreeInodesPercent(),
+		"cache_used_percent":  s.CacheUsedPercent(),
+		"cache_dirty_percent": s.CacheDirtyPercent(),
+	}
+}
+
+func getDbPath(session db.Session) (string, error) {
+	resp := CmdLineOpts{}
+	if err := session.Run(bson.D{{Name: "getCmdLineOpts", Value: 1}}, &resp); err != nil {
+		return "", fmt.Errorf("getCmdLineOpts returned error %v", err)
+	}
+	if resp.Ok == 0 {
+		return "", errors.New(resp.Errmsg)
+	}
+	if resp.Parsed.Storage.DbPath == "" {
+		return DefaultStorageDbPath, nil
+	}
+	return resp.Parsed.Storage.DbPath, nil
+}
+
+func getWiredTigerCacheStatus(session db.Session) (*WiredTigerCacheStatus, error) {
+	resp := StorageServerStatus{}
+	if err := session.Run(bson.D{{Name: "serverStatus", Value: 1}}, &resp); err != nil {
+		return nil, fmt.Errorf("serverStatus returned error %v", err)
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	if resp.WiredTiger == nil {
+		return nil, nil
+	}
+	return resp.WiredTiger.Cache, nil
+}
+
+// getFilesystemStats returns the filesystem stats for the filesystem containing 'path'
+func getFilesystemStats(path string) (*StorageStats, error) {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	blockSize := uint64(stat.Bsize)
+	return &StorageStats{
+		DbPath:      path,
+		TotalBytes:  stat.Blocks * blockSize,
+		FreeBytes:   stat.Bavail * blockSize,
+		TotalInodes: stat.Files,
+		FreeInodes:  stat.Ffree,
+	}, nil
+}
+
+// checkStats checks storage stats against the configured thresholds
+func (cnf *StorageConfig) checkStats(stats *StorageStats) error {
+	if free := stats.FreePercent(); free < cnf.MinFreePercent {
+		return fmt.Errorf("free space on %s is %.2f%%, below minimum of %.2f%%", stats.DbPath, free, cnf.MinFreePercent)
+	}
+	// some filesystems (eg: btrfs) do not report inodes
+	if stats.TotalInodes > 0 {
+		if free := stats.FreeInodesPercent(); free < cnf.MinFreeInodesPercent {
+			return fmt.Errorf("free inodes on %s is %.2f%%, below minimum of %.2f%%", stats.DbPath, free, cnf.MinFreeInodesPercent)
+		}
+	}
+	if cnf.MaxCacheUsedPercent > 0 {
+		if used := stats.CacheUsedPercent(); used > cnf.MaxCacheUsedPercent {
+			return fmt.Errorf("wiredTiger cache usage is %.2f%%, above maximum of %.2f%%", used, cnf.MaxCacheUsedPercent)
+		}
+	}
+	if cnf.MaxCacheDirtyPercent > 0 {
+		if dirty := stats.CacheDirtyPercent(); dirty > cnf.MaxCacheDirtyPercent {
+			return fmt.Errorf("wiredTiger cache dirty is %.2f%%, above maximum of %.2f%%", dirty, cnf.MaxCacheDirtyPercent)
+		}
+	}
+	return nil
+}
+
+// StorageCheck checks the free space and inodes of the storage.dbPath filesystem and
+// the WiredTiger cache pressure of the local mongod
+func StorageCheck(session db.Session, cnf *StorageConfig) (State, *StorageStats, error) {
+	dbPath := cnf.DbPath
+	if dbPath == "" {
+		var err error
+		dbPath, err = getDbPath(session)
+		if err != nil {
+			return StateFailed, nil, err
+		}
+	}
+
+	stats, err := getFilesystemStats(dbPath)
+	if err != nil {
+		return StateFailed, nil, fmt.Errorf("failed to get filesystem stats for %s: %v", dbPath, err)
+	}
+
+	stats.Cache, err = getWiredTigerCacheStatus(session)
+	if err != nil {
+		return StateFailed, stats, err
+	}
+
+	if err := cnf.checkStats(stats); err != nil {
+		return StateFailed, stats, err
+	}
+	return StateOk, stats, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthcheckNewStorageConfig(t *testing.T) {
+	app := kingpin.New(t.Name(), t.Name())
+	cnf := NewStorageConfig(app)
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.False(t, cnf.Enabled)
+	assert.Equal(t, "", cnf.DbPath)
+	assert.Equal(t, float64(10), cnf.MinFreePercent)
+	assert.Equal(t, float64(5), cnf.MinFreeInodesPercent)
+	assert.Equal(t, float64(95), cnf.MaxCacheUsedPercent)
+	assert.Equal(t, float64(20), cnf.MaxCacheDirtyPercent)
+}
+
+func TestHealthcheckStorageStats(t *testing.T) {
+	stats := &StorageStats{
+		TotalBytes:  1000,
+		FreeBytes:   250,
+		TotalInodes: 100,
+		FreeInodes:  50,
+	}
+	assert.Equal(t, float64(25), stats.FreePercent())
+	assert.Equal(t, float64(50), stats.FreeInodesPercent())
+	assert.Equal(t, float64(0), stats.CacheUsedPercent())
+
+	stats.Cache = &WiredTigerCacheStatus{MaxBytes: 200, UsedBytes: 100, DirtyBytes: 10}
+	assert.Equal(t, float64(50), stats.CacheUsedPercent())
+	assert.Equal(t, float64(5), stats.CacheDirtyPercent())
+}
+
+func TestHealthcheckStorageCheckStats(t *testing.T) {
+	cnf := &StorageConfig{
+		MinFreePercent:       10,
+		MinFreeInodesPercent: 5,
+		MaxCacheUsedPercent:  95,
+		MaxCacheDirtyPercent: 20,
+	}
+	stats := &StorageStats{
+		TotalBytes:  100,
+		FreeBytes:   50,
+		TotalInodes: 100,
+		FreeInodes:  50,
+		Cache:       &WiredTigerCacheStatus{MaxBytes: 100, UsedBytes: 80, DirtyBytes: 5},
+	}
+	assert.NoError(t, cnf.checkStats(stats))
+
+	stats.FreeBytes = 5
+	assert.Error(t, cnf.checkStats(stats), ".checkStats() should fail on low free space")
+	stats.FreeBytes = 50
+
+	stats.FreeInodes = 1
+	assert.Error(t, cnf.checkStats(stats), ".checkStats() should fail on low free inodes")
+	stats.TotalInodes = 0
+	assert.NoError(t, cnf.checkStats(stats), ".checkStats() should skip inodes when not reported")
+	stats.TotalInodes = 100
+	stats.FreeInodes = 50
+
+	stats.Cache.UsedBytes = 99
+	assert.Error(t, cnf.checkStats(stats), ".checkStats() should fail on high cache usage")
+	cnf.MaxCacheUsedPercent = 0
+	assert.NoError(t, cnf.checkStats(stats), ".checkStats() should skip a disabled cache usage check")
+
+	stats.Cache.DirtyBytes = 30
+	assert.Error(t, cnf.checkStats(stats), ".checkStats() should fail on high dirty cache")
+}
+
+func TestHealthcheckGetFilesystemStats(t *testing.T) {
+	stats, err := getFilesystemStats(os.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, os.TempDir(), stats.DbPath)
+	assert.NotZero(t, stats.TotalBytes)
+
+	_, err = getFilesystemStats("/does/not/exist")
+	assert.Error(t, err)
+}
+
+func TestHealthcheckStorageCheck(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	cnf := &StorageConfig{DbPath: os.TempDir()}
+	state, stats, err := StorageCheck(testDBSession, cnf)
+	assert.NoError(t, err)
+	assert.Equal(t, StateOk, state)
+	assert.NotNil(t, stats)
+
+	cnf.MinFreePercent = 101
+	state, _, err = StorageCheck(testDBSession, cnf)
+	assert.Error(t, err)
+	assert.Equal(t, StateFailed, state)
+}
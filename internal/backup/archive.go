@@ -0,0 +1,56 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidArchiveName = errors.New("invalid backup archive name")
+
+const (
+	archiveSuffix = ".archive.gz"
+	archiveTime   = "20060102T150405Z"
+)
+
+// ArchiveName returns the name of a backup archive of replset 'rsName' started at 't'
+func ArchiveName(rsName string, t time.Time) string {
+	return rsName + "-" + t.UTC().Format(archiveTime) + archiveSuffix
+}
+
+// FilterArchives returns the names of the backup archives of replset 'rsName' in 'names'
+func FilterArchives(names []string, rsName string) []string {
+	archives := make([]string, 0)
+	for _, name := range names {
+		if strings.HasPrefix(name, rsName+"-") && strings.HasSuffix(name, archiveSuffix) {
+			archives = append(archives, name)
+		}
+	}
+	return archives
+}
+
+// ParseArchiveTime returns the start time of a backup archive of replset 'rsName' named by ArchiveName
+func ParseArchiveTime(name, rsName string) (time.Time, error) {
+	if !strings.HasPrefix(name, rsName+"-") || !strings.HasSuffix(name, archiveSuffix) {
+		return time.Time{}, ErrInvalidArchiveName
+	}
+	t, err := time.Parse(archiveTime, strings.TrimSuffix(strings.TrimPrefix(name, rsName+"-"), archiveSuffix))
+	if err != nil {
+		return time.Time{}, ErrInvalidArchiveName
+	}
+	return t, nil
+}
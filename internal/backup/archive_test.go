@@ -0,0 +1,54 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalBackupArchiveName(t *testing.T) {
+	assert.Equal(t, "rs-20190102T030405Z.archive.gz", ArchiveName("rs", time.Date(2019, time.January, 2, 3, 4, 5, 0, time.UTC)))
+}
+
+func TestInternalBackupFilterArchives(t *testing.T) {
+	assert.Equal(t, []string{
+		"rs-20190101T000000Z.archive.gz",
+		"rs-20190102T000000Z.archive.gz",
+	}, FilterArchives([]string{
+		"other-20190101T000000Z.archive.gz",
+		"rs-20190101T000000Z.archive.gz",
+		"rs-20190102T000000Z.archive.gz",
+		"rs-oplog-1-2.bson.gz",
+	}, "rs"))
+}
+
+func TestInternalBackupParseArchiveTime(t *testing.T) {
+	archiveTime := time.Date(2019, time.January, 2, 3, 4, 5, 0, time.UTC)
+	parsed, err := ParseArchiveTime(ArchiveName("my-rs", archiveTime), "my-rs")
+	assert.NoError(t, err)
+	assert.Equal(t, archiveTime, parsed)
+
+	for _, name := range []string{
+		"other-20190102T030405Z.archive.gz",
+		"my-rs-20190102.archive.gz",
+		"my-rs-oplog-1546398245.0000000003-1546398845.0000000012.bson.gz",
+	} {
+		_, err = ParseArchiveTime(name, "my-rs")
+		assert.Equal(t, ErrInvalidArchiveName, err, name)
+	}
+}
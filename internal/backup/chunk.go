@@ -0,0 +1,112 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const chunkSuffix = ".bson.gz"
+
+var (
+	ErrInvalidChunkName = errors.New("invalid oplog chunk name")
+	chunkNameRegexp     = regexp.MustCompile(`^(.+)-oplog-(\d{10})\.(\d{10})-(\d{10})\.(\d{10})\.bson\.gz$`)
+)
+
+// Chunk is a compressed file of oplog entries in a backup Storage, the entries
+// have timestamps from Start to End, inclusive
+type Chunk struct {
+	Name        string
+	ReplsetName string
+	Start       bson.MongoTimestamp
+	End         bson.MongoTimestamp
+}
+
+// NewTimestamp returns a bson.MongoTimestamp from unix seconds and an ordinal
+func NewTimestamp(secs, ordinal uint32) bson.MongoTimestamp {
+	return bson.MongoTimestamp(int64(secs)<<32 | int64(ordinal))
+}
+
+// TimestampTime returns the time.Time of a bson.MongoTimestamp
+func TimestampTime(ts bson.MongoTimestamp) time.Time {
+	return time.Unix(int64(ts>>32), 0).UTC()
+}
+
+// formatTimestamp returns a sortable string of a bson.MongoTimestamp
+func formatTimestamp(ts bson.MongoTimestamp) string {
+	return fmt.Sprintf("%010d.%010d", uint32(ts>>32), uint32(ts))
+}
+
+// ChunkName returns the name of a chunk of replset 'rsName' oplog entries from 'start' to 'end'
+func ChunkName(rsName string, start, end bson.MongoTimestamp) string {
+	return rsName + "-oplog-" + formatTimestamp(start) + "-" + formatTimestamp(end) + chunkSuffix
+}
+
+func parseTimestamp(secs, ordinal string) (bson.MongoTimestamp, error) {
+	s, err := strconv.ParseUint(secs, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	o, err := strconv.ParseUint(ordinal, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return NewTimestamp(uint32(s), uint32(o)), nil
+}
+
+// ParseChunkName returns the Chunk of a name returned by ChunkName
+func ParseChunkName(name string) (*Chunk, error) {
+	match := chunkNameRegexp.FindStringSubmatch(name)
+	if match == nil {
+		return nil, ErrInvalidChunkName
+	}
+	start, err := parseTimestamp(match[2], match[3])
+	if err != nil {
+		return nil, ErrInvalidChunkName
+	}
+	end, err := parseTimestamp(match[4], match[5])
+	if err != nil || end < start {
+		return nil, ErrInvalidChunkName
+	}
+	return &Chunk{
+		Name:        name,
+		ReplsetName: match[1],
+		Start:       start,
+		End:         end,
+	}, nil
+}
+
+// FilterChunks returns the oplog chunks of replset 'rsName' in 'names', sorted by start timestamp
+func FilterChunks(names []string, rsName string) []*Chunk {
+	chunks := make([]*Chunk, 0)
+	for _, name := range names {
+		chunk, err := ParseChunkName(name)
+		if err != nil || chunk.ReplsetName != rsName {
+			continue
+		}
+		chunks = append(chunks, chunk)
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Start < chunks[j].Start
+	})
+	return chunks
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestInternalBackupTimestamp(t *testing.T) {
+	ts := NewTimestamp(1546398245, 3)
+	assert.Equal(t, bson.MongoTimestamp(1546398245<<32|3), ts)
+	assert.Equal(t, time.Date(2019, time.January, 2, 3, 4, 5, 0, time.UTC), TimestampTime(ts))
+	assert.Equal(t, "1546398245.0000000003", formatTimestamp(ts))
+}
+
+func TestInternalBackupChunkName(t *testing.T) {
+	start := NewTimestamp(1546398245, 3)
+	end := NewTimestamp(1546398845, 12)
+	name := ChunkName("rs", start, end)
+	assert.Equal(t, "rs-oplog-1546398245.0000000003-1546398845.0000000012.bson.gz", name)
+
+	chunk, err := ParseChunkName(name)
+	assert.NoError(t, err)
+	assert.Equal(t, &Chunk{Name: name, ReplsetName: "rs", Start: start, End: end}, chunk)
+
+	chunk, err = ParseChunkName("my-rs-oplog-1546398245.0000000003-1546398845.0000000012.bson.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-rs", chunk.ReplsetName)
+
+	for _, name := range []string{
+		"rs-20190102T030405Z.archive.gz",
+		"rs-oplog-1546398245.0000000003.bson.gz",
+		"rs-oplog-1546398845.0000000012-1546398245.0000000003.bson.gz",
+		"rs-oplog-9999999999.0000000003-9999999999.0000000012.bson.gz",
+	} {
+		_, err = ParseChunkName(name)
+		assert.Equal(t, ErrInvalidChunkName, err, name)
+	}
+}
+
+func TestInternalBackupFilterChunks(t *testing.T) {
+	chunks := FilterChunks([]string{
+		ChunkName("rs", NewTimestamp(200, 1), NewTimestamp(300, 1)),
+		"rs-20190102T030405Z.archive.gz",
+		ChunkName("other", NewTimestamp(100, 1), NewTimestamp(200, 1)),
+		ChunkName("rs", NewTimestamp(100, 1), NewTimestamp(200, 1)),
+	}, "rs")
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, NewTimestamp(100, 1), chunks[0].Start)
+	assert.Equal(t, NewTimestamp(300, 1), chunks[1].End)
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// Storages is a slice of the supported backup storages
+var Storages = []string{
+	StorageLocal,
+	StorageS3,
+}
+
+const (
+	DefaultStorage           = StorageLocal
+	DefaultLocalPathFallback = "/tmp/backup"
+	DefaultS3Endpoint        = "https://s3.amazonaws.com"
+	DefaultS3Region          = "us-east-1"
+)
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+// StorageConfig is the configuration of the storage of backup archives, shared by
+// the executor taking backups and the controller restoring them
+type StorageConfig struct {
+	Storage   string
+	LocalPath string
+	S3        *S3Config
+}
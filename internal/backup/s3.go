@@ -0,0 +1,395 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	s3Service       = "s3"
+	s3SignAlgorithm = "AWS4-HMAC-SHA256"
+	s3EmptyHash     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	// s3PartSize is the size of the parts of multipart uploads. The minimum is 5MB and
+	// an upload has at most 10000 parts, so archives are limited to 160GB
+	s3PartSize = 16 * 1024 * 1024
+)
+
+// S3Storage is a Storage of backup archives in a bucket of an S3-compatible
+// endpoint, using path-style requests signed with AWS Signature Version 4
+type S3Storage struct {
+	config   *S3Config
+	endpoint *url.URL
+	partSize int
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Storage(config *S3Config) (*S3Storage, error) {
+	if config == nil || config.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported s3 endpoint scheme: %q", endpoint.Scheme)
+	}
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		partSize: s3PartSize,
+		client:   &http.Client{},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3Storage) Name() string {
+	return StorageS3
+}
+
+// objectKey returns the key of the object for backup 'name'
+func (s *S3Storage) objectKey(name string) string {
+	return s.prefix() + name
+}
+
+func (s *S3Storage) prefix() string {
+	prefix := strings.Trim(s.config.Prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// objectURL returns the path-style url of 'key' in the bucket
+func (s *S3Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket + "/" + key
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalQuery returns the sorted, strictly-escaped query string used by AWS signatures
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escape := func(s string) string {
+		return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+	}
+	pairs := make([]string, 0)
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, escape(key)+"="+escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sign adds AWS Signature Version 4 headers to 'req' for a payload with SHA256 hash 'payloadHash'
+func (s *S3Storage) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + s.config.Region + "/" + s3Service + "/aws4_request"
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		s3SignAlgorithm,
+		amzDate,
+		scope,
+		hashSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), amzDate[:8])
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgorithm, s.config.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// do sends a signed request, returning an error for non-2xx responses
+func (s *S3Storage) do(ctx context.Context, method string, u *url.URL, body io.ReadSeeker, size int64, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Body = ioutil.NopCloser(body)
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s returned status %d: %s", method, u.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// s3Error is the error document of a request. A CompleteMultipartUpload request may
+// return it with a 200 status
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// doBytes sends a signed request with the payload 'data'
+func (s *S3Storage) doBytes(ctx context.Context, method string, u *url.URL, data []byte) (*http.Response, error) {
+	hash := sha256.Sum256(data)
+	return s.do(ctx, method, u, bytes.NewReader(data), int64(len(data)), hex.EncodeToString(hash[:]))
+}
+
+// createMultipartUpload starts a multipart upload of 'key', returning the upload id
+func (s *S3Storage) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := s.do(ctx, http.MethodPost, s.objectURL(key, url.Values{"uploads": []string{""}}), nil, 0, s3EmptyHash)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	result := &s3InitiateMultipartUploadResult{}
+	err = xml.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", errors.New("s3 multipart upload returned no upload id")
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads 'data' as part 'number' of the multipart upload, returning the ETag of the part
+func (s *S3Storage) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{
+		"partNumber": []string{strconv.Itoa(number)},
+		"uploadId":   []string{uploadID},
+	}
+	resp, err := s.doBytes(ctx, http.MethodPut, s.objectURL(key, query), data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// completeMultipartUpload assembles the uploaded 'parts' into the object 'key'
+func (s *S3Storage) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []s3CompletedPart) error {
+	data, err := xml.Marshal(&s3CompleteMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.doBytes(ctx, http.MethodPost, s.objectURL(key, url.Values{"uploadId": []string{uploadID}}), data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	result := &s3Error{}
+	if xml.Unmarshal(body, result) == nil {
+		return fmt.Errorf("s3 multipart upload of %s failed: %s: %s", key, result.Code, result.Message)
+	}
+	return nil
+}
+
+// abortMultipartUpload removes the uploaded parts of a failed multipart upload. It does
+// not use the context of the upload, which may be done
+func (s *S3Storage) abortMultipartUpload(key, uploadID string) {
+	resp, err := s.do(context.Background(), http.MethodDelete, s.objectURL(key, url.Values{"uploadId": []string{uploadID}}), nil, 0, s3EmptyHash)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"key":   key,
+			"error": err,
+		}).Error("Cannot abort s3 multipart upload")
+		return
+	}
+	resp.Body.Close()
+}
+
+// Put streams 'r' to the bucket as a multipart upload, buffering one part at a time.
+// The upload is aborted if it fails
+func (s *S3Storage) Put(ctx context.Context, name string, r io.Reader) (int64, error) {
+	key := s.objectKey(name)
+	uploadID, err := s.createMultipartUpload(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	parts := []s3CompletedPart{}
+	buffer := make([]byte, s.partSize)
+	for {
+		n, err := io.ReadFull(r, buffer)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			s.abortMultipartUpload(key, uploadID)
+			return 0, err
+		}
+		// an empty archive is uploaded as a single empty part
+		if n > 0 || len(parts) == 0 {
+			etag, err := s.uploadPart(ctx, key, uploadID, len(parts)+1, buffer[:n])
+			if err != nil {
+				s.abortMultipartUpload(key, uploadID)
+				return 0, err
+			}
+			parts = append(parts, s3CompletedPart{PartNumber: len(parts) + 1, ETag: etag})
+			size += int64(n)
+		}
+		if last {
+			break
+		}
+	}
+
+	err = s.completeMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		s.abortMultipartUpload(key, uploadID)
+		return 0, err
+	}
+	return size, nil
+}
+
+// Get opens the backup archive 'name' in the bucket, returning a reader and the size of the archive
+func (s *S3Storage) Get(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(s.objectKey(name), nil), nil, 0, s3EmptyHash)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the sorted names of the backup archives under the prefix of the bucket
+func (s *S3Storage) List(ctx context.Context) ([]string, error) {
+	names := make([]string, 0)
+	query := url.Values{
+		"list-type": []string{"2"},
+		"prefix":    []string{s.prefix()},
+	}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, s.objectURL("", query), nil, 0, s3EmptyHash)
+		if err != nil {
+			return nil, err
+		}
+		result := &s3ListBucketResult{}
+		err = xml.NewDecoder(resp.Body).Decode(result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			name := strings.TrimPrefix(content.Key, s.prefix())
+			if name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			names = append(names, name)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(s.objectKey(name), nil), nil, 0, s3EmptyHash)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
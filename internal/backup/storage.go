@@ -0,0 +1,127 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const tmpSuffix = ".tmp"
+
+// Storage is an interface for storing backup archives by name
+type Storage interface {
+	Name() string
+	Put(ctx context.Context, name string, r io.Reader) (int64, error)
+	Get(ctx context.Context, name string) (io.ReadCloser, int64, error)
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// NewStorage returns the Storage configured by config.Storage
+func NewStorage(config *StorageConfig) (Storage, error) {
+	switch config.Storage {
+	case StorageLocal:
+		return NewLocalStorage(config.LocalPath), nil
+	case StorageS3:
+		return NewS3Storage(config.S3)
+	default:
+		return nil, fmt.Errorf("unsupported backup storage: %q", config.Storage)
+	}
+}
+
+// LocalStorage is a Storage of backup archives in a local directory
+type LocalStorage struct {
+	path string
+}
+
+func NewLocalStorage(path string) *LocalStorage {
+	return &LocalStorage{path: path}
+}
+
+func (s *LocalStorage) Name() string {
+	return StorageLocal
+}
+
+// Put writes 'r' to a temporary file that is renamed to 'name' once complete, so
+// incomplete archives are never listed
+func (s *LocalStorage) Put(ctx context.Context, name string, r io.Reader) (int64, error) {
+	err := os.MkdirAll(s.path, 0750)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := ioutil.TempFile(s.path, name+".*"+tmpSuffix)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+
+	size, err := io.Copy(file, r)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return size, os.Rename(file.Name(), filepath.Join(s.path, name))
+}
+
+// Get opens the backup archive 'name', returning a reader and the size of the archive
+func (s *LocalStorage) Get(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(filepath.Join(s.path, name))
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// List returns the sorted names of the backup archives in the directory
+func (s *LocalStorage) List(ctx context.Context) ([]string, error) {
+	files, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, file := range files {
+		if !file.Mode().IsRegular() || strings.HasSuffix(file.Name(), tmpSuffix) {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(s.path, name))
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type errReader struct{}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read error")
+}
+
+func TestInternalBackupNewStorage(t *testing.T) {
+	storage, err := NewStorage(&StorageConfig{Storage: StorageLocal, LocalPath: "/tmp"})
+	assert.NoError(t, err)
+	assert.Equal(t, StorageLocal, storage.Name())
+
+	storage, err = NewStorage(&StorageConfig{Storage: StorageS3, S3: &S3Config{Endpoint: "http://localhost:9000", Bucket: "test"}})
+	assert.NoError(t, err)
+	assert.Equal(t, StorageS3, storage.Name())
+
+	_, err = NewStorage(&StorageConfig{Storage: "ftp"})
+	assert.Error(t, err)
+}
+
+func TestInternalBackupLocalStorage(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	storage := NewLocalStorage(filepath.Join(dir, "backup"))
+	names, err := storage.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, names, 0, ".List() should return no backups if the path does not exist")
+
+	size, err := storage.Put(ctx, "rs-2.archive.gz", strings.NewReader("archive2"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), size)
+	_, err = storage.Put(ctx, "rs-1.archive.gz", strings.NewReader("archive1"))
+	assert.NoError(t, err)
+
+	reader, size, err := storage.Get(ctx, "rs-2.archive.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), size)
+	data, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "archive2", string(data))
+	_, _, err = storage.Get(ctx, "does-not-exist")
+	assert.Error(t, err)
+
+	// failed writes are not listed or left behind
+	_, err = storage.Put(ctx, "rs-3.archive.gz", errReader{})
+	assert.Error(t, err)
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "backup"))
+	assert.Len(t, files, 2)
+
+	names, err = storage.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rs-1.archive.gz", "rs-2.archive.gz"}, names)
+
+	assert.NoError(t, storage.Delete(ctx, "rs-1.archive.gz"))
+	names, _ = storage.List(ctx)
+	assert.Equal(t, []string{"rs-2.archive.gz"}, names)
+	assert.Error(t, storage.Delete(ctx, "rs-1.archive.gz"))
+}
@@ -16,6 +16,7 @@ package command
 
 import (
 	"errors"
+	"io"
 	"os"
 	"os/exec"
 	"os/user"
@@ -23,6 +24,7 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/percona/mongodb-orchestration-tools/internal"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -33,6 +35,15 @@ type Command struct {
 	User  *user.User
 	Group *user.Group
 
+	// Env are extra 'key=value' environment variables of the command, added to
+	// the executor's own environment. Secrets go here rather than in Args
+	Env []string
+
+	// Stdout and Stderr are the output sinks of a started command, the
+	// executor's own os.Stdout and os.Stderr are used if nil
+	Stdout io.Writer
+	Stderr io.Writer
+
 	command *exec.Cmd
 	running bool
 }
@@ -93,19 +104,43 @@ func (c *Command) prepare() error {
 	return nil
 }
 
+// redactedArgs returns the command arguments with the passwords of mongodb connection
+// strings redacted, for logging
+func (c *Command) redactedArgs() []string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = internal.RedactUri(arg)
+	}
+	return args
+}
+
+// setEnv adds the Env variables to the environment of the command
+func (c *Command) setEnv() {
+	if len(c.Env) > 0 {
+		c.command.Env = append(os.Environ(), c.Env...)
+	}
+}
+
 func (c *Command) Start() error {
 	c.Lock()
 	defer c.Unlock()
 
 	log.WithFields(log.Fields{
 		"command": c.Bin,
-		"args":    c.Args,
+		"args":    c.redactedArgs(),
 		"user":    c.User.Name,
 		"group":   c.Group.Name,
 	}).Debug("Starting command")
 
+	c.setEnv()
 	c.command.Stdout = os.Stdout
+	if c.Stdout != nil {
+		c.command.Stdout = c.Stdout
+	}
 	c.command.Stderr = os.Stderr
+	if c.Stderr != nil {
+		c.command.Stderr = c.Stderr
+	}
 
 	err := c.command.Start()
 	if err != nil {
@@ -119,36 +154,43 @@ func (c *Command) Start() error {
 func (c *Command) CombinedOutput() ([]byte, error) {
 	log.WithFields(log.Fields{
 		"command": c.Bin,
-		"args":    c.Args,
+		"args":    c.redactedArgs(),
 		"user":    c.User.Name,
 		"group":   c.Group.Name,
 	}).Debug("Running command")
 
+	c.setEnv()
 	return c.command.CombinedOutput()
 }
 
 func (c *Command) Run() error {
 	log.WithFields(log.Fields{
 		"command": c.Bin,
-		"args":    c.Args,
+		"args":    c.redactedArgs(),
 		"user":    c.User.Name,
 		"group":   c.Group.Name,
 	}).Debug("Running command")
 
+	c.setEnv()
 	return c.command.Run()
 }
 
+// Wait waits for the started command to exit and for its output to be copied to
+// the Stdout and Stderr sinks. A non-zero exit is not an error, it is in the
+// returned state
 func (c *Command) Wait() (*os.ProcessState, error) {
 	if c.IsRunning() {
-		state, err := c.command.Process.Wait()
-		if err != nil {
-			return nil, err
-		}
+		err := c.command.Wait()
 
+		// the process is gone, whether it exited or was killed by a signal
 		c.Lock()
-		defer c.Unlock()
-		c.running = !state.Exited()
-		return state, nil
+		c.running = false
+		c.Unlock()
+
+		if _, isExitErr := err.(*exec.ExitError); err != nil && !isExitErr {
+			return nil, err
+		}
+		return c.command.ProcessState, nil
 	}
 	return nil, errors.New("not running")
 }
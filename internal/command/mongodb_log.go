@@ -0,0 +1,122 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const fatalAssertionMsg = "Fatal assertion"
+
+// MongoDBLogEntry is a structured JSON log line of MongoDB 4.4+
+type MongoDBLogEntry struct {
+	Timestamp struct {
+		Date string `json:"$date"`
+	} `json:"t"`
+	Severity  string                 `json:"s"`
+	Component string                 `json:"c"`
+	ID        int                    `json:"id"`
+	Context   string                 `json:"ctx"`
+	Message   string                 `json:"msg"`
+	Attr      map[string]interface{} `json:"attr,omitempty"`
+}
+
+// IsFatalAssertion returns true if the entry is a MongoDB fatal assertion
+func (e *MongoDBLogEntry) IsFatalAssertion() bool {
+	return strings.HasPrefix(e.Message, fatalAssertionMsg)
+}
+
+// MongoDBLogParser parses MongoDB 4.4+ structured JSON log lines, re-emitting
+// warnings, errors and fatal entries as logrus entries and counting entries by
+// severity. Lines that are not JSON are ignored
+type MongoDBLogParser struct {
+	EntriesTotal         *prometheus.CounterVec
+	FatalAssertionsTotal prometheus.Counter
+}
+
+func NewMongoDBLogParser() *MongoDBLogParser {
+	return &MongoDBLogParser{
+		EntriesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mongodb",
+			Subsystem: "log",
+			Name:      "entries_total",
+			Help:      "The total number of MongoDB structured log entries by severity",
+		}, []string{"severity"}),
+		FatalAssertionsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "mongodb",
+			Subsystem: "log",
+			Name:      "fatal_assertions_total",
+			Help:      "The total number of MongoDB fatal assertions",
+		}),
+	}
+}
+
+// NewWriter returns an io.Writer parsing the lines of a single output stream
+func (p *MongoDBLogParser) NewWriter() io.Writer {
+	return newLineWriter(p.handleLine)
+}
+
+func (p *MongoDBLogParser) handleLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return nil
+	}
+	entry := &MongoDBLogEntry{}
+	if json.Unmarshal(line, entry) != nil || entry.Severity == "" {
+		return nil
+	}
+	p.handleEntry(entry)
+	return nil
+}
+
+func (p *MongoDBLogParser) handleEntry(entry *MongoDBLogEntry) {
+	p.EntriesTotal.WithLabelValues(entry.Severity).Inc()
+	if entry.IsFatalAssertion() {
+		p.FatalAssertionsTotal.Inc()
+	}
+
+	fields := log.Fields{
+		"severity":  entry.Severity,
+		"component": entry.Component,
+		"id":        entry.ID,
+		"ctx":       entry.Context,
+	}
+	for key, val := range entry.Attr {
+		fields["attr."+key] = val
+	}
+	logger := log.WithFields(fields)
+	switch entry.Severity {
+	case "F", "E":
+		logger.Error(entry.Message)
+	case "W":
+		logger.Warn(entry.Message)
+	}
+}
+
+func (p *MongoDBLogParser) Collect(ch chan<- prometheus.Metric) {
+	p.EntriesTotal.Collect(ch)
+	p.FatalAssertionsTotal.Collect(ch)
+}
+
+func (p *MongoDBLogParser) Describe(ch chan<- *prometheus.Desc) {
+	p.EntriesTotal.Describe(ch)
+	p.FatalAssertionsTotal.Describe(ch)
+}
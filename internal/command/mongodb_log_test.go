@@ -0,0 +1,53 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// counterValue returns the value of a single counter
+func counterValue(t *testing.T, c prometheus.Collector) float64 {
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	metric := &dto.Metric{}
+	assert.NoError(t, (<-ch).Write(metric))
+	return metric.Counter.GetValue()
+}
+
+func TestInternalCommandMongoDBLogParser(t *testing.T) {
+	parser := NewMongoDBLogParser()
+	writer := parser.NewWriter()
+
+	writer.Write([]byte(`{"t":{"$date":"2020-08-01T10:00:00.000+00:00"},"s":"I","c":"NETWORK","id":23016,"ctx":"listener","msg":"Waiting for connections","attr":{"port":27017}}` + "\n"))
+	writer.Write([]byte("2018-08-01T10:00:00.000+0000 I CONTROL  [initandlisten] not json\n"))
+	writer.Write([]byte(`{"t":{"$date":"2020-08-01T10:00:01.000+00:00"},"s":"F","c":"-","id":23089,"ctx":"conn1","msg":"Fatal assertion","attr":{"msgid":40507,"file":"src/mongo/db/repl/rs_rollback.cpp","line":1460}}` + "\n"))
+	writer.Write([]byte(`{"t":{"$date":"2020-08-01T10:00:02.000+00:00"},"s":"W",`))
+	writer.Write([]byte(`"c":"STORAGE","id":22120,"ctx":"initandlisten","msg":"Access control is not enabled for the database"}` + "\n"))
+
+	assert.Equal(t, float64(1), counterValue(t, parser.EntriesTotal.WithLabelValues("I")))
+	assert.Equal(t, float64(1), counterValue(t, parser.EntriesTotal.WithLabelValues("F")))
+	assert.Equal(t, float64(1), counterValue(t, parser.EntriesTotal.WithLabelValues("W")))
+	assert.Equal(t, float64(1), counterValue(t, parser.FatalAssertionsTotal))
+}
+
+func TestInternalCommandMongoDBLogEntryIsFatalAssertion(t *testing.T) {
+	assert.True(t, (&MongoDBLogEntry{Message: "Fatal assertion"}).IsFatalAssertion())
+	assert.False(t, (&MongoDBLogEntry{Message: "Waiting for connections"}).IsFatalAssertion())
+}
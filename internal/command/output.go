@@ -0,0 +1,191 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxLineBytes is the size of the longest line a lineWriter buffers, longer
+// lines are split
+const maxLineBytes = 1024 * 1024
+
+// lineWriter is an io.Writer calling 'handle' for every complete line written
+type lineWriter struct {
+	sync.Mutex
+	buffer   []byte
+	maxBytes int
+	handle   func(line []byte) error
+}
+
+func newLineWriter(handle func(line []byte) error) *lineWriter {
+	return &lineWriter{
+		maxBytes: maxLineBytes,
+		handle:   handle,
+	}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	for {
+		var line []byte
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i >= 0 && i < w.maxBytes {
+			line = w.buffer[:i+1]
+			w.buffer = w.buffer[i+1:]
+		} else if len(w.buffer) >= w.maxBytes {
+			// a line without a newline, eg: binary output, must not grow the buffer
+			// without limit, so it is split in lines of 'maxBytes'
+			line = append(w.buffer[:w.maxBytes:w.maxBytes], '\n')
+			w.buffer = w.buffer[w.maxBytes:]
+		} else {
+			break
+		}
+		err := w.handle(line)
+		if err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// NewPrefixWriter returns an io.Writer that writes every line to 'out' with 'prefix'
+func NewPrefixWriter(out io.Writer, prefix string) io.Writer {
+	return newLineWriter(func(line []byte) error {
+		_, err := out.Write(append([]byte(prefix), line...))
+		return err
+	})
+}
+
+// bestEffortWriter is an io.Writer that never returns an error, so a failing sink of
+// an io.MultiWriter cannot stop the output to the other sinks
+type bestEffortWriter struct {
+	sync.Mutex
+	name   string
+	out    io.Writer
+	failed bool
+}
+
+// NewBestEffortWriter returns an io.Writer to 'out' that drops the output 'out' fails
+// to write, logging the first error only
+func NewBestEffortWriter(name string, out io.Writer) io.Writer {
+	return &bestEffortWriter{name: name, out: out}
+}
+
+func (w *bestEffortWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	_, err := w.out.Write(p)
+	if err != nil && !w.failed {
+		log.WithFields(log.Fields{
+			"sink":  w.name,
+			"error": err,
+		}).Error("Cannot write command output, dropping output of the sink")
+		w.failed = true
+	}
+	return len(p), nil
+}
+
+// RotatingFile is an io.WriteCloser to a file that is rotated when it exceeds a
+// maximum size, keeping a number of backups suffixed .1 (newest) to .N (oldest)
+type RotatingFile struct {
+	sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile opens or creates the file at 'path' for appending
+func NewRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	return r, r.open()
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = stat.Size()
+	return nil
+}
+
+func (r *RotatingFile) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
+
+// rotate renames the current file to the newest backup, removing the oldest backup
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	if err != nil {
+		return err
+	}
+	if r.maxBackups > 0 {
+		os.Remove(r.backupPath(r.maxBackups))
+		for n := r.maxBackups - 1; n >= 1; n-- {
+			os.Rename(r.backupPath(n), r.backupPath(n+1))
+		}
+		err = os.Rename(r.path, r.backupPath(1))
+	} else {
+		err = os.Remove(r.path)
+	}
+	if err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.file.Close()
+}
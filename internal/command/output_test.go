@@ -0,0 +1,104 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalCommandPrefixWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	writer := NewPrefixWriter(out, "[mongod] ")
+	writer.Write([]byte("line 1\nline"))
+	assert.Equal(t, "[mongod] line 1\n", out.String(), "partial lines should be buffered")
+	writer.Write([]byte(" 2\n"))
+	assert.Equal(t, "[mongod] line 1\n[mongod] line 2\n", out.String())
+
+	// lines longer than the max size are split
+	out.Reset()
+	writer.(*lineWriter).maxBytes = 4
+	writer.Write([]byte("abcdefghij"))
+	assert.Equal(t, "[mongod] abcd\n[mongod] efgh\n", out.String())
+	assert.Equal(t, "ij", string(writer.(*lineWriter).buffer))
+	writer.Write([]byte("\nabcdefg\n"))
+	assert.Equal(t, "[mongod] abcd\n[mongod] efgh\n[mongod] ij\n[mongod] abcd\n[mongod] efg\n", out.String())
+}
+
+// failingWriter is an io.Writer that always fails
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("disk full")
+}
+
+func TestInternalCommandBestEffortWriter(t *testing.T) {
+	out := &failingWriter{}
+	writer := NewBestEffortWriter("test", out)
+	for i := 0; i < 2; i++ {
+		n, err := writer.Write([]byte("line\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 5, n)
+	}
+	assert.Equal(t, 2, out.writes)
+	assert.True(t, writer.(*bestEffortWriter).failed)
+}
+
+func TestInternalCommandRotatingFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mongod.log")
+
+	file, err := NewRotatingFile(path, 10, 2)
+	assert.NoError(t, err)
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		n, err := file.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	assert.NoError(t, file.Close())
+
+	for name, expected := range map[string]string{
+		"mongod.log":   "dddddddd\n",
+		"mongod.log.1": "cccccccc\n",
+		"mongod.log.2": "bbbbbbbb\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(data), name)
+	}
+	_, err = os.Stat(filepath.Join(dir, "mongod.log.3"))
+	assert.True(t, os.IsNotExist(err), "the oldest backup should be removed")
+
+	// existing files are appended to
+	file, err = NewRotatingFile(path, 100, 2)
+	assert.NoError(t, err)
+	file.Write([]byte("eeeeeeee\n"))
+	file.Close()
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "dddddddd\neeeeeeee\n", string(data))
+
+	_, err = NewRotatingFile(filepath.Join(dir, "missing", "mongod.log"), 10, 2)
+	assert.Error(t, err)
+}
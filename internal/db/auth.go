@@ -0,0 +1,197 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/percona/mongodb-orchestration-tools/pkg"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AuthMechanismScramSHA1   = "SCRAM-SHA-1"
+	AuthMechanismScramSHA256 = "SCRAM-SHA-256"
+	AuthMechanismX509        = "MONGODB-X509"
+
+	// authSourceExternal is the auth database of users authenticated externally, eg: x.509
+	authSourceExternal = "$external"
+)
+
+var (
+	DefaultAuthFallback = "true"
+
+	// AuthFallbacksTotal counts the sessions connected without authentication after
+	// their authentication failed
+	AuthFallbacksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mongodb",
+		Subsystem: "session",
+		Name:      "auth_fallbacks_total",
+		Help:      "The total number of sessions connected without authentication after an authentication failure",
+	}, []string{"driver"})
+
+	ErrX509NoClientCertificate = errors.New("the " + AuthMechanismX509 + " auth mechanism requires SSL and a client PEM key file")
+	ErrAuthMechanismMgo        = errors.New("the " + AuthMechanismScramSHA256 + " auth mechanism requires the '" + string(DriverMongoDriver) + "' driver")
+)
+
+// AuthMechanisms returns the supported auth mechanisms
+func AuthMechanisms() []string {
+	return []string{AuthMechanismScramSHA1, AuthMechanismScramSHA256, AuthMechanismX509}
+}
+
+// NewAuthMechanismFlag registers the flag selecting the auth mechanism of sessions on 'app'
+func NewAuthMechanismFlag(app *kingpin.Application, mechanism *string) {
+	app.Flag(
+		"authMechanism",
+		"mongodb auth mechanism ("+strings.Join(AuthMechanisms(), ", ")+"), the server default is used if not set. "+
+			AuthMechanismScramSHA256+" requires --driver="+string(DriverMongoDriver)+", "+
+			AuthMechanismX509+" uses the subject of the --sslPEMKeyFile certificate as username, overridden by env var "+pkg.EnvMongoDBAuthMechanism,
+	).Envar(pkg.EnvMongoDBAuthMechanism).EnumVar(mechanism, AuthMechanisms()...)
+}
+
+// NewAuthFallbackFlag registers the flag enabling the unauthenticated retry of sessions
+// that fail to authenticate on 'app'
+func NewAuthFallbackFlag(app *kingpin.Application, fallback *bool) {
+	app.Flag(
+		"authFallback",
+		"retry without authentication when authentication fails, eg: before users are created during the localhost exception. Disable with --no-authFallback, overridden by env var "+pkg.EnvMongoDBAuthFallback,
+	).Default(DefaultAuthFallback).Envar(pkg.EnvMongoDBAuthFallback).BoolVar(fallback)
+}
+
+// AuthError is the authentication failure of a session
+type AuthError struct {
+	Username  string
+	Source    string
+	Mechanism string
+	Hosts     []string
+	Err       error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication of user %s on database %s to %s failed: %s", e.Username, e.Source, strings.Join(e.Hosts, ","), e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthError returns the *AuthError of 'err', false if it is not an authentication failure
+func IsAuthError(err error) (*AuthError, bool) {
+	for _, e := range errorChain(err) {
+		if authErr, ok := e.(*AuthError); ok {
+			return authErr, true
+		}
+	}
+	return nil, false
+}
+
+func (cnf *Config) newAuthError(err error) *AuthError {
+	return &AuthError{
+		Username:  cnf.DialInfo.Username,
+		Source:    cnf.DialInfo.Source,
+		Mechanism: cnf.DialInfo.Mechanism,
+		Hosts:     cnf.DialInfo.Addrs,
+		Err:       err,
+	}
+}
+
+// withoutAuth returns a copy of the config without credentials, for the unauthenticated
+// retry of a session that failed to authenticate. The config keeps its credentials, so
+// its next sessions authenticate again, eg: once the users are created
+func (cnf *Config) withoutAuth() *Config {
+	noAuth := cnf.copy()
+	noAuth.DialInfo.Username = ""
+	noAuth.DialInfo.Password = ""
+	noAuth.DialInfo.Mechanism = ""
+	return noAuth
+}
+
+// logAuthFallback records a session connected without authentication after 'authErr'
+func logAuthFallback(driver Driver, authErr *AuthError) {
+	log.WithFields(log.Fields{
+		"user":   authErr.Username,
+		"source": authErr.Source,
+		"hosts":  authErr.Hosts,
+		"driver": driver,
+	}).Warnf("Authentication failed, connected without authentication: %s", authErr.Err)
+	AuthFallbacksTotal.With(prometheus.Labels{"driver": string(driver)}).Inc()
+}
+
+// IsX509Auth returns true if the config uses x.509 certificate authentication
+func (cnf *Config) IsX509Auth() bool {
+	return cnf.DialInfo != nil && cnf.DialInfo.Mechanism == AuthMechanismX509
+}
+
+// HasCredentials returns true if the config has credentials to authenticate with
+func (cnf *Config) HasCredentials() bool {
+	if cnf.IsX509Auth() {
+		return true
+	}
+	return cnf.DialInfo.Username != "" && cnf.DialInfo.Password != ""
+}
+
+// X509Username returns the subject of the client certificate in 'pemKeyFile', in the
+// RFC 2253 format expected as the username of x.509 users
+func X509Username(pemKeyFile string) (string, error) {
+	keyPair, err := tls.LoadX509KeyPair(pemKeyFile, pemKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot load client certificate %s: %s", pemKeyFile, err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return "", fmt.Errorf("cannot parse client certificate %s: %s", pemKeyFile, err)
+	}
+
+	// use the raw subject to keep the order of the certificate attributes
+	var subject pkix.RDNSequence
+	_, err = asn1.Unmarshal(cert.RawSubject, &subject)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse client certificate subject %s: %s", pemKeyFile, err)
+	}
+	return subject.String(), nil
+}
+
+// configureAuth checks the auth mechanism is usable with 'driver' and, for x.509,
+// sets the username from the client certificate subject
+func (cnf *Config) configureAuth(driver Driver) error {
+	switch cnf.DialInfo.Mechanism {
+	case AuthMechanismScramSHA256:
+		if driver != DriverMongoDriver {
+			return ErrAuthMechanismMgo
+		}
+	case AuthMechanismX509:
+		if cnf.SSL == nil || !cnf.SSL.Enabled || cnf.SSL.PEMKeyFile == "" {
+			return ErrX509NoClientCertificate
+		}
+		if cnf.DialInfo.Username == "" {
+			username, err := X509Username(cnf.SSL.PEMKeyFile)
+			if err != nil {
+				return err
+			}
+			cnf.DialInfo.Username = username
+		}
+		cnf.DialInfo.Source = authSourceExternal
+		cnf.DialInfo.Password = ""
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CertTypeClient = "client"
+	CertTypeCA     = "ca"
+	CertTypePeer   = "peer"
+)
+
+var (
+	// certManagers are the certificate managers of the SSL configs, keyed by the files
+	// they load so connections using the same files share the reloaded certificates
+	certManagers   = map[string]*CertManager{}
+	certManagersMu sync.Mutex
+)
+
+// CertExpiry is the expiry of a certificate used by, or presented to, sessions
+type CertExpiry struct {
+	Type     string
+	Name     string
+	Subject  string
+	NotAfter time.Time
+}
+
+// DaysUntilExpiry returns the number of days until the certificate expires, negative
+// if it has expired
+func (ce CertExpiry) DaysUntilExpiry() float64 {
+	return time.Until(ce.NotAfter).Hours() / 24
+}
+
+type certFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func getCertFileState(file string) (certFileState, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return certFileState{}, err
+	}
+	return certFileState{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// CertManager holds the client certificate and certificate authorities of an SSL
+// config, reloading them when their files change. The client certificate is served
+// via tls.Config.GetClientCertificate so reloads apply to existing tls.Configs, the
+// certificate authorities apply to tls.Configs created after a reload
+type CertManager struct {
+	sync.Mutex
+	pemKeyFile string
+	caFile     string
+	fileStates map[string]certFileState
+	cert       *tls.Certificate
+	certLeaf   *x509.Certificate
+	caPool     *x509.CertPool
+	caCerts    []*x509.Certificate
+	peers      map[string]*x509.Certificate
+}
+
+// NewCertManager returns a CertManager with the certificates of 'sc' loaded
+func NewCertManager(sc *SSLConfig) (*CertManager, error) {
+	cm := &CertManager{
+		pemKeyFile: sc.PEMKeyFile,
+		caFile:     sc.CAFile,
+		fileStates: map[string]certFileState{},
+		peers:      map[string]*x509.Certificate{},
+	}
+	_, err := cm.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// CertManager returns the CertManager shared by all connections using the files of
+// the SSL config
+func (sc *SSLConfig) CertManager() (*CertManager, error) {
+	certManagersMu.Lock()
+	defer certManagersMu.Unlock()
+
+	key := sc.PEMKeyFile + "|" + sc.CAFile
+	if cm, ok := certManagers[key]; ok {
+		return cm, nil
+	}
+	cm, err := NewCertManager(sc)
+	if err != nil {
+		return nil, err
+	}
+	certManagers[key] = cm
+	return cm, nil
+}
+
+// CertManagers returns the CertManagers of the SSL configs used by sessions
+func CertManagers() []*CertManager {
+	certManagersMu.Lock()
+	defer certManagersMu.Unlock()
+
+	managers := []*CertManager{}
+	for _, cm := range certManagers {
+		managers = append(managers, cm)
+	}
+	return managers
+}
+
+// fileChanged returns true if 'file' changed since it was last loaded
+func (cm *CertManager) fileChanged(file string) (bool, certFileState, error) {
+	state, err := getCertFileState(file)
+	if err != nil {
+		return false, state, err
+	}
+	last, ok := cm.fileStates[file]
+	return !ok || last != state, state, nil
+}
+
+func (cm *CertManager) loadClientCertificate() error {
+	changed, state, err := cm.fileChanged(cm.pemKeyFile)
+	if err != nil || !changed {
+		return err
+	}
+	log.Debugf("Loading SSL/TLS PEM certificate: %s", cm.pemKeyFile)
+	cert, err := tls.LoadX509KeyPair(cm.pemKeyFile, cm.pemKeyFile)
+	if err != nil {
+		return fmt.Errorf("Cannot load key pair from '%s'. Got: %v", cm.pemKeyFile, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("Cannot parse certificate from '%s'. Got: %v", cm.pemKeyFile, err)
+	}
+	if cm.cert != nil {
+		log.WithFields(log.Fields{
+			"file":      cm.pemKeyFile,
+			"subject":   leaf.Subject.String(),
+			"not_after": leaf.NotAfter,
+		}).Info("Reloaded SSL/TLS PEM certificate")
+	}
+	cm.cert = &cert
+	cm.certLeaf = leaf
+	cm.fileStates[cm.pemKeyFile] = state
+	return nil
+}
+
+func (cm *CertManager) loadCaCertificates() error {
+	changed, state, err := cm.fileChanged(cm.caFile)
+	if err != nil || !changed {
+		return err
+	}
+	log.Debugf("Loading SSL/TLS Certificate Authority: %s", cm.caFile)
+	caPEM, err := ioutil.ReadFile(cm.caFile)
+	if err != nil {
+		return fmt.Errorf("Couldn't load client CAs from %s. Got: %s", cm.caFile, err)
+	}
+	caCerts := []*x509.Certificate{}
+	for block, rest := pem.Decode(caPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("Couldn't parse client CAs from %s. Got: %s", cm.caFile, err)
+		}
+		caCerts = append(caCerts, cert)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range caCerts {
+		pool.AddCert(cert)
+	}
+	if cm.caPool != nil {
+		log.WithFields(log.Fields{
+			"file":         cm.caFile,
+			"certificates": len(caCerts),
+		}).Info("Reloaded SSL/TLS Certificate Authority")
+	}
+	cm.caPool = pool
+	cm.caCerts = caCerts
+	cm.fileStates[cm.caFile] = state
+	return nil
+}
+
+// Reload reloads the certificate files that changed since they were last loaded,
+// returning true if any were reloaded. On error the loaded certificates are kept
+func (cm *CertManager) Reload() (bool, error) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	loaded := cm.cert != nil || cm.caPool != nil
+	cert, caPool := cm.cert, cm.caPool
+	if cm.pemKeyFile != "" {
+		err := cm.loadClientCertificate()
+		if err != nil {
+			return false, err
+		}
+	}
+	if cm.caFile != "" {
+		err := cm.loadCaCertificates()
+		if err != nil {
+			return false, err
+		}
+	}
+	return loaded && (cert != cm.cert || caPool != cm.caPool), nil
+}
+
+// GetClientCertificate returns the loaded client certificate, for use as
+// tls.Config.GetClientCertificate
+func (cm *CertManager) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cm.Lock()
+	defer cm.Unlock()
+
+	if cm.cert == nil {
+		// an empty certificate sends no certificate to the server
+		return &tls.Certificate{}, nil
+	}
+	return cm.cert, nil
+}
+
+// RootCAs returns the loaded certificate authorities, nil if no CA file is used
+func (cm *CertManager) RootCAs() *x509.CertPool {
+	cm.Lock()
+	defer cm.Unlock()
+	return cm.caPool
+}
+
+// recordPeer records the certificate presented by the server 'host'
+func (cm *CertManager) recordPeer(host string, cert *x509.Certificate) {
+	cm.Lock()
+	defer cm.Unlock()
+	cm.peers[host] = cert
+}
+
+// verifyPeerCertificate records the certificate presented by a server, for use as
+// tls.Config.VerifyPeerCertificate of drivers dialing their own connections. The
+// callback is not given the host, so the server is named by its certificate.
+// Verification of the chain is left to crypto/tls
+func (cm *CertManager) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return nil
+	}
+	name := cert.Subject.CommonName
+	if len(cert.DNSNames) > 0 {
+		name = cert.DNSNames[0]
+	}
+	cm.recordPeer(name, cert)
+	return nil
+}
+
+// TLSConfig returns a *tls.Config using the loaded certificates
+func (cm *CertManager) TLSConfig(insecure bool) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify:    insecure,
+		RootCAs:               cm.RootCAs(),
+		GetClientCertificate:  cm.GetClientCertificate,
+		VerifyPeerCertificate: cm.verifyPeerCertificate,
+	}
+}
+
+// Expiries returns the expiry of the client certificate, the certificate authorities
+// and the certificates presented by servers, sorted by expiry
+func (cm *CertManager) Expiries() []CertExpiry {
+	cm.Lock()
+	defer cm.Unlock()
+
+	expiries := []CertExpiry{}
+	if cm.certLeaf != nil {
+		expiries = append(expiries, CertExpiry{
+			Type:     CertTypeClient,
+			Name:     cm.pemKeyFile,
+			Subject:  cm.certLeaf.Subject.String(),
+			NotAfter: cm.certLeaf.NotAfter,
+		})
+	}
+	for _, cert := range cm.caCerts {
+		expiries = append(expiries, CertExpiry{
+			Type:     CertTypeCA,
+			Name:     cm.caFile,
+			Subject:  cert.Subject.String(),
+			NotAfter: cert.NotAfter,
+		})
+	}
+	for host, cert := range cm.peers {
+		expiries = append(expiries, CertExpiry{
+			Type:     CertTypePeer,
+			Name:     host,
+			Subject:  cert.Subject.String(),
+			NotAfter: cert.NotAfter,
+		})
+	}
+	sort.Slice(expiries, func(i, j int) bool {
+		return expiries[i].NotAfter.Before(expiries[j].NotAfter)
+	})
+	return expiries
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key to 'file'
+func writeTestCertificate(t *testing.T, file, commonName string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...)
+	assert.NoError(t, ioutil.WriteFile(file, data, 0600))
+}
+
+func TestInternalDBCertManager(t *testing.T) {
+	cm, err := NewCertManager(&SSLConfig{PEMKeyFile: sslCertFile, CAFile: sslCAFile})
+	assert.NoError(t, err, ".NewCertManager() should not return an error")
+	assert.NotNil(t, cm.RootCAs())
+
+	cert, err := cm.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cert.Certificate, ".GetClientCertificate() should return the loaded certificate")
+
+	expiries := cm.Expiries()
+	assert.Len(t, expiries, 2)
+	for _, expiry := range expiries {
+		assert.Contains(t, []string{CertTypeClient, CertTypeCA}, expiry.Type)
+		assert.NotEmpty(t, expiry.Subject)
+		assert.False(t, expiry.NotAfter.IsZero())
+	}
+
+	_, err = NewCertManager(&SSLConfig{PEMKeyFile: "/does/not/exist.pem"})
+	assert.Error(t, err, ".NewCertManager() should return an error when given missing path")
+
+	// a manager without files sends no client certificate
+	cm, err = NewCertManager(&SSLConfig{})
+	assert.NoError(t, err)
+	cert, err = cm.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, cert.Certificate)
+	assert.Len(t, cm.Expiries(), 0)
+}
+
+func TestInternalDBCertManagerReload(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(tmpDir)
+
+	pemKeyFile := filepath.Join(tmpDir, "client.pem")
+	writeTestCertificate(t, pemKeyFile, "before", time.Now().Add(240*time.Hour))
+
+	cm, err := NewCertManager(&SSLConfig{PEMKeyFile: pemKeyFile})
+	assert.NoError(t, err)
+	before, _ := cm.GetClientCertificate(nil)
+	assert.Equal(t, "CN=before", cm.Expiries()[0].Subject)
+	assert.InDelta(t, 10, cm.Expiries()[0].DaysUntilExpiry(), 0.01)
+
+	reloaded, err := cm.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, ".Reload() should not reload unchanged files")
+
+	// replace the certificate, as a certificate rotation would
+	writeTestCertificate(t, pemKeyFile, "after", time.Now().Add(480*time.Hour))
+	assert.NoError(t, os.Chtimes(pemKeyFile, time.Now(), time.Now().Add(time.Minute)))
+	reloaded, err = cm.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded, ".Reload() should reload changed files")
+	after, _ := cm.GetClientCertificate(nil)
+	assert.NotEqual(t, before.Certificate, after.Certificate)
+	assert.Equal(t, "CN=after", cm.Expiries()[0].Subject)
+
+	// an invalid file keeps the loaded certificate
+	assert.NoError(t, ioutil.WriteFile(pemKeyFile, []byte("invalid"), 0600))
+	_, err = cm.Reload()
+	assert.Error(t, err)
+	kept, _ := cm.GetClientCertificate(nil)
+	assert.Equal(t, after, kept)
+}
+
+func TestInternalDBSSLConfigCertManager(t *testing.T) {
+	cm, err := (&SSLConfig{PEMKeyFile: sslCertFile}).CertManager()
+	assert.NoError(t, err)
+	cm2, err := (&SSLConfig{Enabled: true, PEMKeyFile: sslCertFile}).CertManager()
+	assert.NoError(t, err)
+	assert.True(t, cm == cm2, ".CertManager() should share managers of the same files")
+	assert.Contains(t, CertManagers(), cm)
+}
+
+func TestInternalDBCertExpiryDaysUntilExpiry(t *testing.T) {
+	assert.InDelta(t, 10, CertExpiry{NotAfter: time.Now().Add(240 * time.Hour)}.DaysUntilExpiry(), 0.01)
+	assert.True(t, CertExpiry{NotAfter: time.Now().Add(-time.Hour)}.DaysUntilExpiry() < 0)
+}
+
+func TestInternalDBCertManagerPeers(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(tmpDir)
+	serverPEMFile := filepath.Join(tmpDir, "server.pem")
+	writeTestCertificate(t, serverPEMFile, "server", time.Now().Add(time.Hour))
+	serverCert, err := tls.LoadX509KeyPair(serverPEMFile, serverPEMFile)
+	assert.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// mgo connections record the certificate with the address of the server
+	cnf := &Config{
+		DialInfo: &mgo.DialInfo{
+			Addrs:    []string{listener.Addr().String()},
+			Timeout:  500 * time.Millisecond,
+			FailFast: true,
+		},
+		SSL: &SSLConfig{Enabled: true, Insecure: true, PEMKeyFile: serverPEMFile},
+	}
+	_, err = GetSession(cnf)
+	assert.Error(t, err, "the test server does not speak the mongodb protocol")
+	cm, err := cnf.SSL.CertManager()
+	assert.NoError(t, err)
+	peers := map[string]string{}
+	for _, expiry := range cm.Expiries() {
+		if expiry.Type == CertTypePeer {
+			peers[expiry.Name] = expiry.Subject
+		}
+	}
+	assert.Equal(t, "CN=server", peers[listener.Addr().String()])
+
+	// other drivers record the certificate with its DNS name
+	assert.NoError(t, cm.verifyPeerCertificate([][]byte{serverCert.Certificate[0]}, nil))
+	for _, expiry := range cm.Expiries() {
+		if expiry.Type == CertTypePeer && expiry.Name == "localhost" {
+			assert.Equal(t, "CN=server", expiry.Subject)
+			return
+		}
+	}
+	t.Error(".verifyPeerCertificate() should record the certificate with its DNS name")
+}
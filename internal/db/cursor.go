@@ -0,0 +1,173 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type cursorResp struct {
+	Cursor struct {
+		Id         int64      `bson:"id"`
+		Ns         string     `bson:"ns"`
+		FirstBatch []bson.Raw `bson:"firstBatch"`
+		NextBatch  []bson.Raw `bson:"nextBatch"`
+	} `bson:"cursor"`
+
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+// Cursor iterates the documents of a command returning a cursor, eg: 'find' or
+// 'aggregate', using 'getMore' to fetch the next batches. It only uses server commands
+// so it works with all the drivers of Session
+type Cursor struct {
+	session      Session
+	dbName       string
+	collection   string
+	id           int64
+	batch        []bson.Raw
+	maxAwaitTime time.Duration
+	timeout      bool
+	err          error
+}
+
+// NewCursor runs the command 'cmd' on the database 'dbName' and returns a Cursor of its
+// results. The 'getMore' commands of tailable 'awaitData' cursors wait up to
+// 'maxAwaitTime' for new documents, if it is set
+func NewCursor(session Session, dbName string, cmd bson.D, maxAwaitTime time.Duration) (*Cursor, error) {
+	resp := &cursorResp{}
+	err := session.RunOn(dbName, cmd, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	cursor := &Cursor{
+		session:      session,
+		dbName:       dbName,
+		id:           resp.Cursor.Id,
+		batch:        resp.Cursor.FirstBatch,
+		maxAwaitTime: maxAwaitTime,
+	}
+	// the namespace is '<db>.<collection>', collection names may contain dots
+	if split := strings.SplitN(resp.Cursor.Ns, ".", 2); len(split) == 2 {
+		cursor.collection = split[1]
+	}
+	return cursor, nil
+}
+
+// FindOne runs the 'find' command 'cmd' on the database 'dbName', unmarshalling the
+// first document into 'result'. ErrNotFound is returned if there is no document
+func FindOne(session Session, dbName string, cmd bson.D, result interface{}) error {
+	cursor, err := NewCursor(session, dbName, append(cmd,
+		bson.DocElem{Name: "limit", Value: 1},
+		bson.DocElem{Name: "singleBatch", Value: true},
+	), 0)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	if cursor.Next(result) {
+		return nil
+	} else if cursor.Err() != nil {
+		return cursor.Err()
+	}
+	return ErrNotFound
+}
+
+func (c *Cursor) getMore() error {
+	cmd := bson.D{
+		{Name: "getMore", Value: c.id},
+		{Name: "collection", Value: c.collection},
+	}
+	if c.maxAwaitTime > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "maxTimeMS", Value: int64(c.maxAwaitTime / time.Millisecond)})
+	}
+	resp := &cursorResp{}
+	err := c.session.RunOn(c.dbName, cmd, resp)
+	if err != nil {
+		return err
+	}
+	if resp.Ok == 0 {
+		return errors.New(resp.Errmsg)
+	}
+	c.id = resp.Cursor.Id
+	c.batch = resp.Cursor.NextBatch
+	return nil
+}
+
+// Next unmarshals the next document into 'result', a *bson.Raw 'result' is set to the
+// document as is. It returns false once the cursor is exhausted, on errors or if a
+// tailable cursor did not receive a new document in time, see Err and Timeout
+func (c *Cursor) Next(result interface{}) bool {
+	c.timeout = false
+	if len(c.batch) == 0 {
+		if c.id == 0 || c.err != nil {
+			return false
+		}
+		c.err = c.getMore()
+		if c.err != nil {
+			return false
+		}
+		if len(c.batch) == 0 {
+			// tailable cursors return empty batches until new documents are inserted
+			c.timeout = c.id != 0
+			return false
+		}
+	}
+
+	doc := c.batch[0]
+	c.batch = c.batch[1:]
+	if raw, ok := result.(*bson.Raw); ok {
+		*raw = doc
+		return true
+	}
+	c.err = doc.Unmarshal(result)
+	return c.err == nil
+}
+
+// Err returns the error of the last Next call, if any
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Timeout returns true if the last Next call of a tailable cursor returned no document
+// because none was inserted in time, the cursor is still usable
+func (c *Cursor) Timeout() bool {
+	return c.timeout
+}
+
+// Close kills the cursor on the server if it is not exhausted
+func (c *Cursor) Close() error {
+	if c.id == 0 {
+		return nil
+	}
+	cmd := bson.D{
+		{Name: "killCursors", Value: c.collection},
+		{Name: "cursors", Value: []int64{c.id}},
+	}
+	c.id = 0
+	c.batch = nil
+	return c.session.RunOn(c.dbName, cmd, nil)
+}
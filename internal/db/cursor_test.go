@@ -0,0 +1,121 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// testCursorSession is a Session replying to commands with the next of 'resps'
+type testCursorSession struct {
+	testPoolSession
+	resps []bson.M
+	cmds  []bson.D
+}
+
+func (s *testCursorSession) RunOn(dbName string, cmd interface{}, result interface{}) error {
+	s.cmds = append(s.cmds, cmd.(bson.D))
+	resp := bson.M{"ok": 1}
+	if len(s.resps) > 0 {
+		resp = s.resps[0]
+		s.resps = s.resps[1:]
+	}
+	if result == nil {
+		return nil
+	}
+	data, err := bson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, result)
+}
+
+func testCursorResp(id int64, batchName string, docs ...bson.M) bson.M {
+	return bson.M{
+		"cursor": bson.M{"id": id, "ns": "local.oplog.rs", batchName: docs},
+		"ok":     1,
+	}
+}
+
+func TestInternalDBCursor(t *testing.T) {
+	session := &testCursorSession{resps: []bson.M{
+		testCursorResp(123, "firstBatch", bson.M{"n": 1}),
+		testCursorResp(123, "nextBatch"),
+		testCursorResp(0, "nextBatch", bson.M{"n": 2}),
+	}}
+	cursor, err := NewCursor(session, "local", bson.D{{Name: "find", Value: "oplog.rs"}}, time.Second)
+	assert.NoError(t, err)
+
+	doc := struct {
+		N int `bson:"n"`
+	}{}
+	assert.True(t, cursor.Next(&doc))
+	assert.Equal(t, 1, doc.N)
+
+	// an empty batch of an open cursor is a timeout of a tailable cursor
+	assert.False(t, cursor.Next(&doc))
+	assert.True(t, cursor.Timeout())
+	assert.NoError(t, cursor.Err())
+	assert.Equal(t, bson.D{
+		{Name: "getMore", Value: int64(123)},
+		{Name: "collection", Value: "oplog.rs"},
+		{Name: "maxTimeMS", Value: int64(1000)},
+	}, session.cmds[1])
+
+	raw := bson.Raw{}
+	assert.True(t, cursor.Next(&raw))
+	assert.NoError(t, raw.Unmarshal(&doc))
+	assert.Equal(t, 2, doc.N)
+
+	assert.False(t, cursor.Next(&doc))
+	assert.False(t, cursor.Timeout())
+	assert.NoError(t, cursor.Close())
+	assert.Len(t, session.cmds, 3, "an exhausted cursor should not be killed")
+}
+
+func TestInternalDBCursorClose(t *testing.T) {
+	session := &testCursorSession{resps: []bson.M{
+		testCursorResp(123, "firstBatch", bson.M{"n": 1}),
+	}}
+	cursor, err := NewCursor(session, "local", bson.D{{Name: "find", Value: "oplog.rs"}}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, cursor.Close())
+	assert.Equal(t, bson.D{
+		{Name: "killCursors", Value: "oplog.rs"},
+		{Name: "cursors", Value: []int64{123}},
+	}, session.cmds[1])
+	assert.False(t, cursor.Next(&bson.M{}))
+}
+
+func TestInternalDBFindOne(t *testing.T) {
+	session := &testCursorSession{resps: []bson.M{
+		testCursorResp(0, "firstBatch", bson.M{"n": 1}),
+		testCursorResp(0, "firstBatch"),
+	}}
+	doc := bson.M{}
+	assert.NoError(t, FindOne(session, "local", bson.D{{Name: "find", Value: "oplog.rs"}}, &doc))
+	assert.Equal(t, 1, doc["n"])
+	assert.Equal(t, bson.D{
+		{Name: "find", Value: "oplog.rs"},
+		{Name: "limit", Value: 1},
+		{Name: "singleBatch", Value: true},
+	}, session.cmds[0])
+
+	assert.Equal(t, ErrNotFound, FindOne(session, "local", bson.D{{Name: "find", Value: "oplog.rs"}}, &doc))
+}
@@ -15,6 +15,7 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -29,7 +30,17 @@ var (
 	ErrPrimaryTimeout          = errors.New("timed out waiting for host to become primary")
 )
 
+// GetSession connects an mgo session of the config. The session is connected with a
+// copy of the config, so the config is left unchanged and can be used concurrently
 func GetSession(cnf *Config) (*mgo.Session, error) {
+	session, _, err := dialMgo(cnf)
+	return session, err
+}
+
+// dialMgo connects an mgo session with a copy of the config, it returns true if the
+// session was connected without authentication after its authentication failed
+func dialMgo(cnf *Config) (*mgo.Session, bool, error) {
+	cnf = cnf.copy()
 	if cnf.SSL == nil {
 		cnf.SSL = &SSLConfig{}
 	}
@@ -40,54 +51,74 @@ func GetSession(cnf *Config) (*mgo.Session, error) {
 		"ssl_secure": !cnf.SSL.Insecure,
 	}).Debug("Connecting to mongodb")
 
-	if cnf.DialInfo.Username != "" && cnf.DialInfo.Password != "" {
+	err := cnf.configureAuth(DriverMgo)
+	if err != nil {
+		log.Errorf("Failed to configure authentication: %s", err)
+		return nil, false, err
+	}
+
+	if cnf.HasCredentials() {
 		log.WithFields(log.Fields{
-			"user":   cnf.DialInfo.Username,
-			"source": cnf.DialInfo.Source,
+			"user":      cnf.DialInfo.Username,
+			"source":    cnf.DialInfo.Source,
+			"mechanism": cnf.DialInfo.Mechanism,
 		}).Debug("Enabling authentication for session")
 	}
 
+	var tlsErrors *tlsErrorSet
 	if cnf.SSL.Enabled {
-		err := cnf.configureSSLDialInfo()
+		tlsErrors, err = cnf.configureSSLDialInfo()
 		if err != nil {
 			log.Errorf("Failed to configure SSL/TLS: %s", err)
-			return nil, err
+			return nil, false, err
 		}
 	}
 
+	var authFallback bool
 	session, err := mgo.DialWithInfo(cnf.DialInfo)
 	if err != nil && err.Error() == ErrMsgAuthFailedStr {
+		authErr := cnf.newAuthError(err)
+		if !cnf.AuthFallback {
+			return nil, false, authErr
+		}
 		log.Debug("Authentication failed, retrying with authentication disabled")
-		cnf.DialInfo.Username = ""
-		cnf.DialInfo.Password = ""
-		session, err = mgo.DialWithInfo(cnf.DialInfo)
+		session, err = mgo.DialWithInfo(cnf.withoutAuth().DialInfo)
+		if err == nil {
+			logAuthFallback(DriverMgo, authErr)
+			authFallback = true
+		}
 	}
 	if err != nil {
-		return nil, err
+		// mgo hides the cause of unreachable servers, return the TLS errors if any
+		if tlsErrs := tlsErrors.errors(); tlsErrs != nil {
+			return nil, false, tlsErrs
+		}
+		return nil, false, err
 	}
 
-	session.SetMode(mgo.Monotonic, true)
-	return session, err
+	if cnf.Primary {
+		session.SetMode(mgo.Primary, true)
+	} else {
+		session.SetMode(mgo.Monotonic, true)
+	}
+	return session, authFallback, nil
 }
 
-func WaitForSession(cnf *Config, maxRetries uint, sleepDuration time.Duration) (*mgo.Session, error) {
-	var err error
-	var tries uint
-	for tries <= maxRetries || maxRetries == 0 {
-		session, err := GetSession(cnf)
-		if err == nil && session.Ping() == nil {
-			return session, err
-		}
-		time.Sleep(sleepDuration)
-		tries++
-	}
-	if err == nil {
-		return nil, ErrSessionTimeout
+// sleepContext sleeps for 'duration', returning the context error if the context is done first
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return nil, err
 }
 
-func WaitForPrimary(session *mgo.Session, maxRetries uint, sleepDuration time.Duration) error {
+// WaitForPrimary retries until the session is connected to a writable primary, 'maxRetries'
+// is reached or the context is done
+func WaitForPrimary(ctx context.Context, session Session, maxRetries uint, sleepDuration time.Duration) error {
 	resp := struct {
 		IsMaster bool `bson:"ismaster"`
 		ReadOnly bool `bson:"readOnly"`
@@ -95,11 +126,13 @@ func WaitForPrimary(session *mgo.Session, maxRetries uint, sleepDuration time.Du
 	var err error
 	var tries uint
 	for tries <= maxRetries {
-		err = session.Run(bson.D{{"isMaster", "1"}}, &resp)
+		err = session.Run(bson.D{{Name: "isMaster", Value: "1"}}, &resp)
 		if err == nil && resp.IsMaster && !resp.ReadOnly {
 			return nil
 		}
-		time.Sleep(sleepDuration)
+		if ctxErr := sleepContext(ctx, sleepDuration); ctxErr != nil {
+			return ctxErr
+		}
 		tries++
 	}
 	if err == nil {
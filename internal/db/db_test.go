@@ -15,6 +15,7 @@
 package db
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -60,7 +61,7 @@ func TestInternalDBGetSession(t *testing.T) {
 	assert.Equal(t, "admin", resp.Users[0].User, "'user' field of 'usersInfo' response is not correct")
 }
 
-func TestInternalDBWaitForSession(t *testing.T) {
+func TestInternalDBWaitForNewSession(t *testing.T) {
 	testutils.DoSkipTest(t)
 
 	failConfig := &Config{
@@ -71,21 +72,21 @@ func TestInternalDBWaitForSession(t *testing.T) {
 		},
 		SSL: &SSLConfig{},
 	}
-	session, err := WaitForSession(failConfig, 1, time.Second)
-	assert.Error(t, err, ".WaitForSession() should fail due to bad dial info")
-	assert.Nil(t, session, ".WaitForSession() should return a nil *mgo.Session on failure")
+	session, err := WaitForNewSession(context.Background(), failConfig, 1, time.Second)
+	assert.Error(t, err, ".WaitForNewSession() should fail due to bad dial info")
+	assert.Nil(t, session, ".WaitForNewSession() should return a nil Session on failure")
 
-	session, err = WaitForSession(testPrimaryDbConfig, 3, time.Second)
-	assert.NoError(t, err, ".WaitForSession() should not return an error")
-	assert.NotNil(t, session, ".WaitForSession() should not return a nil session")
+	session, err = WaitForNewSession(context.Background(), testPrimaryDbConfig, 3, time.Second)
+	assert.NoError(t, err, ".WaitForNewSession() should not return an error")
+	assert.NotNil(t, session, ".WaitForNewSession() should not return a nil session")
 	defer session.Close()
-	assert.NoError(t, session.Ping(), ".WaitForSession() should return a ping-able session")
+	assert.NoError(t, session.Ping(), ".WaitForNewSession() should return a ping-able session")
 }
 
 func TestInternalDBWaitForPrimary(t *testing.T) {
 	testutils.DoSkipTest(t)
 
-	assert.NoError(t, WaitForPrimary(testPrimarySession, 1, time.Second), ".WaitForPrimary() should return no error for primary")
+	assert.NoError(t, WaitForPrimary(context.Background(), NewMgoSession(testPrimarySession), 1, time.Second), ".WaitForPrimary() should return no error for primary")
 
 	secondarySession, err := testutils.GetSession(testutils.MongodbSecondary1Port)
 	assert.NoError(t, err, "could not get secondary-host session for testing .WaitForPrimary()")
@@ -93,7 +94,22 @@ func TestInternalDBWaitForPrimary(t *testing.T) {
 	defer secondarySession.Close()
 	secondarySession.SetMode(mgo.Eventual, true)
 
-	err = WaitForPrimary(secondarySession, 1, time.Second)
+	err = WaitForPrimary(context.Background(), NewMgoSession(secondarySession), 1, time.Second)
 	assert.Error(t, err, ".WaitForPrimary() should return an error for secondary")
 	assert.Equal(t, err, ErrPrimaryTimeout, ".WaitForPrimary() should return a ErrPrimaryTimeout error on timeout")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = WaitForPrimary(ctx, NewMgoSession(secondarySession), 0, time.Minute)
+	assert.Equal(t, context.Canceled, err, ".WaitForPrimary() should return the context error when cancelled")
+}
+
+func TestInternalDBSleepContext(t *testing.T) {
+	assert.NoError(t, sleepContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	assert.Equal(t, context.Canceled, sleepContext(ctx, time.Minute))
+	assert.True(t, time.Since(start) < time.Second, ".sleepContext() should return immediately on a cancelled context")
 }
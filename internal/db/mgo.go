@@ -0,0 +1,76 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"gopkg.in/mgo.v2"
+)
+
+// mgoSession is a Session using the 'gopkg.in/mgo.v2' driver
+type mgoSession struct {
+	session      *mgo.Session
+	authFallback bool
+}
+
+// NewMgoSession returns a Session wrapping an existing *mgo.Session
+func NewMgoSession(session *mgo.Session) Session {
+	return &mgoSession{session: session}
+}
+
+// MgoSession returns the *mgo.Session of a Session using the mgo driver, for code that
+// still depends on libraries bound to mgo
+func MgoSession(session Session) (*mgo.Session, error) {
+	if pooled, ok := session.(*pooledSession); ok {
+		session = pooled.entry.session
+	}
+	s, ok := session.(*mgoSession)
+	if !ok {
+		return nil, ErrNotMgoSession
+	}
+	return s.session, nil
+}
+
+// refreshOnError refreshes the session after an error, mgo sessions keep using a broken
+// socket until they are refreshed
+func (s *mgoSession) refreshOnError(err error) error {
+	if err != nil {
+		s.session.Refresh()
+	}
+	return err
+}
+
+func (s *mgoSession) Run(cmd interface{}, result interface{}) error {
+	return s.refreshOnError(s.session.Run(cmd, result))
+}
+
+func (s *mgoSession) RunOn(dbName string, cmd interface{}, result interface{}) error {
+	return s.refreshOnError(s.session.DB(dbName).Run(cmd, result))
+}
+
+func (s *mgoSession) Ping() error {
+	return s.session.Ping()
+}
+
+func (s *mgoSession) Close() {
+	s.session.Close()
+}
+
+func (s *mgoSession) Driver() Driver {
+	return DriverMgo
+}
+
+func (s *mgoSession) isAuthFallback() bool {
+	return s.authFallback
+}
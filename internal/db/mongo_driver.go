@@ -0,0 +1,197 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	mongobson "go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// mongoDriverSession is a Session using the official 'go.mongodb.org/mongo-driver' driver
+type mongoDriverSession struct {
+	client       *mongo.Client
+	timeout      time.Duration
+	authFallback bool
+}
+
+// clientOptions returns the mongo-driver client options of the config
+func (cnf *Config) clientOptions() (*options.ClientOptions, error) {
+	opts := options.Client().SetHosts(cnf.DialInfo.Addrs)
+
+	// a direct connection is only possible to a single host
+	if cnf.DialInfo.Direct && len(cnf.DialInfo.Addrs) == 1 {
+		opts.SetDirect(true)
+	} else if cnf.DialInfo.ReplicaSetName != "" {
+		opts.SetReplicaSet(cnf.DialInfo.ReplicaSetName)
+	}
+
+	if cnf.DialInfo.Timeout > 0 {
+		opts.SetConnectTimeout(cnf.DialInfo.Timeout)
+		opts.SetServerSelectionTimeout(cnf.DialInfo.Timeout)
+	}
+
+	if cnf.HasCredentials() {
+		opts.SetAuth(options.Credential{
+			AuthMechanism: cnf.DialInfo.Mechanism,
+			AuthSource:    cnf.DialInfo.Source,
+			Username:      cnf.DialInfo.Username,
+			Password:      cnf.DialInfo.Password,
+		})
+	}
+
+	if cnf.SSL != nil && cnf.SSL.Enabled {
+		tlsConfig, err := cnf.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return opts, opts.Validate()
+}
+
+// connectMongoDriver connects a mongo-driver client and checks it is usable. The client
+// connects lazily, so a ping is needed to surface authentication errors
+func connectMongoDriver(cnf *Config) (*mongoDriverSession, error) {
+	opts, err := cnf.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &mongoDriverSession{timeout: cnf.DialInfo.Timeout}
+	ctx, cancel := session.context()
+	defer cancel()
+
+	session.client, err = mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = session.Ping()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// newMongoDriverSession connects a mongo-driver session with a copy of the config, so
+// the config is left unchanged and can be used concurrently
+func newMongoDriverSession(cnf *Config) (Session, error) {
+	cnf = cnf.copy()
+	if cnf.SSL == nil {
+		cnf.SSL = &SSLConfig{}
+	}
+
+	log.WithFields(log.Fields{
+		"hosts":      cnf.DialInfo.Addrs,
+		"ssl":        cnf.SSL.Enabled,
+		"ssl_secure": !cnf.SSL.Insecure,
+		"driver":     DriverMongoDriver,
+	}).Debug("Connecting to mongodb")
+
+	err := cnf.configureAuth(DriverMongoDriver)
+	if err != nil {
+		log.Errorf("Failed to configure authentication: %s", err)
+		return nil, err
+	}
+
+	session, err := connectMongoDriver(cnf)
+	if isAuthFailedError(err) {
+		authErr := cnf.newAuthError(err)
+		if !cnf.AuthFallback {
+			return nil, authErr
+		}
+		log.Debug("Authentication failed, retrying with authentication disabled")
+		session, err = connectMongoDriver(cnf.withoutAuth())
+		if err == nil {
+			logAuthFallback(DriverMongoDriver, authErr)
+			session.authFallback = true
+		}
+	}
+	if cnf.SSL.Enabled {
+		// the driver reports errors of the whole deployment, not per host
+		hosts := strings.Join(cnf.DialInfo.Addrs, ",")
+		if tlsErr := newTLSError(hosts, err, false); tlsErr != nil {
+			return nil, &TLSErrors{Errors: []*TLSError{tlsErr}}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// context returns a context bound by the session timeout
+func (s *mongoDriverSession) context() (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(context.Background(), s.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (s *mongoDriverSession) Run(cmd interface{}, result interface{}) error {
+	return s.RunOn("admin", cmd, result)
+}
+
+// RunOn marshals the command with mgo's bson package and runs the raw document, the
+// raw response is then unmarshalled into 'result' with mgo's bson package
+func (s *mongoDriverSession) RunOn(dbName string, cmd interface{}, result interface{}) error {
+	raw, err := bson.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+
+	resp, err := s.client.Database(dbName).RunCommand(ctx, mongobson.Raw(raw)).DecodeBytes()
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return bson.Unmarshal(resp, result)
+}
+
+func (s *mongoDriverSession) Ping() error {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.client.Ping(ctx, nil)
+}
+
+func (s *mongoDriverSession) Close() {
+	ctx, cancel := s.context()
+	defer cancel()
+	err := s.client.Disconnect(ctx)
+	if err != nil {
+		log.Errorf("Error closing mongo-driver session: %s", err)
+	}
+}
+
+func (s *mongoDriverSession) Driver() Driver {
+	return DriverMongoDriver
+}
+
+func (s *mongoDriverSession) isAuthFallback() bool {
+	return s.authFallback
+}
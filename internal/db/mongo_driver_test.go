@@ -0,0 +1,67 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+)
+
+func TestInternalDBClientOptions(t *testing.T) {
+	cnf := &Config{
+		DialInfo: &mgo.DialInfo{
+			Addrs:   []string{"localhost:27017"},
+			Direct:  true,
+			Timeout: 3 * time.Second,
+		},
+	}
+	opts, err := cnf.clientOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"localhost:27017"}, opts.Hosts)
+	assert.True(t, *opts.Direct, ".clientOptions() should enable a direct connection to a single host")
+	assert.Nil(t, opts.ReplicaSet)
+	assert.Nil(t, opts.Auth, ".clientOptions() should not set credentials without a username and password")
+	assert.Equal(t, 3*time.Second, *opts.ConnectTimeout)
+	assert.Equal(t, 3*time.Second, *opts.ServerSelectionTimeout)
+	assert.Nil(t, opts.TLSConfig)
+
+	// replset with auth
+	cnf.DialInfo.Addrs = []string{"host1:27017", "host2:27017"}
+	cnf.DialInfo.ReplicaSetName = "rs"
+	cnf.DialInfo.Username = "admin"
+	cnf.DialInfo.Password = "123456"
+	cnf.DialInfo.Source = "admin"
+	opts, err = cnf.clientOptions()
+	assert.NoError(t, err)
+	assert.Nil(t, opts.Direct, ".clientOptions() should not enable a direct connection to many hosts")
+	assert.Equal(t, "rs", *opts.ReplicaSet)
+	assert.Equal(t, "admin", opts.Auth.Username)
+	assert.Equal(t, "123456", opts.Auth.Password)
+	assert.Equal(t, "admin", opts.Auth.AuthSource)
+
+	// ssl
+	cnf.SSL = &SSLConfig{Enabled: true, Insecure: true}
+	opts, err = cnf.clientOptions()
+	assert.NoError(t, err)
+	assert.NotNil(t, opts.TLSConfig)
+	assert.True(t, opts.TLSConfig.InsecureSkipVerify)
+
+	cnf.SSL.PEMKeyFile = "/does/not/exist.pem"
+	_, err = cnf.clientOptions()
+	assert.Error(t, err, ".clientOptions() should return an error for a missing PEM key file")
+}
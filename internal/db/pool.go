@@ -0,0 +1,423 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	DefaultPoolMaxConnections = "100"
+	DefaultPoolBackoffMin     = "1s"
+	DefaultPoolBackoffMax     = "1m"
+
+	ErrPoolClosed         = errors.New("session pool is closed")
+	ErrPoolMaxConnections = errors.New("session pool reached its max connections")
+)
+
+// PoolConfig is the configuration of a session Pool
+type PoolConfig struct {
+	MaxConnections uint
+	BackoffMin     time.Duration
+	BackoffMax     time.Duration
+}
+
+// NewPoolConfig returns a PoolConfig with command-line flags registered on 'app'
+func NewPoolConfig(app *kingpin.Application) *PoolConfig {
+	cnf := &PoolConfig{}
+	app.Flag(
+		"pool.maxConnections",
+		"maximum number of mongodb sessions open at once, idle sessions are closed to stay under the limit, 0 is unlimited",
+	).Default(DefaultPoolMaxConnections).UintVar(&cnf.MaxConnections)
+	app.Flag(
+		"pool.backoffMin",
+		"initial time to wait before reconnecting to a host that failed to connect, doubled on each failure",
+	).Default(DefaultPoolBackoffMin).DurationVar(&cnf.BackoffMin)
+	app.Flag(
+		"pool.backoffMax",
+		"maximum time to wait before reconnecting to a host that failed to connect",
+	).Default(DefaultPoolBackoffMax).DurationVar(&cnf.BackoffMax)
+	return cnf
+}
+
+// HostHealth is the connection health of a host of pooled sessions
+type HostHealth struct {
+	Host      string
+	Healthy   bool
+	Failures  uint
+	LastError error
+	NextRetry time.Time
+}
+
+// backoff returns the time to wait before reconnecting to the host
+func (h *HostHealth) backoff(cnf *PoolConfig) time.Duration {
+	backoff := cnf.BackoffMin
+	for i := uint(1); i < h.Failures && backoff < cnf.BackoffMax; i++ {
+		backoff *= 2
+	}
+	if cnf.BackoffMax > 0 && backoff > cnf.BackoffMax {
+		return cnf.BackoffMax
+	}
+	return backoff
+}
+
+// BackoffError is returned for sessions to hosts that all failed to connect recently
+type BackoffError struct {
+	Hosts []string
+	Until time.Time
+	Err   error
+}
+
+func (e *BackoffError) Error() string {
+	return fmt.Sprintf("backing off connecting to %s until %s after error: %s", strings.Join(e.Hosts, ","), e.Until.Format(time.RFC3339), e.Err)
+}
+
+func (e *BackoffError) Unwrap() error {
+	return e.Err
+}
+
+type poolEntry struct {
+	key      string
+	session  Session
+	refs     uint
+	lastUsed time.Time
+	evicted  bool
+}
+
+// Pool is a pool of sessions shared by the users of the same config, with per-host
+// health tracking, backoff of hosts failing to connect and a max number of sessions
+type Pool struct {
+	sync.Mutex
+	config     *PoolConfig
+	entries    map[string]*poolEntry
+	hosts      map[string]*HostHealth
+	dialing    uint
+	closed     bool
+	newSession func(cnf *Config) (Session, error)
+}
+
+// NewPool returns a session Pool, using the default configuration if 'config' is nil
+func NewPool(config *PoolConfig) *Pool {
+	if config == nil {
+		maxConnections, _ := strconv.ParseUint(DefaultPoolMaxConnections, 10, 0)
+		backoffMin, _ := time.ParseDuration(DefaultPoolBackoffMin)
+		backoffMax, _ := time.ParseDuration(DefaultPoolBackoffMax)
+		config = &PoolConfig{
+			MaxConnections: uint(maxConnections),
+			BackoffMin:     backoffMin,
+			BackoffMax:     backoffMax,
+		}
+	}
+	return &Pool{
+		config:     config,
+		entries:    make(map[string]*poolEntry),
+		hosts:      make(map[string]*HostHealth),
+		newSession: NewSession,
+	}
+}
+
+// PoolKey returns the key of the pooled session of a config. Configs with the same
+// driver, hosts, replset, credentials, read mode and SSL options share a session. The
+// password is hashed so it is not kept in the key
+func PoolKey(cnf *Config) string {
+	addrs := append([]string{}, cnf.DialInfo.Addrs...)
+	sort.Strings(addrs)
+	key := []string{
+		string(cnf.Driver),
+		strings.Join(addrs, ","),
+		cnf.DialInfo.ReplicaSetName,
+		strconv.FormatBool(cnf.DialInfo.Direct),
+		cnf.DialInfo.Username,
+		fmt.Sprintf("%x", sha256.Sum256([]byte(cnf.DialInfo.Password))),
+		cnf.DialInfo.Source,
+		cnf.DialInfo.Mechanism,
+		strconv.FormatBool(cnf.Primary),
+	}
+	if cnf.SSL != nil && cnf.SSL.Enabled {
+		key = append(key, "ssl", cnf.SSL.PEMKeyFile, cnf.SSL.CAFile, strconv.FormatBool(cnf.SSL.Insecure))
+	}
+	return strings.Join(key, "|")
+}
+
+// Get returns the pooled session of 'cnf', connecting a new session if there is none or
+// if the pooled session fails to ping. The session is shared, closing it releases it to
+// the pool. Hosts that failed to connect are not retried until their backoff ends, a
+// *BackoffError is returned meanwhile. A session connected without authentication
+// after its authentication failed is not pooled, so the next Get authenticates again
+// instead of sharing an unauthenticated session under the key of the credentials
+func (p *Pool) Get(cnf *Config) (Session, error) {
+	key := PoolKey(cnf)
+	session, err := p.getPooled(key)
+	if session != nil || err != nil {
+		return session, err
+	}
+
+	err = p.reserve(cnf.DialInfo.Addrs)
+	if err != nil {
+		return nil, err
+	}
+	dialed, err := p.newSession(cnf)
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.dialing--
+	if err != nil {
+		p.setHostsFailed(cnf.DialInfo.Addrs, err)
+		return nil, err
+	}
+	p.setHostsHealthy(cnf.DialInfo.Addrs)
+	if p.closed {
+		dialed.Close()
+		return nil, ErrPoolClosed
+	}
+	if isAuthFallback(dialed) {
+		return dialed, nil
+	}
+
+	entry, ok := p.entries[key]
+	if ok {
+		// connected concurrently by another user of the config
+		dialed.Close()
+	} else {
+		entry = &poolEntry{key: key, session: dialed}
+		p.entries[key] = entry
+	}
+	return p.acquire(entry), nil
+}
+
+// getPooled returns the pooled session of 'key' if it is usable
+func (p *Pool) getPooled(key string) (Session, error) {
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return nil, ErrPoolClosed
+	}
+	entry, ok := p.entries[key]
+	if !ok {
+		p.Unlock()
+		return nil, nil
+	}
+	session := p.acquire(entry)
+	p.Unlock()
+
+	err := entry.session.Ping()
+	if err == nil {
+		return session, nil
+	}
+
+	log.WithFields(log.Fields{
+		"error": err,
+	}).Debug("Pooled session failed to ping, reconnecting")
+	p.Lock()
+	p.evict(entry)
+	p.Unlock()
+	session.Close()
+	return nil, nil
+}
+
+// reserve checks a new session to 'hosts' can be connected, closing idle sessions if
+// the pool is at its max connections
+func (p *Pool) reserve(hosts []string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	// back off if all the hosts failed to connect recently
+	now := time.Now()
+	var backoffErr *BackoffError
+	for _, host := range hosts {
+		health, ok := p.hosts[host]
+		if !ok || !now.Before(health.NextRetry) {
+			backoffErr = nil
+			break
+		}
+		if backoffErr == nil {
+			backoffErr = &BackoffError{Hosts: hosts, Until: health.NextRetry, Err: health.LastError}
+		} else if health.NextRetry.Before(backoffErr.Until) {
+			backoffErr.Until = health.NextRetry
+		}
+	}
+	if backoffErr != nil {
+		return backoffErr
+	}
+
+	if p.config.MaxConnections > 0 {
+		for uint(len(p.entries))+p.dialing >= p.config.MaxConnections {
+			idle := p.leastRecentlyUsedIdle()
+			if idle == nil {
+				return ErrPoolMaxConnections
+			}
+			p.evict(idle)
+		}
+	}
+	p.dialing++
+	return nil
+}
+
+func (p *Pool) leastRecentlyUsedIdle() *poolEntry {
+	var idle *poolEntry
+	for _, entry := range p.entries {
+		if entry.refs == 0 && (idle == nil || entry.lastUsed.Before(idle.lastUsed)) {
+			idle = entry
+		}
+	}
+	return idle
+}
+
+func (p *Pool) acquire(entry *poolEntry) Session {
+	entry.refs++
+	entry.lastUsed = time.Now()
+	return &pooledSession{pool: p, entry: entry}
+}
+
+// release releases a reference to a pooled session, closing it if it was evicted
+func (p *Pool) release(entry *poolEntry) {
+	p.Lock()
+	defer p.Unlock()
+
+	entry.refs--
+	entry.lastUsed = time.Now()
+	if entry.evicted && entry.refs == 0 {
+		entry.session.Close()
+	}
+}
+
+// evict removes a session from the pool, it is closed once it is released by its users
+func (p *Pool) evict(entry *poolEntry) {
+	if entry.evicted {
+		return
+	}
+	entry.evicted = true
+	if p.entries[entry.key] == entry {
+		delete(p.entries, entry.key)
+	}
+	if entry.refs == 0 {
+		entry.session.Close()
+	}
+}
+
+func (p *Pool) setHostsFailed(hosts []string, err error) {
+	now := time.Now()
+	for _, host := range hosts {
+		health, ok := p.hosts[host]
+		if !ok {
+			health = &HostHealth{Host: host}
+			p.hosts[host] = health
+		}
+		health.Healthy = false
+		health.Failures++
+		health.LastError = err
+		health.NextRetry = now.Add(health.backoff(p.config))
+	}
+}
+
+func (p *Pool) setHostsHealthy(hosts []string) {
+	for _, host := range hosts {
+		p.hosts[host] = &HostHealth{Host: host, Healthy: true}
+	}
+}
+
+// Remove closes the pooled session of 'cnf' once it is released by its users, the next
+// Get connects a new session
+func (p *Pool) Remove(cnf *Config) {
+	p.Lock()
+	defer p.Unlock()
+
+	entry, ok := p.entries[PoolKey(cnf)]
+	if ok {
+		p.evict(entry)
+	}
+}
+
+// Len returns the number of sessions in the pool
+func (p *Pool) Len() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.entries)
+}
+
+// Health returns the connection health of the hosts of the pool, sorted by host
+func (p *Pool) Health() []HostHealth {
+	p.Lock()
+	defer p.Unlock()
+
+	health := make([]HostHealth, 0, len(p.hosts))
+	for _, h := range p.hosts {
+		health = append(health, *h)
+	}
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Host < health[j].Host
+	})
+	return health
+}
+
+// Close closes the sessions of the pool, sessions in use are closed once released
+func (p *Pool) Close() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.closed = true
+	for _, entry := range p.entries {
+		p.evict(entry)
+	}
+}
+
+// pooledSession is a Session shared through a Pool, its Close releases it to the pool
+type pooledSession struct {
+	sync.Mutex
+	pool     *Pool
+	entry    *poolEntry
+	released bool
+}
+
+func (s *pooledSession) Run(cmd interface{}, result interface{}) error {
+	return s.entry.session.Run(cmd, result)
+}
+
+func (s *pooledSession) RunOn(dbName string, cmd interface{}, result interface{}) error {
+	return s.entry.session.RunOn(dbName, cmd, result)
+}
+
+func (s *pooledSession) Ping() error {
+	return s.entry.session.Ping()
+}
+
+func (s *pooledSession) Close() {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.released {
+		s.released = true
+		s.pool.release(s.entry)
+	}
+}
+
+func (s *pooledSession) Driver() Driver {
+	return s.entry.session.Driver()
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+)
+
+// testPoolSession is a Session counting its pings and closes
+type testPoolSession struct {
+	sync.Mutex
+	pingErr      error
+	pings        int
+	closed       bool
+	authFallback bool
+}
+
+func (s *testPoolSession) Run(cmd interface{}, result interface{}) error { return nil }
+func (s *testPoolSession) RunOn(dbName string, cmd interface{}, result interface{}) error {
+	return nil
+}
+func (s *testPoolSession) Driver() Driver       { return DriverMgo }
+func (s *testPoolSession) isAuthFallback() bool { return s.authFallback }
+
+func (s *testPoolSession) Ping() error {
+	s.Lock()
+	defer s.Unlock()
+	s.pings++
+	return s.pingErr
+}
+
+func (s *testPoolSession) Close() {
+	s.Lock()
+	defer s.Unlock()
+	s.closed = true
+}
+
+func (s *testPoolSession) isClosed() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.closed
+}
+
+// newTestPool returns a Pool connecting testPoolSessions, or failing with the error
+// returned by 'dialErr' if it is set
+func newTestPool(cnf *PoolConfig, dialErr func(cnf *Config) error) (*Pool, *[]*testPoolSession) {
+	sessions := &[]*testPoolSession{}
+	pool := NewPool(cnf)
+	pool.newSession = func(cnf *Config) (Session, error) {
+		if dialErr != nil {
+			if err := dialErr(cnf); err != nil {
+				return nil, err
+			}
+		}
+		session := &testPoolSession{}
+		*sessions = append(*sessions, session)
+		return session, nil
+	}
+	return pool, sessions
+}
+
+func newTestPoolConfig(addrs ...string) *Config {
+	return &Config{DialInfo: &mgo.DialInfo{Addrs: addrs, Direct: true}}
+}
+
+func TestInternalDBNewPoolConfig(t *testing.T) {
+	app := kingpin.New(t.Name(), t.Name())
+	cnf := NewPoolConfig(app)
+	_, err := app.Parse([]string{"--pool.maxConnections=5"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), cnf.MaxConnections)
+	assert.Equal(t, time.Second, cnf.BackoffMin)
+	assert.Equal(t, time.Minute, cnf.BackoffMax)
+
+	pool := NewPool(nil)
+	assert.Equal(t, uint(100), pool.config.MaxConnections)
+	assert.Equal(t, time.Second, pool.config.BackoffMin)
+}
+
+func TestInternalDBPoolKey(t *testing.T) {
+	cnf := newTestPoolConfig("host2:27017", "host1:27017")
+	cnf.DialInfo.Username = "test"
+	cnf.DialInfo.Password = "123456"
+	assert.NotContains(t, PoolKey(cnf), "123456", "the password should not be in the pool key")
+
+	sorted := newTestPoolConfig("host1:27017", "host2:27017")
+	sorted.DialInfo.Username = "test"
+	sorted.DialInfo.Password = "123456"
+	assert.Equal(t, PoolKey(cnf), PoolKey(sorted), "the order of hosts should not change the key")
+
+	otherPassword := newTestPoolConfig("host1:27017", "host2:27017")
+	otherPassword.DialInfo.Username = "test"
+	otherPassword.DialInfo.Password = "654321"
+	assert.NotEqual(t, PoolKey(cnf), PoolKey(otherPassword), "configs with different passwords should not share a session")
+
+	sorted.SSL = &SSLConfig{Enabled: true}
+	assert.NotEqual(t, PoolKey(cnf), PoolKey(sorted))
+}
+
+func TestInternalDBPoolGetAuthFallback(t *testing.T) {
+	pool := NewPool(nil)
+	sessions := []*testPoolSession{}
+	pool.newSession = func(cnf *Config) (Session, error) {
+		session := &testPoolSession{authFallback: true}
+		sessions = append(sessions, session)
+		return session, nil
+	}
+	cnf := newTestPoolConfig("host1:27017")
+	cnf.DialInfo.Username = "test"
+	cnf.DialInfo.Password = "123456"
+
+	session, err := pool.Get(cnf)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pool.Len(), "a session connected without authentication should not be pooled")
+
+	// the next user of the config connects with authentication again
+	_, err = pool.Get(cnf)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	session.Close()
+	assert.True(t, sessions[0].isClosed())
+}
+
+func TestInternalDBPoolGet(t *testing.T) {
+	pool, sessions := newTestPool(nil, nil)
+	cnf := newTestPoolConfig("host1:27017")
+
+	session, err := pool.Get(cnf)
+	assert.NoError(t, err)
+	assert.Len(t, *sessions, 1)
+
+	// the session of the same config is shared
+	session2, err := pool.Get(newTestPoolConfig("host1:27017"))
+	assert.NoError(t, err)
+	assert.Len(t, *sessions, 1, ".Get() should reuse the pooled session")
+	assert.Equal(t, 1, (*sessions)[0].pings, ".Get() should ping a pooled session before reusing it")
+	assert.Equal(t, 1, pool.Len())
+
+	// closing releases the session to the pool
+	session.Close()
+	session.Close()
+	session2.Close()
+	assert.False(t, (*sessions)[0].isClosed())
+
+	// a pooled session failing to ping is replaced
+	(*sessions)[0].pingErr = errors.New("ping failed")
+	session, err = pool.Get(cnf)
+	assert.NoError(t, err)
+	assert.Len(t, *sessions, 2)
+	assert.True(t, (*sessions)[0].isClosed())
+
+	// removed sessions are closed once released
+	pool.Remove(cnf)
+	assert.Equal(t, 0, pool.Len())
+	assert.False(t, (*sessions)[1].isClosed())
+	session.Close()
+	assert.True(t, (*sessions)[1].isClosed())
+
+	pool.Close()
+	_, err = pool.Get(cnf)
+	assert.Equal(t, ErrPoolClosed, err)
+}
+
+func TestInternalDBPoolMaxConnections(t *testing.T) {
+	pool, sessions := newTestPool(&PoolConfig{MaxConnections: 2}, nil)
+
+	session1, err := pool.Get(newTestPoolConfig("host1:27017"))
+	assert.NoError(t, err)
+	session2, err := pool.Get(newTestPoolConfig("host2:27017"))
+	assert.NoError(t, err)
+	_, err = pool.Get(newTestPoolConfig("host3:27017"))
+	assert.Equal(t, ErrPoolMaxConnections, err, ".Get() should fail when all sessions are in use")
+
+	// the least recently used idle session is closed for a new one
+	session1.Close()
+	session2.Close()
+	session3, err := pool.Get(newTestPoolConfig("host3:27017"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pool.Len())
+	assert.True(t, (*sessions)[0].isClosed())
+	assert.False(t, (*sessions)[1].isClosed())
+	session3.Close()
+}
+
+func TestInternalDBPoolBackoff(t *testing.T) {
+	dialErr := errors.New("connection refused")
+	failing := true
+	pool, _ := newTestPool(&PoolConfig{BackoffMin: 50 * time.Millisecond, BackoffMax: 150 * time.Millisecond}, func(cnf *Config) error {
+		if failing {
+			return dialErr
+		}
+		return nil
+	})
+	cnf := newTestPoolConfig("host1:27017")
+
+	_, err := pool.Get(cnf)
+	assert.Equal(t, dialErr, err)
+	health := pool.Health()
+	if assert.Len(t, health, 1) {
+		assert.Equal(t, "host1:27017", health[0].Host)
+		assert.False(t, health[0].Healthy)
+		assert.Equal(t, uint(1), health[0].Failures)
+		assert.Equal(t, dialErr, health[0].LastError)
+	}
+
+	// the host is not retried during its backoff
+	_, err = pool.Get(cnf)
+	backoffErr, ok := err.(*BackoffError)
+	if assert.True(t, ok, ".Get() should return a *BackoffError during the backoff") {
+		assert.Equal(t, dialErr, backoffErr.Err)
+		assert.Equal(t, []string{"host1:27017"}, backoffErr.Hosts)
+	}
+
+	// the backoff doubles with each failure, up to the max
+	assert.Equal(t, 50*time.Millisecond, (&HostHealth{Failures: 1}).backoff(pool.config))
+	assert.Equal(t, 100*time.Millisecond, (&HostHealth{Failures: 2}).backoff(pool.config))
+	assert.Equal(t, 150*time.Millisecond, (&HostHealth{Failures: 10}).backoff(pool.config))
+
+	// a session is connected after the backoff
+	failing = false
+	session, err := pool.WaitForSession(context.Background(), cnf, 3, time.Millisecond)
+	assert.NoError(t, err)
+	session.Close()
+	health = pool.Health()
+	assert.True(t, health[0].Healthy)
+	assert.Equal(t, uint(0), health[0].Failures)
+}
+
+func TestInternalDBPoolMgoSession(t *testing.T) {
+	pool := NewPool(nil)
+	entry := &poolEntry{key: "test", session: NewMgoSession(&mgo.Session{})}
+	_, err := MgoSession(pool.acquire(entry))
+	assert.NoError(t, err, ".MgoSession() should return the *mgo.Session of a pooled session")
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"errors"
+
+	rsConfig "github.com/timvaillancourt/go-mongodb-replset/config"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrNoReplsetConfig = errors.New("no replset config loaded or set")
+
+// ReplsetConfigManager loads, changes and saves the config of a replica set
+type ReplsetConfigManager interface {
+	AddMember(member *rsConfig.Member)
+	Get() *rsConfig.Config
+	IncrVersion()
+	Initiate() error
+	Load() error
+	RemoveMember(member *rsConfig.Member)
+	Save() error
+	Set(config *rsConfig.Config)
+}
+
+// replsetConfigManager is a ReplsetConfigManager running the replset commands through
+// a Session, unlike the config manager of 'go-mongodb-replset' which is bound to mgo
+type replsetConfigManager struct {
+	session Session
+	config  *rsConfig.Config
+}
+
+// NewReplsetConfigManager returns a ReplsetConfigManager using 'session'
+func NewReplsetConfigManager(session Session) ReplsetConfigManager {
+	return &replsetConfigManager{session: session}
+}
+
+type replsetCommandResp struct {
+	Config *rsConfig.Config `bson:"config,omitempty"`
+
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+// run runs a replset command, returning the error of failed commands
+func (m *replsetConfigManager) run(cmd bson.D) (*replsetCommandResp, error) {
+	resp := &replsetCommandResp{}
+	err := m.session.Run(cmd, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	return resp, nil
+}
+
+func (m *replsetConfigManager) Get() *rsConfig.Config {
+	return m.config
+}
+
+func (m *replsetConfigManager) Set(config *rsConfig.Config) {
+	m.config = config
+}
+
+// AddMember adds 'member' to the config with the next free member id, members that are
+// already in the config are not added again
+func (m *replsetConfigManager) AddMember(member *rsConfig.Member) {
+	if m.config == nil || m.config.HasMember(member.Host) {
+		return
+	}
+	member.Id = 0
+	for _, configMember := range m.config.Members {
+		if configMember.Id >= member.Id {
+			member.Id = configMember.Id + 1
+		}
+	}
+	m.config.AddMember(member)
+}
+
+// RemoveMember removes the member with the host of 'member' from the config
+func (m *replsetConfigManager) RemoveMember(member *rsConfig.Member) {
+	if m.config == nil {
+		return
+	}
+	members := make([]*rsConfig.Member, 0, len(m.config.Members))
+	for _, configMember := range m.config.Members {
+		if configMember.Host != member.Host {
+			members = append(members, configMember)
+		}
+	}
+	m.config.Members = members
+}
+
+func (m *replsetConfigManager) IncrVersion() {
+	if m.config != nil {
+		m.config.Version++
+	}
+}
+
+// Load loads the config of the replset with 'replSetGetConfig'
+func (m *replsetConfigManager) Load() error {
+	resp, err := m.run(bson.D{{Name: "replSetGetConfig", Value: 1}})
+	if err != nil {
+		return err
+	}
+	if resp.Config == nil {
+		return ErrNoReplsetConfig
+	}
+	m.config = resp.Config
+	return nil
+}
+
+// Save saves the config to the replset with 'replSetReconfig'
+func (m *replsetConfigManager) Save() error {
+	if m.config == nil {
+		return ErrNoReplsetConfig
+	}
+	_, err := m.run(bson.D{{Name: "replSetReconfig", Value: m.config}})
+	return err
+}
+
+// Initiate initiates the replset with the config with 'replSetInitiate'
+func (m *replsetConfigManager) Initiate() error {
+	if m.config == nil {
+		return ErrNoReplsetConfig
+	}
+	_, err := m.run(bson.D{{Name: "replSetInitiate", Value: m.config}})
+	return err
+}
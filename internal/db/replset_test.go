@@ -0,0 +1,98 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rsConfig "github.com/timvaillancourt/go-mongodb-replset/config"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// testReplsetSession is a Session replying to commands with 'resp' and recording them
+type testReplsetSession struct {
+	testPoolSession
+	resp interface{}
+	cmds []bson.D
+}
+
+func (s *testReplsetSession) Run(cmd interface{}, result interface{}) error {
+	s.cmds = append(s.cmds, cmd.(bson.D))
+	data, err := bson.Marshal(s.resp)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, result)
+}
+
+func TestInternalDBReplsetConfigManagerLoad(t *testing.T) {
+	session := &testReplsetSession{resp: bson.M{
+		"config": rsConfig.NewConfig("rs"),
+		"ok":     1,
+	}}
+	rsCnfMan := NewReplsetConfigManager(session)
+	assert.NoError(t, rsCnfMan.Load())
+	assert.Equal(t, "replSetGetConfig", session.cmds[0][0].Name)
+	assert.Equal(t, "rs", rsCnfMan.Get().Name)
+
+	session.resp = bson.M{"ok": 0, "errmsg": "not running with --replSet"}
+	assert.EqualError(t, rsCnfMan.Load(), "not running with --replSet")
+
+	session.resp = bson.M{"ok": 1}
+	assert.Equal(t, ErrNoReplsetConfig, rsCnfMan.Load())
+}
+
+func TestInternalDBReplsetConfigManagerSave(t *testing.T) {
+	session := &testReplsetSession{resp: bson.M{"ok": 1}}
+	rsCnfMan := NewReplsetConfigManager(session)
+	assert.Equal(t, ErrNoReplsetConfig, rsCnfMan.Save())
+	assert.Equal(t, ErrNoReplsetConfig, rsCnfMan.Initiate())
+	assert.Len(t, session.cmds, 0)
+
+	config := rsConfig.NewConfig("rs")
+	rsCnfMan.Set(config)
+	rsCnfMan.IncrVersion()
+	assert.Equal(t, 2, config.Version)
+
+	assert.NoError(t, rsCnfMan.Save())
+	assert.Equal(t, bson.D{{Name: "replSetReconfig", Value: config}}, session.cmds[0])
+	assert.NoError(t, rsCnfMan.Initiate())
+	assert.Equal(t, bson.D{{Name: "replSetInitiate", Value: config}}, session.cmds[1])
+
+	session.resp = bson.M{"ok": 0, "errmsg": "reconfig failed"}
+	assert.Equal(t, errors.New("reconfig failed"), rsCnfMan.Save())
+}
+
+func TestInternalDBReplsetConfigManagerMembers(t *testing.T) {
+	rsCnfMan := NewReplsetConfigManager(&testReplsetSession{})
+	rsCnfMan.Set(rsConfig.NewConfig("rs"))
+
+	rsCnfMan.AddMember(rsConfig.NewMember("host0:27017"))
+	rsCnfMan.AddMember(rsConfig.NewMember("host1:27017"))
+	rsCnfMan.AddMember(rsConfig.NewMember("host1:27017"))
+	config := rsCnfMan.Get()
+	assert.Len(t, config.Members, 2)
+	assert.Equal(t, 0, config.Members[0].Id)
+	assert.Equal(t, 1, config.Members[1].Id)
+
+	rsCnfMan.RemoveMember(rsConfig.NewMember("host0:27017"))
+	rsCnfMan.AddMember(rsConfig.NewMember("host2:27017"))
+	assert.Len(t, config.Members, 2)
+	assert.Equal(t, "host1:27017", config.Members[0].Host)
+	assert.Equal(t, "host2:27017", config.Members[1].Host)
+	assert.Equal(t, 2, config.Members[1].Id)
+}
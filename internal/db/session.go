@@ -0,0 +1,196 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Driver is the name of a MongoDB driver implementing Session
+type Driver string
+
+const (
+	DriverMgo         Driver = "mgo"
+	DriverMongoDriver Driver = "mongo-driver"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown mongodb driver")
+	ErrNotMgoSession = errors.New("session is not using the mgo driver")
+)
+
+// Session is a driver-agnostic connection to a MongoDB server or replica set. Commands
+// are marshalled and results are unmarshalled with the 'gopkg.in/mgo.v2/bson' package
+// regardless of the driver, so existing command and response types work unchanged
+type Session interface {
+	// Run runs a server command on the 'admin' database, unmarshalling the response into 'result'
+	Run(cmd interface{}, result interface{}) error
+	// RunOn runs a server command on the database 'dbName', unmarshalling the response into 'result'
+	RunOn(dbName string, cmd interface{}, result interface{}) error
+	// Ping checks the session is connected
+	Ping() error
+	// Close closes the session
+	Close()
+	// Driver returns the driver of the session
+	Driver() Driver
+}
+
+func driverNames() []string {
+	return []string{string(DriverMgo), string(DriverMongoDriver)}
+}
+
+func isAuthFailedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "Authentication failed")
+}
+
+// isAuthFallback returns true if the session was connected without authentication
+// after its authentication failed
+func isAuthFallback(session Session) bool {
+	s, ok := session.(interface{ isAuthFallback() bool })
+	return ok && s.isAuthFallback()
+}
+
+// NewSession returns a Session using the driver of the config, defaulting to the mgo driver
+func NewSession(cnf *Config) (Session, error) {
+	switch cnf.Driver {
+	case DriverMgo, "":
+		session, authFallback, err := dialMgo(cnf)
+		if err != nil {
+			return nil, err
+		}
+		return &mgoSession{session: session, authFallback: authFallback}, nil
+	case DriverMongoDriver:
+		return newMongoDriverSession(cnf)
+	}
+	return nil, ErrUnknownDriver
+}
+
+// WaitForNewSession retries getting a ping-able Session until 'maxRetries' is reached (0 is
+// unlimited) or the context is done
+func WaitForNewSession(ctx context.Context, cnf *Config, maxRetries uint, sleepDuration time.Duration) (Session, error) {
+	return waitForSession(ctx, func() (Session, error) {
+		return NewSession(cnf)
+	}, maxRetries, sleepDuration)
+}
+
+// WaitForSession retries getting a ping-able pooled Session of 'cnf' until 'maxRetries'
+// is reached (0 is unlimited) or the context is done. Retries wait for the end of the
+// backoff of hosts that failed to connect
+func (p *Pool) WaitForSession(ctx context.Context, cnf *Config, maxRetries uint, sleepDuration time.Duration) (Session, error) {
+	return waitForSession(ctx, func() (Session, error) {
+		return p.Get(cnf)
+	}, maxRetries, sleepDuration)
+}
+
+func waitForSession(ctx context.Context, getSession func() (Session, error), maxRetries uint, sleepDuration time.Duration) (Session, error) {
+	var err error
+	var tries uint
+	for tries <= maxRetries || maxRetries == 0 {
+		var session Session
+		session, err = getSession()
+		if err == nil {
+			err = session.Ping()
+			if err == nil {
+				return session, nil
+			}
+			session.Close()
+		}
+		if err == ErrUnknownDriver || err == ErrPoolClosed || err == ErrAuthMechanismMgo {
+			return nil, err
+		}
+		if tlsErrs, ok := IsTLSError(err); ok && tlsErrs.Permanent() {
+			// retrying cannot fix certificate errors
+			return nil, err
+		}
+		sleep := sleepDuration
+		if backoffErr, ok := err.(*BackoffError); ok && time.Until(backoffErr.Until) > sleep {
+			sleep = time.Until(backoffErr.Until)
+		}
+		if ctxErr := sleepContext(ctx, sleep); ctxErr != nil {
+			return nil, ctxErr
+		}
+		tries++
+	}
+	if err == nil {
+		return nil, ErrSessionTimeout
+	}
+	return nil, err
+}
+
+// BuildInfo is the subset of the 'buildInfo' server command response used by the tools
+type BuildInfo struct {
+	Version      string `bson:"version"`
+	VersionArray []int  `bson:"versionArray"`
+
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+// VersionAtLeast returns true if the server version is greater than or equal to 'version'
+func (bi *BuildInfo) VersionAtLeast(version ...int) bool {
+	for i, vi := range version {
+		if i == len(bi.VersionArray) {
+			return false
+		}
+		if bi.VersionArray[i] != vi {
+			return bi.VersionArray[i] >= vi
+		}
+	}
+	return true
+}
+
+// GetBuildInfo runs the 'buildInfo' server command on 'session'
+func GetBuildInfo(session Session) (*BuildInfo, error) {
+	info := &BuildInfo{}
+	err := session.Run(bson.D{{Name: "buildInfo", Value: 1}}, info)
+	if err != nil {
+		return nil, err
+	}
+	if info.Ok == 0 {
+		return nil, errors.New(info.Errmsg)
+	}
+	return info, nil
+}
+
+type listDatabasesResp struct {
+	Databases []struct {
+		Name string `bson:"name"`
+	} `bson:"databases"`
+
+	Ok     int    `bson:"ok"`
+	Errmsg string `bson:"errmsg,omitempty"`
+}
+
+// ListDatabases returns the names of all the databases of the server
+func ListDatabases(session Session) ([]string, error) {
+	resp := listDatabasesResp{}
+	err := session.Run(bson.D{{Name: "listDatabases", Value: 1}}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ok == 0 {
+		return nil, errors.New(resp.Errmsg)
+	}
+	dbNames := []string{}
+	for _, database := range resp.Databases {
+		dbNames = append(dbNames, database.Name)
+	}
+	return dbNames, nil
+}
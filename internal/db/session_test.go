@@ -0,0 +1,119 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestInternalDBNewSession(t *testing.T) {
+	_, err := NewSession(&Config{Driver: "not-a-driver"})
+	assert.Equal(t, ErrUnknownDriver, err, ".NewSession() should return ErrUnknownDriver for an unknown driver")
+
+	testutils.DoSkipTest(t)
+
+	for _, driver := range []Driver{DriverMgo, DriverMongoDriver} {
+		session, err := NewSession(&Config{
+			DialInfo: &mgo.DialInfo{
+				Addrs:    []string{testutils.MongodbHost + ":" + testutils.MongodbPrimaryPort},
+				Direct:   true,
+				Timeout:  testutils.MongodbTimeout,
+				Username: testutils.MongodbAdminUser,
+				Password: testutils.MongodbAdminPassword,
+				Source:   "admin",
+			},
+			Driver: driver,
+		})
+		assert.NoErrorf(t, err, ".NewSession() returned error for driver %s", driver)
+		assert.NotNil(t, session)
+		assert.Equal(t, driver, session.Driver())
+		assert.NoError(t, session.Ping())
+
+		resp := struct {
+			IsMaster bool   `bson:"ismaster"`
+			SetName  string `bson:"setName"`
+			Ok       int    `bson:"ok"`
+		}{}
+		assert.NoError(t, session.Run(bson.D{{Name: "isMaster", Value: 1}}, &resp))
+		assert.Equal(t, 1, resp.Ok)
+		assert.Equal(t, testutils.MongodbReplsetName, resp.SetName)
+
+		oplogStats := struct {
+			Ns string `bson:"ns"`
+			Ok int    `bson:"ok"`
+		}{}
+		assert.NoError(t, session.RunOn("local", bson.D{{Name: "collStats", Value: "oplog.rs"}}, &oplogStats))
+		assert.Equal(t, "local.oplog.rs", oplogStats.Ns)
+
+		info, err := GetBuildInfo(session)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, info.Version)
+
+		mgoSession, err := MgoSession(session)
+		if driver == DriverMgo {
+			assert.NoError(t, err)
+			assert.NotNil(t, mgoSession)
+		} else {
+			assert.Equal(t, ErrNotMgoSession, err)
+		}
+		session.Close()
+	}
+}
+
+func TestInternalDBWaitForNewSessionAuthMechanismMgo(t *testing.T) {
+	cnf := &Config{
+		DialInfo: &mgo.DialInfo{
+			Addrs:     []string{"localhost:27017"},
+			Mechanism: AuthMechanismScramSHA256,
+		},
+		Driver: DriverMgo,
+	}
+	start := time.Now()
+	_, err := WaitForNewSession(context.Background(), cnf, 10, time.Second)
+	assert.Equal(t, ErrAuthMechanismMgo, err)
+	assert.True(t, time.Since(start) < 5*time.Second, ".WaitForNewSession() should not retry an auth mechanism the driver does not support")
+}
+
+func TestInternalDBBuildInfoVersionAtLeast(t *testing.T) {
+	info := &BuildInfo{VersionArray: []int{4, 0, 12, 0}}
+	assert.True(t, info.VersionAtLeast(4))
+	assert.True(t, info.VersionAtLeast(3, 6))
+	assert.True(t, info.VersionAtLeast(4, 0, 12))
+	assert.False(t, info.VersionAtLeast(4, 2))
+	assert.False(t, info.VersionAtLeast(4, 0, 12, 0, 1))
+}
+
+func TestInternalDBListDatabases(t *testing.T) {
+	testutils.DoSkipTest(t)
+
+	dbNames, err := ListDatabases(NewMgoSession(testPrimarySession))
+	assert.NoError(t, err)
+	assert.Contains(t, dbNames, "admin")
+}
+
+func TestInternalDBIsAuthFailedError(t *testing.T) {
+	assert.False(t, isAuthFailedError(nil))
+	assert.True(t, isAuthFailedError(errors.New(ErrMsgAuthFailedStr)))
+	assert.True(t, isAuthFailedError(errors.New("auth error: sasl conversation error: unable to authenticate using mechanism \"SCRAM-SHA-1\": (AuthenticationFailed) Authentication failed.")))
+	assert.False(t, isAuthFailedError(errors.New("connection refused")))
+}
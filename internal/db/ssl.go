@@ -27,8 +27,6 @@ import (
 	"gopkg.in/mgo.v2"
 )
 
-var lastSSLErr error
-
 type SSLConfig struct {
 	Enabled    bool
 	PEMKeyFile string
@@ -36,6 +34,24 @@ type SSLConfig struct {
 	Insecure   bool
 }
 
+// ToolArgs returns the command-line SSL arguments of the MongoDB tools, such as mongodump
+func (sc *SSLConfig) ToolArgs() []string {
+	if sc == nil || !sc.Enabled {
+		return []string{}
+	}
+	args := []string{"--ssl"}
+	if sc.PEMKeyFile != "" {
+		args = append(args, "--sslPEMKeyFile="+sc.PEMKeyFile)
+	}
+	if sc.CAFile != "" {
+		args = append(args, "--sslCAFile="+sc.CAFile)
+	}
+	if sc.Insecure {
+		args = append(args, "--sslAllowInvalidCertificates", "--sslAllowInvalidHostnames")
+	}
+	return args
+}
+
 func (sc *SSLConfig) loadCaCertificate() (*x509.CertPool, error) {
 	caCert, err := ioutil.ReadFile(sc.CAFile)
 	if err != nil {
@@ -46,43 +62,40 @@ func (sc *SSLConfig) loadCaCertificate() (*x509.CertPool, error) {
 	return certificates, nil
 }
 
-// LastSSLError returns the last error related to the DB connection SSL handshake
-func LastSSLError() error {
-	return lastSSLErr
+// tlsConfig returns the *tls.Config of the SSL configuration, shared by all drivers. The
+// certificates are served by the CertManager of the SSL configuration
+func (cnf *Config) tlsConfig() (*tls.Config, error) {
+	cm, err := cnf.SSL.CertManager()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load SSL/TLS certificates to connect to server '%s'. Got: %v", cnf.DialInfo.Addrs, err)
+	}
+	return cm.TLSConfig(cnf.SSL.Insecure), nil
 }
 
-func (cnf *Config) configureSSLDialInfo() error {
-	config := &tls.Config{
-		InsecureSkipVerify: cnf.SSL.Insecure,
-	}
-	if len(cnf.SSL.PEMKeyFile) > 0 {
-		log.Debugf("Loading SSL/TLS PEM certificate: %s", cnf.SSL.PEMKeyFile)
-		certificates, err := tls.LoadX509KeyPair(cnf.SSL.PEMKeyFile, cnf.SSL.PEMKeyFile)
-		if err != nil {
-			return fmt.Errorf(
-				"Cannot load key pair from '%s' to connect to server '%s'. Got: %v",
-				cnf.SSL.PEMKeyFile,
-				cnf.DialInfo.Addrs,
-				err,
-			)
-		}
-		config.Certificates = []tls.Certificate{certificates}
-	}
-	if len(cnf.SSL.CAFile) > 0 {
-		log.Debugf("Loading SSL/TLS Certificate Authority: %s", cnf.SSL.CAFile)
-		ca, err := cnf.SSL.loadCaCertificate()
-		if err != nil {
-			return fmt.Errorf("Couldn't load client CAs from %s. Got: %s", cnf.SSL.CAFile, err)
-		}
-		config.RootCAs = ca
+// configureSSLDialInfo sets the TLS dialer of the dial info, it returns the set of the
+// TLS errors of the connections of the dial info
+func (cnf *Config) configureSSLDialInfo() (*tlsErrorSet, error) {
+	cm, err := cnf.SSL.CertManager()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load SSL/TLS certificates to connect to server '%s'. Got: %v", cnf.DialInfo.Addrs, err)
 	}
+	tlsErrors := newTLSErrorSet()
+	insecure := cnf.SSL.Insecure
+	dialer := &net.Dialer{Timeout: cnf.DialInfo.Timeout}
 	cnf.DialInfo.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
-		conn, err := tls.Dial("tcp", addr.String(), config)
+		// a config per connection uses certificate authorities reloaded since the last one.
+		// The server certificate is recorded with the address of the server instead
+		config := cm.TLSConfig(insecure)
+		config.VerifyPeerCertificate = nil
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr.String(), config)
 		if err != nil {
 			log.Errorf("Could not connect to %v. Got: %v", addr, err)
-			lastSSLErr = err
+			tlsErrors.set(addr.String(), newTLSError(addr.String(), err, true))
 			return nil, err
 		}
+		if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+			cm.recordPeer(addr.String(), certs[0])
+		}
 		if !config.InsecureSkipVerify {
 			dnsName := strings.SplitN(addr.String(), ":", 2)[0]
 			err = validateConnection(conn, config, dnsName)
@@ -90,9 +103,10 @@ func (cnf *Config) configureSSLDialInfo() error {
 				log.Errorf("Could not disable hostname validation. Got: %v", err)
 			}
 		}
+		tlsErrors.set(addr.String(), newTLSError(addr.String(), err, true))
 		return conn, err
 	}
-	return nil
+	return tlsErrors, nil
 }
 
 func validateConnection(conn *tls.Conn, tlsConfig *tls.Config, dnsName string) error {
@@ -60,8 +60,9 @@ func TestInternalDBConfigureSSLDialInfo(t *testing.T) {
 	}
 	assert.Nil(t, config.DialInfo.DialServer, "config.DialInfo.DialServer should be nil")
 
-	err := config.configureSSLDialInfo()
+	tlsErrors, err := config.configureSSLDialInfo()
 	assert.NoError(t, err, ".configureSSLDialInfo() should not return an error")
+	assert.Nil(t, tlsErrors.errors(), ".configureSSLDialInfo() should return an empty set of TLS errors")
 	assert.NotNil(t, config.DialInfo.DialServer, "config.DialInfo.DialServer should not be nil")
 }
 
@@ -109,11 +110,9 @@ func TestInternalDBGetSessionSSL(t *testing.T) {
 
 	// test secure mode
 	testLogBuffer.Reset()
-	assert.Nil(t, LastSSLError(), ".LastSSLError() should be nil")
 	testPrimaryDbConfig.DialInfo.Timeout = 100 * time.Millisecond
 	_, err := GetSession(testPrimaryDbConfig)
 	assert.NoError(t, err, ".GetSession() should return nil")
-	assert.NoError(t, LastSSLError(), ".LastSSLError() should return nil")
 
 	// enable insecure mode (due to self-signed certs) and connect
 	testPrimaryDbConfig.DialInfo.Timeout = testutils.MongodbTimeout
@@ -128,3 +127,16 @@ func TestInternalDBGetSessionSSL(t *testing.T) {
 
 	testPrimaryDbConfig.SSL = &SSLConfig{}
 }
+
+func TestInternalDBSSLConfigToolArgs(t *testing.T) {
+	var nilConfig *SSLConfig
+	assert.Len(t, nilConfig.ToolArgs(), 0)
+	assert.Len(t, (&SSLConfig{CAFile: sslCAFile}).ToolArgs(), 0)
+	assert.Equal(t, []string{
+		"--ssl",
+		"--sslPEMKeyFile=/client.pem",
+		"--sslCAFile=/ca.crt",
+		"--sslAllowInvalidCertificates",
+		"--sslAllowInvalidHostnames",
+	}, (&SSLConfig{Enabled: true, PEMKeyFile: "/client.pem", CAFile: "/ca.crt", Insecure: true}).ToolArgs())
+}
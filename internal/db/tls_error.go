@@ -0,0 +1,247 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TLSErrorKind is the cause of a TLS connection failure
+type TLSErrorKind string
+
+const (
+	TLSErrorHostnameMismatch TLSErrorKind = "hostname mismatch"
+	TLSErrorUnknownAuthority TLSErrorKind = "unknown authority"
+	TLSErrorExpired          TLSErrorKind = "expired certificate"
+	TLSErrorHandshakeTimeout TLSErrorKind = "handshake timeout"
+	TLSErrorHandshake        TLSErrorKind = "handshake failure"
+)
+
+// TLSError is the failure of a TLS connection to a host
+type TLSError struct {
+	Kind TLSErrorKind
+	Host string
+	Err  error
+}
+
+func (e *TLSError) Error() string {
+	return fmt.Sprintf("tls %s connecting to %s: %s", e.Kind, e.Host, e.Err)
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent returns true if the error is caused by the certificates, so retrying with
+// the same SSL configuration cannot succeed
+func (e *TLSError) Permanent() bool {
+	switch e.Kind {
+	case TLSErrorHostnameMismatch, TLSErrorUnknownAuthority, TLSErrorExpired:
+		return true
+	}
+	return false
+}
+
+// errorChain returns 'err' followed by the errors it wraps. The chain is walked by hand
+// rather than with errors.As to support Go releases before 1.13
+func errorChain(err error) []error {
+	chain := []error{}
+	for err != nil {
+		chain = append(chain, err)
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case *net.OpError:
+			err = e.Err
+		default:
+			err = nil
+		}
+	}
+	return chain
+}
+
+// isDialError returns true if 'err' is caused by a failure to connect, before the handshake
+func isDialError(err error) bool {
+	for _, e := range errorChain(err) {
+		if opErr, ok := e.(*net.OpError); ok && opErr.Op == "dial" {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsErrorKind returns the kind of TLS error of 'err', false if 'err' is not a TLS error
+func tlsErrorKind(err error) (TLSErrorKind, bool) {
+	chain := errorChain(err)
+	for _, e := range chain {
+		switch e := e.(type) {
+		case x509.HostnameError, *x509.HostnameError:
+			return TLSErrorHostnameMismatch, true
+		case x509.UnknownAuthorityError, *x509.UnknownAuthorityError:
+			return TLSErrorUnknownAuthority, true
+		case x509.CertificateInvalidError:
+			if e.Reason == x509.Expired {
+				return TLSErrorExpired, true
+			}
+		case *x509.CertificateInvalidError:
+			if e.Reason == x509.Expired {
+				return TLSErrorExpired, true
+			}
+		case tls.RecordHeaderError, *tls.RecordHeaderError:
+			// the server did not answer with TLS
+			return TLSErrorHandshake, true
+		}
+	}
+	if isDialError(err) {
+		// the connection failed before the handshake
+		return "", false
+	}
+	for _, e := range chain {
+		if netErr, ok := e.(net.Error); ok && netErr.Timeout() {
+			return TLSErrorHandshakeTimeout, true
+		}
+	}
+	return "", false
+}
+
+// newTLSError returns a *TLSError for the failed connection to 'host', or nil if 'err'
+// is not a TLS error. If 'handshake' is true, 'err' is known to come from a TLS dial so
+// errors of unknown kinds are handshake failures
+func newTLSError(host string, err error, handshake bool) *TLSError {
+	if err == nil {
+		return nil
+	}
+	kind, ok := tlsErrorKind(err)
+	if !ok {
+		if !handshake || isDialError(err) {
+			return nil
+		}
+		kind = TLSErrorHandshake
+	}
+	return &TLSError{Kind: kind, Host: host, Err: err}
+}
+
+// TLSErrors are the TLS errors of the hosts of a session that failed to connect
+type TLSErrors struct {
+	Errors []*TLSError
+}
+
+func (e *TLSErrors) Error() string {
+	errs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+	return strings.Join(errs, ", ")
+}
+
+// Permanent returns true if all the TLS errors are permanent
+func (e *TLSErrors) Permanent() bool {
+	for _, err := range e.Errors {
+		if !err.Permanent() {
+			return false
+		}
+	}
+	return len(e.Errors) > 0
+}
+
+// Handshake returns true if all the TLS errors are handshake failures, eg: the server
+// does not accept TLS connections, rather than errors of the certificates
+func (e *TLSErrors) Handshake() bool {
+	for _, err := range e.Errors {
+		if err.Kind != TLSErrorHandshake && err.Kind != TLSErrorHandshakeTimeout {
+			return false
+		}
+	}
+	return len(e.Errors) > 0
+}
+
+// Kinds returns the distinct kinds of the TLS errors
+func (e *TLSErrors) Kinds() []TLSErrorKind {
+	kinds := []TLSErrorKind{}
+	seen := map[TLSErrorKind]bool{}
+	for _, err := range e.Errors {
+		if !seen[err.Kind] {
+			kinds = append(kinds, err.Kind)
+			seen[err.Kind] = true
+		}
+	}
+	return kinds
+}
+
+// tlsErrorSet is the last TLS error of each host of a dial
+type tlsErrorSet struct {
+	sync.Mutex
+	errs map[string]*TLSError
+}
+
+func newTLSErrorSet() *tlsErrorSet {
+	return &tlsErrorSet{errs: map[string]*TLSError{}}
+}
+
+// set records the result of a TLS connection to 'host', a nil error clears the host
+func (s *tlsErrorSet) set(host string, err *TLSError) {
+	s.Lock()
+	defer s.Unlock()
+	if err == nil {
+		delete(s.errs, host)
+		return
+	}
+	s.errs[host] = err
+}
+
+func (s *tlsErrorSet) get() []*TLSError {
+	s.Lock()
+	defer s.Unlock()
+	errs := []*TLSError{}
+	for _, err := range s.errs {
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Host < errs[j].Host
+	})
+	return errs
+}
+
+// errors returns the last TLS error of each host that failed to connect, nil if there
+// are none
+func (s *tlsErrorSet) errors() *TLSErrors {
+	if s == nil {
+		return nil
+	}
+	errs := s.get()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &TLSErrors{Errors: errs}
+}
+
+// IsTLSError returns the *TLSErrors of 'err', false if it was not caused by TLS errors
+func IsTLSError(err error) (*TLSErrors, bool) {
+	for _, e := range errorChain(err) {
+		switch e := e.(type) {
+		case *TLSErrors:
+			return e, true
+		case *TLSError:
+			return &TLSErrors{Errors: []*TLSError{e}}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+)
+
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "i/o timeout" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+func TestInternalDBNewTLSError(t *testing.T) {
+	cert := &x509.Certificate{}
+	for _, test := range []struct {
+		err  error
+		kind TLSErrorKind
+	}{
+		{x509.HostnameError{Certificate: cert, Host: "host"}, TLSErrorHostnameMismatch},
+		{x509.UnknownAuthorityError{Cert: cert}, TLSErrorUnknownAuthority},
+		{x509.CertificateInvalidError{Cert: cert, Reason: x509.Expired}, TLSErrorExpired},
+		{&tls.CertificateVerificationError{Err: x509.HostnameError{Certificate: cert, Host: "host"}}, TLSErrorHostnameMismatch},
+		{&net.OpError{Op: "read", Err: testTimeoutError{}}, TLSErrorHandshakeTimeout},
+		{tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, TLSErrorHandshake},
+	} {
+		err, kind := test.err, test.kind
+		tlsErr := newTLSError("host:27017", err, false)
+		assert.NotNil(t, tlsErr, err.Error())
+		assert.Equal(t, kind, tlsErr.Kind)
+		assert.Equal(t, "host:27017", tlsErr.Host)
+		assert.Equal(t, err, tlsErr.Unwrap())
+	}
+
+	assert.Nil(t, newTLSError("host:27017", nil, true))
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	assert.Nil(t, newTLSError("host:27017", dialErr, true), "dial errors should not be TLS errors")
+	assert.Nil(t, newTLSError("host:27017", errors.New("auth failed"), false))
+	assert.Equal(t, TLSErrorHandshake, newTLSError("host:27017", errors.New("EOF"), true).Kind)
+}
+
+func TestInternalDBTLSErrors(t *testing.T) {
+	tlsErrs := &TLSErrors{Errors: []*TLSError{
+		{Kind: TLSErrorExpired, Host: "host1:27017", Err: errors.New("expired")},
+		{Kind: TLSErrorUnknownAuthority, Host: "host2:27017", Err: errors.New("unknown")},
+	}}
+	assert.True(t, tlsErrs.Permanent())
+	assert.False(t, tlsErrs.Handshake())
+	assert.Equal(t, []TLSErrorKind{TLSErrorExpired, TLSErrorUnknownAuthority}, tlsErrs.Kinds())
+	assert.Equal(t, "tls expired certificate connecting to host1:27017: expired, tls unknown authority connecting to host2:27017: unknown", tlsErrs.Error())
+
+	tlsErrs.Errors = append(tlsErrs.Errors, &TLSError{Kind: TLSErrorHandshakeTimeout, Host: "host3:27017"})
+	assert.False(t, tlsErrs.Permanent())
+	assert.False(t, (&TLSErrors{}).Permanent())
+	assert.False(t, tlsErrs.Handshake(), "certificate errors should not be handshake failures")
+	assert.True(t, (&TLSErrors{Errors: tlsErrs.Errors[2:]}).Handshake())
+	assert.False(t, (&TLSErrors{}).Handshake())
+
+	found, ok := IsTLSError(tlsErrs)
+	assert.True(t, ok)
+	assert.Equal(t, tlsErrs, found)
+	found, ok = IsTLSError(tlsErrs.Errors[0])
+	assert.True(t, ok)
+	assert.Len(t, found.Errors, 1)
+	_, ok = IsTLSError(errors.New("not tls"))
+	assert.False(t, ok)
+	_, ok = IsTLSError(nil)
+	assert.False(t, ok)
+}
+
+func TestInternalDBGetSessionTLSErrors(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(tmpDir)
+	serverPEMFile := filepath.Join(tmpDir, "server.pem")
+	writeTestCertificate(t, serverPEMFile, "server", time.Now().Add(time.Hour))
+	serverCert, err := tls.LoadX509KeyPair(serverPEMFile, serverPEMFile)
+	assert.NoError(t, err)
+
+	// a TLS server with a certificate of an unknown authority
+	tlsListener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	assert.NoError(t, err)
+	defer tlsListener.Close()
+	go func() {
+		for {
+			conn, err := tlsListener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// a plaintext server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("HTTP/1.0 400 Bad Request\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+
+	cnf := &Config{
+		DialInfo: &mgo.DialInfo{
+			Addrs:    []string{tlsListener.Addr().String(), listener.Addr().String()},
+			Timeout:  500 * time.Millisecond,
+			FailFast: true,
+		},
+		SSL: &SSLConfig{Enabled: true},
+	}
+	_, err = GetSession(cnf)
+	assert.Error(t, err)
+
+	assert.Nil(t, cnf.DialInfo.DialServer, ".GetSession() should not modify the config")
+
+	tlsErrs, ok := IsTLSError(err)
+	assert.True(t, ok, ".GetSession() should return the TLS errors")
+	if assert.Len(t, tlsErrs.Errors, 2) {
+		hosts := map[string]TLSErrorKind{}
+		for _, tlsErr := range tlsErrs.Errors {
+			hosts[tlsErr.Host] = tlsErr.Kind
+		}
+		assert.Equal(t, TLSErrorUnknownAuthority, hosts[tlsListener.Addr().String()])
+		assert.Equal(t, TLSErrorHandshake, hosts[listener.Addr().String()])
+	}
+
+	// certificate errors are not retried
+	cnf.DialInfo.Addrs = []string{tlsListener.Addr().String()}
+	start := time.Now()
+	_, err = WaitForNewSession(context.Background(), cnf, 10, time.Second)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second, ".WaitForNewSession() should not retry permanent TLS errors")
+}
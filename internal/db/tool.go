@@ -0,0 +1,51 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// toolConfig is the YAML '--config' file of the MongoDB tools, such as mongodump
+type toolConfig struct {
+	Password string `yaml:"password"`
+}
+
+// WriteToolConfig writes 'password' to a '--config' file of the MongoDB tools in 'dir',
+// so the password is not visible in the command-line of the process. The file is only
+// readable by its owner and must be removed by the caller
+func WriteToolConfig(dir, password string) (string, error) {
+	data, err := yaml.Marshal(&toolConfig{Password: password})
+	if err != nil {
+		return "", err
+	}
+	file, err := ioutil.TempFile(dir, "mongodb-tool-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	err = file.Chmod(0600)
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
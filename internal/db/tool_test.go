@@ -0,0 +1,43 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalDBWriteToolConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	path, err := WriteToolConfig(dir, "pass: \"word")
+	assert.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+
+	stat, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "password: 'pass: \"word'\n", string(data))
+
+	_, err = WriteToolConfig(filepath.Join(dir, "missing"), "123456")
+	assert.Error(t, err)
+}
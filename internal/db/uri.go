@@ -0,0 +1,198 @@
+// Copyright 2018 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+)
+
+const (
+	uriScheme    = "mongodb://"
+	uriSchemeSRV = "mongodb+srv://"
+)
+
+var (
+	ErrInvalidUri        = errors.New("invalid mongodb connection string")
+	ErrUnsupportedUriSRV = errors.New("'" + uriSchemeSRV + "' connection strings are not supported")
+)
+
+func parseUriBool(key, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: option '%s' must be a boolean, got '%s'", ErrInvalidUri, key, value)
+	}
+	return b, nil
+}
+
+func parseUriMS(key, value string) (time.Duration, error) {
+	ms, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: option '%s' must be a number of milliseconds, got '%s'", ErrInvalidUri, key, value)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// parseUriAuthMechanism checks 'value' is one of AuthMechanisms(), as the
+// --authMechanism flag does
+func parseUriAuthMechanism(key, value string) (string, error) {
+	for _, mechanism := range AuthMechanisms() {
+		if value == mechanism {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("%s: option '%s' must be one of %s, got '%s'", ErrInvalidUri, key, strings.Join(AuthMechanisms(), ", "), value)
+}
+
+// applyUriOption applies a single connection string option to the config
+func (cnf *Config) applyUriOption(key, value string) error {
+	var err error
+	switch strings.ToLower(key) {
+	case "replicaset":
+		cnf.DialInfo.ReplicaSetName = value
+	case "authsource":
+		cnf.DialInfo.Source = value
+	case "authmechanism":
+		cnf.DialInfo.Mechanism, err = parseUriAuthMechanism(key, value)
+	case "ssl", "tls":
+		cnf.SSL.Enabled, err = parseUriBool(key, value)
+	case "tlscertificatekeyfile", "sslclientcertificatekeyfile":
+		cnf.SSL.PEMKeyFile = value
+	case "tlscafile", "sslcertificateauthorityfile":
+		cnf.SSL.CAFile = value
+	case "tlsinsecure", "tlsallowinvalidcertificates", "sslallowinvalidcertificates":
+		cnf.SSL.Insecure, err = parseUriBool(key, value)
+	case "tlsallowinvalidhostnames", "sslallowinvalidhostnames":
+		// the hostname is only validated with the certificate chain, it cannot be
+		// skipped on its own
+		var allow bool
+		allow, err = parseUriBool(key, value)
+		if err == nil && allow {
+			err = fmt.Errorf("%s: option '%s' is not supported, use 'tlsInsecure' to skip the validation of the certificate and hostname", ErrInvalidUri, key)
+		}
+	case "connecttimeoutms", "serverselectiontimeoutms":
+		cnf.DialInfo.Timeout, err = parseUriMS(key, value)
+	case "directconnection":
+		cnf.DialInfo.Direct, err = parseUriBool(key, value)
+	case "connect":
+		cnf.DialInfo.Direct = strings.ToLower(value) == "direct"
+	default:
+		log.Warnf("Ignoring unsupported mongodb connection string option: %s", key)
+	}
+	return err
+}
+
+// ApplyUri overrides the config with the hosts, credentials and options set in the
+// mongodb connection string 'uri'. Fields not present in the connection string are
+// left unchanged
+func (cnf *Config) ApplyUri(uri string) error {
+	if strings.HasPrefix(uri, uriSchemeSRV) {
+		return ErrUnsupportedUriSRV
+	} else if !strings.HasPrefix(uri, uriScheme) {
+		return fmt.Errorf("%s: must start with '%s'", ErrInvalidUri, uriScheme)
+	}
+	if cnf.DialInfo == nil {
+		cnf.DialInfo = &mgo.DialInfo{}
+	}
+	if cnf.SSL == nil {
+		cnf.SSL = &SSLConfig{}
+	}
+
+	rest := strings.TrimPrefix(uri, uriScheme)
+	var query string
+	if i := strings.Index(rest, "?"); i >= 0 {
+		rest, query = rest[:i], rest[i+1:]
+	}
+	var authDB string
+	if i := strings.Index(rest, "/"); i >= 0 {
+		rest, authDB = rest[:i], rest[i+1:]
+	}
+
+	// userinfo must be percent-escaped, so the last '@' separates it from the hosts
+	hosts := rest
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		userInfo := rest[:i]
+		hosts = rest[i+1:]
+		username, password := userInfo, ""
+		if j := strings.Index(userInfo, ":"); j >= 0 {
+			username, password = userInfo[:j], userInfo[j+1:]
+		}
+		var err error
+		cnf.DialInfo.Username, err = url.PathUnescape(username)
+		if err != nil {
+			return fmt.Errorf("%s: cannot unescape username: %s", ErrInvalidUri, err)
+		}
+		cnf.DialInfo.Password, err = url.PathUnescape(password)
+		if err != nil {
+			return fmt.Errorf("%s: cannot unescape password", ErrInvalidUri)
+		}
+	}
+
+	if hosts == "" {
+		return fmt.Errorf("%s: no hosts", ErrInvalidUri)
+	}
+	addrs := strings.Split(hosts, ",")
+	for _, addr := range addrs {
+		if addr == "" {
+			return fmt.Errorf("%s: empty host in '%s'", ErrInvalidUri, hosts)
+		}
+	}
+	cnf.DialInfo.Addrs = addrs
+
+	if authDB != "" {
+		source, err := url.PathUnescape(authDB)
+		if err != nil {
+			return fmt.Errorf("%s: cannot unescape database: %s", ErrInvalidUri, err)
+		}
+		cnf.DialInfo.Source = source
+	}
+
+	if query != "" {
+		options, err := url.ParseQuery(query)
+		if err != nil {
+			return fmt.Errorf("%s: cannot parse options: %s", ErrInvalidUri, err)
+		}
+		for key, values := range options {
+			err = cnf.applyUriOption(key, values[len(values)-1])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseUri returns a Config of the mongodb connection string 'uri'
+func ParseUri(uri string) (*Config, error) {
+	cnf := &Config{
+		DialInfo: &mgo.DialInfo{
+			Source:  DefaultMongoDBAuthDB,
+			Timeout: DefaultMongoDBTimeoutDuration,
+		},
+		SSL: &SSLConfig{},
+	}
+	err := cnf.ApplyUri(uri)
+	if err != nil {
+		return nil, err
+	}
+	return cnf, nil
+}
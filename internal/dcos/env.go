@@ -29,6 +29,8 @@ const (
 	EnvMongoDBChangeUserUsername       = "MONGODB_CHANGE_USER_USERNAME"
 	EnvMongoDBChangeUserNewPassword    = "MONGODB_CHANGE_USER_NEW_PASSWORD"
 	EnvMongoDBWiredTigerCacheSizeRatio = "MONGODB_STORAGE_WIREDTIGER_ENGINE_CONFIG_CACHE_SIZE_RATIO"
+	EnvMongoDBConfigOverlayFile        = "MONGODB_CONFIG_OVERLAY_FILE"
+	EnvMongoDBPreflightMode            = "MONGODB_PREFLIGHT_MODE"
 
 	EnvWatchdogMetricsListen = "WATCHDOG_METRICS_LISTEN"
 
@@ -46,4 +48,29 @@ const (
 	EnvMetricsInterval   = "DCOS_METRICS_INTERVAL"
 	EnvMetricsStatsdHost = "STATSD_UDP_HOST"
 	EnvMetricsStatsdPort = "STATSD_UDP_PORT"
+
+	EnvPrometheusEnabled  = "PROMETHEUS_ENABLED"
+	EnvPrometheusListen   = "PROMETHEUS_LISTEN"
+	EnvPrometheusInterval = "PROMETHEUS_INTERVAL"
+
+	EnvBackupEnabled     = "BACKUP_ENABLED"
+	EnvBackupSchedule    = "BACKUP_SCHEDULE"
+	EnvBackupStorage     = "BACKUP_STORAGE"
+	EnvBackupRetention   = "BACKUP_RETENTION"
+	EnvBackupS3Endpoint  = "BACKUP_S3_ENDPOINT"
+	EnvBackupS3Region    = "BACKUP_S3_REGION"
+	EnvBackupS3Bucket    = "BACKUP_S3_BUCKET"
+	EnvBackupS3Prefix    = "BACKUP_S3_PREFIX"
+	EnvBackupS3AccessKey = "AWS_ACCESS_KEY_ID"
+	EnvBackupS3SecretKey = "AWS_SECRET_ACCESS_KEY"
+
+	EnvPITREnabled       = "PITR_ENABLED"
+	EnvPITRChunkInterval = "PITR_CHUNK_INTERVAL"
+	EnvPITRRetention     = "PITR_RETENTION"
+
+	EnvProfilerEnabled    = "PROFILER_ENABLED"
+	EnvProfilerLevel      = "PROFILER_LEVEL"
+	EnvProfilerSlowMs     = "PROFILER_SLOWMS"
+	EnvProfilerSampleRate = "PROFILER_SAMPLE_RATE"
+	EnvProfilerDrainFile  = "PROFILER_DRAIN_FILE"
 )
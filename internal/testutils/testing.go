@@ -16,7 +16,9 @@ package testutils
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -77,9 +79,14 @@ func getDialInfo(host, port string) (*mgo.DialInfo, error) {
 	}, nil
 }
 
+// GetDialInfo returns a *mgo.DialInfo configured for testing against the MongoDB on 'port'
+func GetDialInfo(port string) (*mgo.DialInfo, error) {
+	return getDialInfo(MongodbHost, port)
+}
+
 // GetSession returns a *mgo.Session configured for testing against a MongoDB Primary
 func GetSession(port string) (*mgo.Session, error) {
-	dialInfo, err := getDialInfo(MongodbHost, port)
+	dialInfo, err := GetDialInfo(port)
 	if err != nil {
 		return nil, err
 	}
@@ -96,3 +103,14 @@ func DoSkipTest(t *testing.T) {
 		t.Skipf("Skipping test, env var %s is not 'true'", envEnableDBTests)
 	}
 }
+
+// WriteScript writes an executable shell script 'name' to 'dir' that runs 'script',
+// for tests that stand in for external binaries. It returns the path of the script
+func WriteScript(t *testing.T, dir, name, script string) string {
+	bin := filepath.Join(dir, name)
+	err := ioutil.WriteFile(bin, []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	if err != nil {
+		t.Fatalf("Cannot write test %s: %s", name, err)
+	}
+	return bin
+}
@@ -13,3 +13,33 @@
 // limitations under the License.
 
 package testutils
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalTestutilsWriteScript(t *testing.T) {
+	dir, _ := ioutil.TempDir("", t.Name())
+	defer os.RemoveAll(dir)
+
+	bin := WriteScript(t, dir, "tool", "echo \"$@\"")
+	out, err := exec.Command(bin, "hello", "world").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world\n", string(out))
+}
+
+func TestInternalTestutilsMetricValue(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "test"})
+	gauge.Set(2)
+	assert.Equal(t, float64(2), MetricValue(t, gauge))
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "test"})
+	counter.Inc()
+	assert.Equal(t, float64(1), MetricValue(t, counter))
+}
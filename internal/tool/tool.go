@@ -15,14 +15,17 @@
 package tool
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 
 	"github.com/alecthomas/kingpin"
 	tools "github.com/percona/mongodb-orchestration-tools"
 	"github.com/percona/mongodb-orchestration-tools/internal/logger"
+	log "github.com/sirupsen/logrus"
 )
 
 // Author is the author used by kingpin
@@ -42,3 +45,21 @@ func New(help, commit, branch string) (*kingpin.Application, *bool) {
 		os.Stdout,
 	)
 }
+
+// NewSignalContext returns a context.Context that is cancelled when one of 'signals'
+// is received from the OS or when the returned context.CancelFunc is called
+func NewSignalContext(signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, signals...)
+	go func() {
+		defer signal.Stop(sigChan)
+		select {
+		case sig := <-sigChan:
+			log.Infof("Received %s signal, stopping", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -15,7 +15,10 @@
 package tool
 
 import (
+	"os"
+	"syscall"
 	"testing"
+	"time"
 
 	tools "github.com/percona/mongodb-orchestration-tools"
 	"github.com/stretchr/testify/assert"
@@ -28,3 +31,21 @@ func TestInternalToolNew(t *testing.T) {
 	assert.Equal(t, Author, appModel.Author, "kingpin.Application author is unexpected")
 	assert.Equal(t, "test help", appModel.Help, "kingpin.Application help is unexpected")
 }
+
+func TestInternalToolNewSignalContext(t *testing.T) {
+	ctx, cancel := NewSignalContext(syscall.SIGUSR1)
+	defer cancel()
+	assert.NoError(t, ctx.Err())
+
+	proc, _ := os.FindProcess(os.Getpid())
+	assert.NoError(t, proc.Signal(syscall.SIGUSR1))
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "context was not cancelled by signal")
+	}
+
+	ctx, cancel = NewSignalContext(syscall.SIGUSR1)
+	cancel()
+	assert.Error(t, ctx.Err())
+}
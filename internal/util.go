@@ -19,6 +19,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -26,6 +27,15 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const uriRedactedPasswd = "xxxxx"
+
+var uriPasswordRegexp = regexp.MustCompile(`(mongodb(?:\+srv)?://[^:@/\s]*:)[^@\s]*@`)
+
+// RedactUri replaces the password of any mongodb connection strings in 's'
+func RedactUri(s string) string {
+	return uriPasswordRegexp.ReplaceAllString(s, "${1}"+uriRedactedPasswd+"@")
+}
+
 // GetUserID returns the numeric ID of a system user
 func GetUserID(userName string) (int, error) {
 	u, err := user.Lookup(userName)
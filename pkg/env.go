@@ -21,6 +21,12 @@ const (
 	EnvMongoDBPort    = "MONGODB_PORT"
 	EnvMongoDBIp      = "MONGODB_IP"
 	EnvMongoDBReplset = "MONGODB_REPLSET"
+	EnvMongoDBDriver  = "MONGODB_DRIVER"
+	EnvMongoDBUri     = "MONGODB_URI"
+
+	// auth
+	EnvMongoDBAuthMechanism = "MONGODB_AUTH_MECHANISM"
+	EnvMongoDBAuthFallback  = "MONGODB_AUTH_FALLBACK"
 
 	// backup user
 	EnvMongoDBBackupUser     = "MONGODB_BACKUP_USER"
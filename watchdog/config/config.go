@@ -15,10 +15,13 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/dcos/api"
+	"github.com/percona/mongodb-orchestration-tools/pkg"
+	"gopkg.in/mgo.v2"
 )
 
 var (
@@ -32,6 +35,7 @@ var (
 	DefaultReplsetTimeout = "3s"
 	DefaultMetricsListen  = ":8080"
 	DefaultMetricsPath    = "/metrics"
+	DefaultCertReloadPoll = "1m"
 )
 
 // Watchdog Configuration
@@ -42,6 +46,45 @@ type Config struct {
 	API            *api.Config
 	APIPoll        time.Duration
 	SSL            *db.SSLConfig
+	Pool           *db.PoolConfig
+	Driver         db.Driver
+	AuthMechanism  string
+	AuthFallback   bool
 	ReplsetPoll    time.Duration
 	ReplsetTimeout time.Duration
+	CertReloadPoll time.Duration
+}
+
+// ApplyUri applies the clusterAdmin credentials, auth mechanism and SSL options of the mongodb connection
+// string 'uri', if it is set. The hosts of the connection string are not used, as the
+// watchdog finds the replset members from the DC/OS SDK API
+func (cnf *Config) ApplyUri(uri string) error {
+	if uri != "" {
+		dbCnf := &db.Config{
+			DialInfo: &mgo.DialInfo{
+				Username:  cnf.Username,
+				Password:  cnf.Password,
+				Mechanism: cnf.AuthMechanism,
+			},
+			SSL: cnf.SSL,
+		}
+		err := dbCnf.ApplyUri(uri)
+		if err != nil {
+			return err
+		}
+		cnf.Username = dbCnf.DialInfo.Username
+		cnf.Password = dbCnf.DialInfo.Password
+		cnf.AuthMechanism = dbCnf.DialInfo.Mechanism
+		cnf.SSL = dbCnf.SSL
+	}
+
+	if cnf.AuthMechanism == db.AuthMechanismX509 {
+		// the username is the client certificate subject if not set
+		return nil
+	} else if cnf.Username == "" {
+		return fmt.Errorf("required flag --username, env var %s or a --uri with credentials not provided", pkg.EnvMongoDBClusterAdminUser)
+	} else if cnf.Password == "" {
+		return fmt.Errorf("required flag --password, env var %s or a --uri with credentials not provided", pkg.EnvMongoDBClusterAdminPassword)
+	}
+	return nil
 }
@@ -21,8 +21,9 @@ import (
 const namespace = "watchdog"
 
 type Collector struct {
-	PodSourceErrorsTotal *prometheus.CounterVec
-	PodSourceGetsTotal   *prometheus.CounterVec
+	PodSourceErrorsTotal  *prometheus.CounterVec
+	PodSourceGetsTotal    *prometheus.CounterVec
+	CertificateExpiryDays *prometheus.GaugeVec
 }
 
 func NewCollector() *Collector {
@@ -39,15 +40,23 @@ func NewCollector() *Collector {
 			Name:      "gets_total",
 			Help:      "The total number of successful times the watchdog has polled a pod source",
 		}, []string{"source"}),
+		CertificateExpiryDays: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tls_certificate",
+			Name:      "expiry_days",
+			Help:      "The number of days until the expiry of the SSL/TLS certificates used by, or presented to, the watchdog",
+		}, []string{"type", "name", "subject"}),
 	}
 }
 
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	c.PodSourceErrorsTotal.Collect(ch)
 	c.PodSourceGetsTotal.Collect(ch)
+	c.CertificateExpiryDays.Collect(ch)
 }
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	c.PodSourceErrorsTotal.Describe(ch)
 	c.PodSourceGetsTotal.Describe(ch)
+	c.CertificateExpiryDays.Describe(ch)
 }
@@ -20,16 +20,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/logger"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
 	wdConfig "github.com/percona/mongodb-orchestration-tools/watchdog/config"
 	rsConfig "github.com/timvaillancourt/go-mongodb-replset/config"
-	"gopkg.in/mgo.v2"
 )
 
 var (
-	testDBSession       *mgo.Session
-	testRsConfigManager rsConfig.Manager
+	testDBSession       db.Session
+	testRsConfigManager db.ReplsetConfigManager
 	testRsConfigBefore  *rsConfig.Config
 	testMongod          *Mongod
 	testState           *State
@@ -52,12 +52,12 @@ var (
 func TestMain(m *testing.M) {
 	logger.SetupLogger(nil, logger.GetLogFormatter(), testLogBuffer)
 	if testutils.Enabled() {
-		var err error
-		testDBSession, err = testutils.GetSession(testutils.MongodbPrimaryPort)
+		mgoSession, err := testutils.GetSession(testutils.MongodbPrimaryPort)
 		if err != nil {
 			panic(err)
 		}
-		testRsConfigManager = rsConfig.New(testDBSession)
+		testDBSession = db.NewMgoSession(mgoSession)
+		testRsConfigManager = db.NewReplsetConfigManager(testDBSession)
 		_ = testRsConfigManager.Load()
 		testRsConfigBefore = testRsConfigManager.Get()
 	}
@@ -116,11 +116,19 @@ func (r *Replset) GetReplsetDBConfig(sslCnf *db.SSLConfig) *db.Config {
 			ReplicaSetName: r.Name,
 			Timeout:        r.config.ReplsetTimeout,
 		},
-		SSL: sslCnf,
+		SSL:          sslCnf,
+		Driver:       r.config.Driver,
+		AuthFallback: r.config.AuthFallback,
+		// replset reconfigs must run on the primary
+		Primary: true,
 	}
-	if r.config.Username != "" && r.config.Password != "" {
+	if r.config.AuthMechanism == db.AuthMechanismX509 {
+		cnf.DialInfo.Username = r.config.Username
+		cnf.DialInfo.Mechanism = r.config.AuthMechanism
+	} else if r.config.Username != "" && r.config.Password != "" {
 		cnf.DialInfo.Username = r.config.Username
 		cnf.DialInfo.Password = r.config.Password
+		cnf.DialInfo.Mechanism = r.config.AuthMechanism
 	}
 	return cnf
 }
@@ -82,6 +82,7 @@ func TestWatchdogReplsetGetReplsetDBConfig(t *testing.T) {
 	assert.Equal(t, testWatchdogConfig.ReplsetTimeout, dbCnf.DialInfo.Timeout, "*mgo.DialInfo 'Timeout' is incorrect")
 	assert.False(t, dbCnf.DialInfo.Direct, "*mgo.DialInfo 'Direct' must be false")
 	assert.True(t, dbCnf.DialInfo.FailFast, "*mgo.DialInfo 'FailFast' must be true")
+	assert.True(t, dbCnf.Primary, "replset.GetReplsetDBConfig() returned config with false Primary field")
 }
 
 func TestWatchdogReplsetRemoveMember(t *testing.T) {
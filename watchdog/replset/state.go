@@ -15,17 +15,19 @@
 package replset
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"sync"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/pkg/pod"
 
 	log "github.com/sirupsen/logrus"
 	rsConfig "github.com/timvaillancourt/go-mongodb-replset/config"
 	rsStatus "github.com/timvaillancourt/go-mongodb-replset/status"
-	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
 const (
@@ -42,7 +44,7 @@ type State struct {
 	doUpdate  bool
 }
 
-func (s *State) updateConfig(configManager rsConfig.Manager) error {
+func (s *State) updateConfig(configManager db.ReplsetConfigManager) error {
 	if s.doUpdate == false {
 		return nil
 	}
@@ -65,7 +67,7 @@ func (s *State) updateConfig(configManager rsConfig.Manager) error {
 	return nil
 }
 
-func (s *State) fetchConfig(configManager rsConfig.Manager) error {
+func (s *State) fetchConfig(configManager db.ReplsetConfigManager) error {
 	err := configManager.Load()
 	if err != nil {
 		return err
@@ -75,11 +77,15 @@ func (s *State) fetchConfig(configManager rsConfig.Manager) error {
 	return nil
 }
 
-func (s *State) fetchStatus(session *mgo.Session) error {
-	status, err := rsStatus.New(session)
+func (s *State) fetchStatus(session db.Session) error {
+	status := &rsStatus.Status{}
+	err := session.Run(bson.D{{Name: "replSetGetStatus", Value: 1}}, status)
 	if err != nil {
 		return err
 	}
+	if status.Ok == 0 {
+		return errors.New(status.Errmsg)
+	}
 
 	s.Status = status
 	return nil
@@ -183,7 +189,7 @@ func NewState(replset string) *State {
 }
 
 // Fetch gets the current MongoDB Replica Set status and config while locking the State
-func (s *State) Fetch(session *mgo.Session, configManager rsConfig.Manager) error {
+func (s *State) Fetch(session db.Session, configManager db.ReplsetConfigManager) error {
 	s.Lock()
 	defer s.Unlock()
 
@@ -216,7 +222,7 @@ func (s *State) GetStatus() *rsStatus.Status {
 }
 
 // AddConfigMembers adds members to the MongoDB Replica Set config
-func (s *State) AddConfigMembers(session *mgo.Session, configManager rsConfig.Manager, members []*Mongod) error {
+func (s *State) AddConfigMembers(session db.Session, configManager db.ReplsetConfigManager, members []*Mongod) error {
 	if len(members) == 0 {
 		return nil
 	}
@@ -268,7 +274,7 @@ func (s *State) AddConfigMembers(session *mgo.Session, configManager rsConfig.Ma
 }
 
 // RemoveConfigMembers removes members from the MongoDB Replica Set config
-func (s *State) RemoveConfigMembers(session *mgo.Session, configManager rsConfig.Manager, members []*rsConfig.Member) error {
+func (s *State) RemoveConfigMembers(session db.Session, configManager db.ReplsetConfigManager, members []*rsConfig.Member) error {
 	if len(members) == 0 {
 		return nil
 	}
@@ -15,11 +15,13 @@
 package watchdog
 
 import (
+	"context"
 	"runtime"
 	"sync"
 	"time"
 
 	tools "github.com/percona/mongodb-orchestration-tools"
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/pkg/pod"
 	"github.com/percona/mongodb-orchestration-tools/watchdog/config"
 	"github.com/percona/mongodb-orchestration-tools/watchdog/metrics"
@@ -35,18 +37,16 @@ type Watchdog struct {
 	podSource      pod.Source
 	metrics        *metrics.Collector
 	watcherManager watcher.Manager
-	quit           chan bool
 	activePods     *pod.Pods
 	running        bool
 }
 
-func New(config *config.Config, podSource pod.Source, metricCollector *metrics.Collector, quit chan bool) *Watchdog {
+func New(config *config.Config, podSource pod.Source, metricCollector *metrics.Collector) *Watchdog {
 	activePods := pod.NewPods()
 	return &Watchdog{
 		config:         config,
 		podSource:      podSource,
 		metrics:        metricCollector,
-		quit:           quit,
 		watcherManager: watcher.NewManager(config, activePods),
 		activePods:     activePods,
 	}
@@ -64,7 +64,7 @@ func (w *Watchdog) setRunning(running bool) {
 	w.running = running
 }
 
-func (w *Watchdog) podMongodFetcher(podName string, wg *sync.WaitGroup) {
+func (w *Watchdog) podMongodFetcher(ctx context.Context, podName string, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	log.WithFields(log.Fields{
@@ -101,7 +101,7 @@ func (w *Watchdog) podMongodFetcher(podName string, wg *sync.WaitGroup) {
 		serviceName := mongod.Task.Service()
 		if !w.watcherManager.HasWatcher(serviceName, mongod.Replset) {
 			rs := replset.New(w.config, mongod.Replset)
-			w.watcherManager.Watch(serviceName, rs)
+			w.watcherManager.Watch(ctx, serviceName, rs)
 		}
 
 		// send the update to the watcher for the given replset
@@ -123,7 +123,7 @@ func (w *Watchdog) doIgnorePod(podName string) bool {
 	return false
 }
 
-func (w *Watchdog) fetchPods() {
+func (w *Watchdog) fetchPods(ctx context.Context) {
 	log.WithFields(log.Fields{
 		"source": w.podSource.Name(),
 		"url":    w.podSource.URL(),
@@ -159,13 +159,49 @@ func (w *Watchdog) fetchPods() {
 			continue
 		}
 		wg.Add(1)
-		go w.podMongodFetcher(podName, &wg)
+		go w.podMongodFetcher(ctx, podName, &wg)
 		log.WithFields(log.Fields{"pod": podName}).Debug("Started pod fetcher")
 	}
 	wg.Wait()
 	log.Debug("Completed all pod fetchers")
 }
 
+// updateCertificates reloads the SSL/TLS certificates of sessions that changed and
+// updates the certificate expiry metrics
+func (w *Watchdog) updateCertificates() {
+	w.metrics.CertificateExpiryDays.Reset()
+	for _, cm := range db.CertManagers() {
+		reloaded, err := cm.Reload()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Error reloading SSL/TLS certificates, keeping the loaded certificates")
+		} else if reloaded {
+			log.Info("Reloaded SSL/TLS certificates, new connections will use them")
+		}
+		for _, expiry := range cm.Expiries() {
+			w.metrics.CertificateExpiryDays.With(prometheus.Labels{
+				"type":    expiry.Type,
+				"name":    expiry.Name,
+				"subject": expiry.Subject,
+			}).Set(expiry.DaysUntilExpiry())
+		}
+	}
+}
+
+func (w *Watchdog) watchCertificates(ctx context.Context) {
+	ticker := time.NewTicker(w.config.CertReloadPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.updateCertificates()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (w *Watchdog) StopWatcher(serviceName, rsName string) {
 	if w.watcherManager == nil {
 		return
@@ -173,7 +209,7 @@ func (w *Watchdog) StopWatcher(serviceName, rsName string) {
 	w.watcherManager.Stop(serviceName, rsName)
 }
 
-func (w *Watchdog) Run() {
+func (w *Watchdog) Run(ctx context.Context) {
 	w.setRunning(true)
 
 	log.WithFields(log.Fields{
@@ -182,14 +218,18 @@ func (w *Watchdog) Run() {
 		"source":  w.podSource.Name(),
 	}).Info("Starting watchdog")
 
-	w.fetchPods()
+	w.fetchPods(ctx)
+
+	if w.config.SSL != nil && w.config.SSL.Enabled && w.config.CertReloadPoll > 0 {
+		go w.watchCertificates(ctx)
+	}
 
 	ticker := time.NewTicker(w.config.APIPoll)
 	for {
 		select {
 		case <-ticker.C:
-			w.fetchPods()
-		case <-w.quit:
+			w.fetchPods(ctx)
+		case <-ctx.Done():
 			log.Info("Stopping watchers")
 			ticker.Stop()
 			w.setRunning(false)
@@ -15,11 +15,14 @@
 package watchdog
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/percona/mongodb-orchestration-tools/internal"
 	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/internal/logger"
 	"github.com/percona/mongodb-orchestration-tools/internal/testutils"
@@ -28,12 +31,13 @@ import (
 	"github.com/percona/mongodb-orchestration-tools/pkg/pod/mocks"
 	"github.com/percona/mongodb-orchestration-tools/watchdog/config"
 	"github.com/percona/mongodb-orchestration-tools/watchdog/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
 var (
 	testWatchdog *Watchdog
-	testQuitChan = make(chan bool)
 	testConfig   = &config.Config{
 		Username:    testutils.MongodbAdminUser,
 		Password:    testutils.MongodbAdminPassword,
@@ -60,7 +64,7 @@ func TestWatchdogRun(t *testing.T) {
 
 	testPodSource := &mocks.Source{}
 	wMetrics := metrics.NewCollector()
-	testWatchdog := New(testConfig, testPodSource, wMetrics, testQuitChan)
+	testWatchdog := New(testConfig, testPodSource, wMetrics)
 	assert.NotNil(t, testWatchdog, ".New() returned nil")
 
 	testPodSource.On("Name").Return("test")
@@ -93,7 +97,9 @@ func TestWatchdogRun(t *testing.T) {
 	testPodSource.On("GetTasks", "testPod").Return(tasks, nil)
 
 	// start watchdog
-	go testWatchdog.Run()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go testWatchdog.Run(ctx)
 	tries := 0
 	for tries < 100 {
 		if testWatchdog.getRunning() {
@@ -126,7 +132,7 @@ func TestWatchdogRun(t *testing.T) {
 	assert.NotNil(t, state.GetStatus())
 
 	// stop watchdog
-	close(testQuitChan)
+	cancel()
 	tries = 0
 	for tries < 100 {
 		if !testWatchdog.getRunning() {
@@ -139,3 +145,26 @@ func TestWatchdogRun(t *testing.T) {
 		assert.FailNow(t, "could not stop watchdog after 100 tries")
 	}
 }
+
+func TestWatchdogUpdateCertificates(t *testing.T) {
+	sslCnf := &db.SSLConfig{
+		Enabled:    true,
+		PEMKeyFile: internal.RelPathToAbs(filepath.Join("../docker/test/ssl", "client.pem")),
+	}
+	_, err := sslCnf.CertManager()
+	assert.NoError(t, err)
+
+	wMetrics := metrics.NewCollector()
+	watchdog := &Watchdog{config: testConfig, metrics: wMetrics}
+	watchdog.updateCertificates()
+
+	gauge, err := wMetrics.CertificateExpiryDays.GetMetricWith(prometheus.Labels{
+		"type":    db.CertTypeClient,
+		"name":    sslCnf.PEMKeyFile,
+		"subject": "CN=localhost",
+	})
+	assert.NoError(t, err)
+	metric := &dto.Metric{}
+	assert.NoError(t, gauge.Write(metric))
+	assert.True(t, metric.Gauge.GetValue() < 0, "the test certificate has expired")
+}
@@ -15,8 +15,10 @@
 package watcher
 
 import (
+	"context"
 	"sync"
 
+	"github.com/percona/mongodb-orchestration-tools/internal/db"
 	"github.com/percona/mongodb-orchestration-tools/pkg/pod"
 	"github.com/percona/mongodb-orchestration-tools/watchdog/config"
 	"github.com/percona/mongodb-orchestration-tools/watchdog/replset"
@@ -28,22 +30,24 @@ type Manager interface {
 	Get(serviceName, rsName string) *Watcher
 	HasWatcher(serviceName, rsName string) bool
 	Stop(serviceName, rsName string)
-	Watch(serviceName string, rs *replset.Replset)
+	Watch(ctx context.Context, serviceName string, rs *replset.Replset)
 }
 
 type WatcherManager struct {
 	sync.Mutex
 	config     *config.Config
-	quitChans  map[string]chan bool
+	cancels    map[string]context.CancelFunc
 	watchers   map[string]*Watcher
 	activePods *pod.Pods
+	pool       *db.Pool
 }
 
 func NewManager(config *config.Config, activePods *pod.Pods) *WatcherManager {
 	return &WatcherManager{
 		config:     config,
 		activePods: activePods,
-		quitChans:  make(map[string]chan bool),
+		pool:       db.NewPool(config.Pool),
+		cancels:    make(map[string]context.CancelFunc),
 		watchers:   make(map[string]*Watcher),
 	}
 }
@@ -52,7 +56,7 @@ func (wm *WatcherManager) HasWatcher(serviceName, rsName string) bool {
 	return wm.Get(serviceName, rsName) != nil
 }
 
-func (wm *WatcherManager) Watch(serviceName string, rs *replset.Replset) {
+func (wm *WatcherManager) Watch(ctx context.Context, serviceName string, rs *replset.Replset) {
 	if wm.HasWatcher(serviceName, rs.Name) {
 		return
 	}
@@ -65,12 +69,12 @@ func (wm *WatcherManager) Watch(serviceName string, rs *replset.Replset) {
 	wm.Lock()
 	defer wm.Unlock()
 
-	quitChan := make(chan bool)
+	watcherCtx, cancel := context.WithCancel(ctx)
 	watcherName := serviceName + "-" + rs.Name
-	wm.quitChans[watcherName] = quitChan
-	wm.watchers[watcherName] = New(rs, wm.config, quitChan, wm.activePods)
+	wm.cancels[watcherName] = cancel
+	wm.watchers[watcherName] = New(rs, wm.config, wm.activePods, wm.pool)
 
-	go wm.watchers[watcherName].Run()
+	go wm.watchers[watcherName].Run(watcherCtx)
 }
 
 func (wm *WatcherManager) Get(serviceName, rsName string) *Watcher {
@@ -83,8 +87,8 @@ func (wm *WatcherManager) Get(serviceName, rsName string) *Watcher {
 func (wm *WatcherManager) stopWatcher(name string) {
 	for watcherName := range wm.watchers {
 		if watcherName == name {
-			close(wm.quitChans[watcherName])
-			delete(wm.quitChans, watcherName)
+			wm.cancels[watcherName]()
+			delete(wm.cancels, watcherName)
 			delete(wm.watchers, watcherName)
 		}
 	}
@@ -104,4 +108,5 @@ func (wm *WatcherManager) Close() {
 	for watcherName := range wm.watchers {
 		wm.stopWatcher(watcherName)
 	}
+	wm.pool.Close()
 }
@@ -15,6 +15,7 @@
 package watcher
 
 import (
+	"context"
 	"strconv"
 	"testing"
 	"time"
@@ -43,7 +44,7 @@ func TestWatchdogWatcherManagerWatch(t *testing.T) {
 	apiTaskState.On("String").Return("OK")
 	apiTask.On("State").Return(apiTaskState)
 
-	go testManager.Watch(testWatchRsService, testWatchRs)
+	go testManager.Watch(context.Background(), testWatchRsService, testWatchRs)
 
 	// primary
 	port, _ := strconv.Atoi(testutils.MongodbPrimaryPort)
@@ -107,7 +108,7 @@ func TestWatchdogWatcherManagerWatch(t *testing.T) {
 	// Test 2 x clusters with one watchdog, both with the same replset name
 	// https://jira.percona.com/browse/CLOUD-97
 	testWatchRs2 := replset.New(testConfig, testutils.MongodbReplsetName)
-	go testManager.Watch(testWatchRsService+"2", testWatchRs2)
+	go testManager.Watch(context.Background(), testWatchRsService+"2", testWatchRs2)
 
 	apiTask2 := &mocks.Task{}
 	apiTask2.On("Name").Return("test")
@@ -1,6 +1,7 @@
 // Code generated by mockery v1.0.0. DO NOT EDIT.
 package mocks
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 import replset "github.com/percona/mongodb-orchestration-tools/watchdog/replset"
 import watcher "github.com/percona/mongodb-orchestration-tools/watchdog/watcher"
@@ -50,7 +51,7 @@ func (_m *Manager) Stop(rsName string) {
 	_m.Called(rsName)
 }
 
-// Watch provides a mock function with given fields: rs
-func (_m *Manager) Watch(rs *replset.Replset) {
-	_m.Called(rs)
+// Watch provides a mock function with given fields: ctx, serviceName, rs
+func (_m *Manager) Watch(ctx context.Context, serviceName string, rs *replset.Replset) {
+	_m.Called(ctx, serviceName, rs)
 }
@@ -15,6 +15,7 @@
 package watcher
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -26,49 +27,44 @@ import (
 	log "github.com/sirupsen/logrus"
 	rsConfig "github.com/timvaillancourt/go-mongodb-replset/config"
 	rsStatus "github.com/timvaillancourt/go-mongodb-replset/status"
-	"gopkg.in/mgo.v2"
 )
 
-var (
-	connectReplsetTimeout              = time.Minute * 3
-	replsetReadPreference              = mgo.Primary
-	waitForMongodAvailableRetries uint = 10
-)
+var connectReplsetTimeout = time.Minute * 3
 
 type Watcher struct {
 	sync.Mutex
-	config        *config.Config
-	masterSession *mgo.Session
-	dbConfig      *db.Config
-	replset       *replset.Replset
-	state         *replset.State
-	quit          chan bool
-	running       bool
-	activePods    *pod.Pods
+	config         *config.Config
+	pool           *db.Pool
+	replsetSession db.Session
+	dbConfig       *db.Config
+	replset        *replset.Replset
+	state          *replset.State
+	running        bool
+	activePods     *pod.Pods
 }
 
-func New(rs *replset.Replset, config *config.Config, quit chan bool, activePods *pod.Pods) *Watcher {
+func New(rs *replset.Replset, config *config.Config, activePods *pod.Pods, pool *db.Pool) *Watcher {
 	return &Watcher{
 		config:     config,
+		pool:       pool,
 		replset:    rs,
 		state:      replset.NewState(rs.Name),
-		quit:       quit,
 		activePods: activePods,
 	}
 }
 
-func (rw *Watcher) getReplsetSession() *mgo.Session {
-	if rw.masterSession == nil || rw.masterSession.Ping() != nil {
-		err := rw.connectReplsetSession()
+func (rw *Watcher) getReplsetSession(ctx context.Context) db.Session {
+	if rw.replsetSession == nil || rw.replsetSession.Ping() != nil {
+		err := rw.connectReplsetSession(ctx)
 		if err != nil {
 			return nil
 		}
 	}
-	return rw.masterSession
+	return rw.replsetSession
 }
 
-func (rw *Watcher) connectReplsetSession() error {
-	var session *mgo.Session
+func (rw *Watcher) connectReplsetSession(ctx context.Context) error {
+	var session db.Session
 	for {
 		ticker := time.NewTicker(rw.config.ReplsetPoll)
 		select {
@@ -76,16 +72,10 @@ func (rw *Watcher) connectReplsetSession() error {
 			rw.dbConfig = rw.replset.GetReplsetDBConfig(rw.config.SSL)
 			if len(rw.dbConfig.DialInfo.Addrs) >= 1 {
 				var err error
-				if session == nil {
-					session, err = db.GetSession(rw.dbConfig)
-				}
-				if err == nil && session != nil {
-					session.SetMode(replsetReadPreference, true)
-					err = session.Ping()
-					if err == nil {
-						ticker.Stop()
-						break
-					}
+				session, err = rw.pool.Get(rw.dbConfig)
+				if err == nil {
+					ticker.Stop()
+					break
 				}
 
 				log.WithFields(log.Fields{
@@ -93,17 +83,13 @@ func (rw *Watcher) connectReplsetSession() error {
 					"replset": rw.replset.Name,
 					"ssl":     rw.dbConfig.SSL.Enabled,
 				}).Errorf("Error connecting to mongodb replset: %s", err)
-
-				if session != nil {
-					session.Close()
-				}
 			} else {
 				log.Errorf("no addresses for mongodb replset: %s", rw.replset.Name)
 			}
 		case <-time.After(connectReplsetTimeout):
 			return errors.New("timeout getting replset connection")
-		case <-rw.quit:
-			return errors.New("received quit")
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 		break
 	}
@@ -111,22 +97,37 @@ func (rw *Watcher) connectReplsetSession() error {
 	rw.Lock()
 	defer rw.Unlock()
 
-	if rw.masterSession != nil {
+	if rw.replsetSession != nil {
 		log.WithFields(log.Fields{
 			"addrs":   rw.dbConfig.DialInfo.Addrs,
 			"replset": rw.replset.Name,
 			"ssl":     rw.dbConfig.SSL.Enabled,
 		}).Info("Reconnecting to mongodb replset")
-		rw.masterSession.Close()
+		rw.replsetSession.Close()
 	}
-	rw.masterSession = session
+	rw.replsetSession = session
 
 	return nil
 }
 
-func (rw *Watcher) reconnectReplsetSession() {
-	err := rw.connectReplsetSession()
-	if err != nil {
+// releaseReplsetSession releases the replset session to the pool
+func (rw *Watcher) releaseReplsetSession() {
+	rw.Lock()
+	defer rw.Unlock()
+
+	if rw.replsetSession != nil {
+		rw.replsetSession.Close()
+		rw.replsetSession = nil
+	}
+}
+
+func (rw *Watcher) reconnectReplsetSession(ctx context.Context) {
+	// the replset members changed, connect a new session instead of the pooled one
+	if rw.dbConfig != nil {
+		rw.pool.Remove(rw.dbConfig)
+	}
+	err := rw.connectReplsetSession(ctx)
+	if err != nil && err != ctx.Err() {
 		log.WithFields(log.Fields{
 			"addrs":   rw.dbConfig.DialInfo.Addrs,
 			"replset": rw.replset.Name,
@@ -200,27 +201,32 @@ func (rw *Watcher) getScaledDownMembers() []*rsConfig.Member {
 	return scaledDown
 }
 
-func (rw *Watcher) waitForMongodAvailable(mongod *replset.Mongod) error {
-	session, err := db.WaitForSession(
-		mongod.DBConfig(rw.config.SSL),
-		waitForMongodAvailableRetries,
-		rw.config.ReplsetPoll,
-	)
+// waitForMongodAvailable checks a mongod accepts connections. Mongods that fail are
+// retried on later polls, after the backoff of the session pool
+func (rw *Watcher) waitForMongodAvailable(ctx context.Context, mongod *replset.Mongod) error {
+	dbCnf := mongod.DBConfig(rw.config.SSL)
+	dbCnf.Driver = rw.config.Driver
+	session, err := rw.pool.Get(dbCnf)
 	if err != nil {
 		return err
 	}
 	session.Close()
+
+	// the session is not needed once the mongod is added to the replset
+	rw.pool.Remove(dbCnf)
 	return nil
 }
 
-func (rw *Watcher) replsetConfigAdder(add []*replset.Mongod) error {
+func (rw *Watcher) replsetConfigAdder(ctx context.Context, add []*replset.Mongod) error {
 	mongods := make([]*replset.Mongod, 0)
 	for _, mongod := range add {
-		err := rw.waitForMongodAvailable(mongod)
+		err := rw.waitForMongodAvailable(ctx, mongod)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			log.WithFields(log.Fields{
-				"host":    mongod.Name(),
-				"retries": waitForMongodAvailableRetries,
+				"host": mongod.Name(),
 			}).Error(err)
 			continue
 		}
@@ -233,22 +239,22 @@ func (rw *Watcher) replsetConfigAdder(add []*replset.Mongod) error {
 	if len(mongods) == 0 {
 		return nil
 	}
-	session := rw.getReplsetSession()
+	session := rw.getReplsetSession(ctx)
 	if session != nil {
-		err := rw.state.AddConfigMembers(session, rsConfig.New(session), mongods)
+		err := rw.state.AddConfigMembers(session, db.NewReplsetConfigManager(session), mongods)
 		if err != nil {
 			return err
 		}
 	}
-	rw.reconnectReplsetSession()
+	rw.reconnectReplsetSession(ctx)
 	return nil
 }
 
-func (rw *Watcher) replsetConfigRemover(remove []*rsConfig.Member) error {
+func (rw *Watcher) replsetConfigRemover(ctx context.Context, remove []*rsConfig.Member) error {
 	if rw.state == nil || len(remove) == 0 {
 		return nil
 	}
-	session := rw.getReplsetSession()
+	session := rw.getReplsetSession(ctx)
 	if session != nil {
 		for _, member := range remove {
 			lf := log.Fields{
@@ -268,12 +274,12 @@ func (rw *Watcher) replsetConfigRemover(remove []*rsConfig.Member) error {
 				return err
 			}
 		}
-		err := rw.state.RemoveConfigMembers(session, rsConfig.New(session), remove)
+		err := rw.state.RemoveConfigMembers(session, db.NewReplsetConfigManager(session), remove)
 		if err != nil {
 			return err
 		}
 	}
-	rw.reconnectReplsetSession()
+	rw.reconnectReplsetSession(ctx)
 	return nil
 }
 
@@ -316,10 +322,10 @@ func (rw *Watcher) IsRunning() bool {
 	return rw.running
 }
 
-func (rw *Watcher) Run() {
-	err := rw.connectReplsetSession()
+func (rw *Watcher) Run(ctx context.Context) {
+	err := rw.connectReplsetSession(ctx)
 	if err != nil {
-		if err.Error() != "received quit" {
+		if err != ctx.Err() {
 			log.WithError(err).Error("Cannot connect to replset")
 		}
 		return
@@ -337,15 +343,15 @@ func (rw *Watcher) Run() {
 	for {
 		select {
 		case <-ticker.C:
-			session := rw.getReplsetSession()
+			session := rw.getReplsetSession(ctx)
 			if session == nil {
 				continue
 			}
 
-			err := rw.state.Fetch(session, rsConfig.New(session))
+			err := rw.state.Fetch(session, db.NewReplsetConfigManager(session))
 			if err != nil {
 				log.Errorf("Error fetching replset state: %s", err)
-				rw.reconnectReplsetSession()
+				rw.reconnectReplsetSession(ctx)
 				continue
 			}
 
@@ -353,24 +359,25 @@ func (rw *Watcher) Run() {
 				continue
 			}
 
-			err = rw.replsetConfigAdder(rw.getMissingReplsetMembers())
+			err = rw.replsetConfigAdder(ctx, rw.getMissingReplsetMembers())
 			if err != nil {
 				log.Errorf("Error adding missing member(s): %s", err)
 				continue
 			}
 
-			err = rw.replsetConfigRemover(rw.getScaledDownMembers())
+			err = rw.replsetConfigRemover(ctx, rw.getScaledDownMembers())
 			if err != nil {
 				log.Errorf("Error removing stale member(s): %s", err)
 				continue
 			}
 
 			rw.logReplsetState()
-		case <-rw.quit:
+		case <-ctx.Done():
 			log.WithFields(log.Fields{
 				"replset": rw.replset.Name,
 			}).Info("Stopping watcher for replset")
 			ticker.Stop()
+			rw.releaseReplsetSession()
 			return
 		}
 	}